@@ -20,6 +20,14 @@ func main() {
 		return
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(&conf, os.Args[2:]); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	srv, err := service.NewService(&conf)
 	if err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
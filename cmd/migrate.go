@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mabaro3009/example-architecture-go/pkg/migrate"
+	"github.com/mabaro3009/example-architecture-go/service"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up        apply every pending migration
+  down N    roll back the last N applied migrations
+  status    list migrations and whether they are applied`
+
+func runMigrate(conf *service.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, closeFn, err := service.NewMigrator(conf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = closeFn()
+	}()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		printMigrations("applied", done)
+		return err
+	case "down":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid number of migrations %q: %w", args[1], err)
+		}
+		done, err := m.Down(ctx, n)
+		printMigrations("rolled back", done)
+		return err
+	case "status":
+		return printStatus(ctx, m)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func printMigrations(action string, migrations []*migrate.Migration) {
+	if len(migrations) == 0 {
+		fmt.Printf("no migrations %s\n", action)
+		return
+	}
+
+	for _, m := range migrations {
+		fmt.Printf("%s %d_%s\n", action, m.Version, m.Name)
+	}
+}
+
+func printStatus(ctx context.Context, m *migrate.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
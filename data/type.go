@@ -3,6 +3,8 @@ package data
 const (
 	TypeInMemory Type = "in_memory"
 	TypePostgres Type = "postgres"
+	TypeFile     Type = "file"
+	TypeSQLite   Type = "sqlite"
 )
 
 type Type string
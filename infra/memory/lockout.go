@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+type lockoutMem struct {
+	UserID      string
+	Failures    int
+	Lockouts    int
+	LockedUntil *time.Time
+}
+
+func (l *lockoutMem) ToDomain() *user.Lockout {
+	return &user.Lockout{
+		UserID:      l.UserID,
+		Failures:    l.Failures,
+		Lockouts:    l.Lockouts,
+		LockedUntil: copyTime(l.LockedUntil),
+	}
+}
+
+// LockoutDB stores the login lockout state of users in memory. Users without
+// failed logins have no entry. It is safe for concurrent use.
+type LockoutDB struct {
+	mu       sync.RWMutex
+	lockouts map[string]*lockoutMem
+}
+
+func NewLockoutDB() *LockoutDB {
+	return &LockoutDB{
+		lockouts: make(map[string]*lockoutMem),
+	}
+}
+
+func (m *LockoutDB) GetLockout(_ context.Context, userID string) (*user.Lockout, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, ok := m.lockouts[userID]
+	if !ok {
+		return &user.Lockout{UserID: userID}, nil
+	}
+
+	return l.ToDomain(), nil
+}
+
+func (m *LockoutDB) AddLoginFailure(_ context.Context, params *user.LoginFailureParams) (*user.Lockout, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := m.get(params.UserID)
+	if l.LockedUntil != nil && params.Now.Before(*l.LockedUntil) {
+		return l.ToDomain(), nil
+	}
+
+	l.Failures++
+	if l.Failures >= params.Threshold {
+		until := params.Now.Add(params.LockFor(l.Lockouts))
+		l.Failures = 0
+		l.Lockouts++
+		l.LockedUntil = &until
+	}
+
+	return l.ToDomain(), nil
+}
+
+func (m *LockoutDB) ResetLockout(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.lockouts, userID)
+
+	return nil
+}
+
+// get returns the entry of the user, creating it if needed. m.mu must be
+// held.
+func (m *LockoutDB) get(userID string) *lockoutMem {
+	l, ok := m.lockouts[userID]
+	if !ok {
+		l = &lockoutMem{UserID: userID}
+		m.lockouts[userID] = l
+	}
+
+	return l
+}
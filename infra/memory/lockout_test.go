@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutDB(t *testing.T) {
+	ctx := context.Background()
+	db := NewLockoutDB()
+	now := time.Now()
+	lockFor := func(lockouts int) time.Duration {
+		return time.Duration(lockouts+1) * time.Minute
+	}
+	fail := func(userID string, at time.Time) *user.Lockout {
+		got, err := db.AddLoginFailure(ctx, &user.LoginFailureParams{UserID: userID, Now: at, Threshold: 3, LockFor: lockFor})
+		assert.NoError(t, err)
+
+		return got
+	}
+
+	got, err := db.GetLockout(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, &user.Lockout{UserID: "user"}, got)
+
+	for i := 1; i < 3; i++ {
+		got = fail("user", now)
+		assert.Equal(t, i, got.Failures)
+		assert.Nil(t, got.LockedUntil)
+	}
+
+	until := now.Add(time.Minute)
+	got = fail("user", now)
+	assert.Equal(t, &user.Lockout{UserID: "user", Lockouts: 1, LockedUntil: &until}, got)
+
+	got = fail("user", now.Add(time.Second))
+	assert.Equal(t, &user.Lockout{UserID: "user", Lockouts: 1, LockedUntil: &until}, got, "failures while locked are not counted")
+
+	*got.LockedUntil = until.Add(time.Hour)
+	got, err = db.GetLockout(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, until, *got.LockedUntil)
+
+	later := until.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		got = fail("user", later)
+	}
+	secondUntil := later.Add(2 * time.Minute)
+	assert.Equal(t, &user.Lockout{UserID: "user", Lockouts: 2, LockedUntil: &secondUntil}, got)
+
+	fail("other", now)
+
+	assert.NoError(t, db.ResetLockout(ctx, "user"))
+	got, err = db.GetLockout(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, &user.Lockout{UserID: "user"}, got)
+
+	got, err = db.GetLockout(ctx, "other")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Failures)
+}
+
+func TestLockoutDB_ConcurrentFailures(t *testing.T) {
+	ctx := context.Background()
+	db := NewLockoutDB()
+	lockouts := user.NewLockouts(user.LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: time.Hour}, db, db)
+
+	const attempts = 50
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		locked int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := lockouts.Fail(ctx, "user")
+			if err != nil {
+				assert.ErrorIs(t, err, user.ErrAccountLocked)
+				mu.Lock()
+				locked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := db.GetLockout(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Lockouts, "concurrent failures must lock the user only once")
+	assert.Zero(t, got.Failures)
+	assert.Equal(t, attempts-2, locked)
+}
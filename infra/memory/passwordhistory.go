@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+type passwordHistoryMem struct {
+	UserID         string
+	HashedPassword []byte
+	CreatedAt      time.Time
+}
+
+func (e *passwordHistoryMem) ToDomain() *user.PasswordHistoryEntry {
+	return &user.PasswordHistoryEntry{
+		UserID:         e.UserID,
+		HashedPassword: append([]byte(nil), e.HashedPassword...),
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
+// PasswordHistoryDB stores the previous passwords of users in memory, oldest
+// first. It is safe for concurrent use.
+type PasswordHistoryDB struct {
+	mu      sync.RWMutex
+	entries map[string][]*passwordHistoryMem
+}
+
+func NewPasswordHistoryDB() *PasswordHistoryDB {
+	return &PasswordHistoryDB{
+		entries: make(map[string][]*passwordHistoryMem),
+	}
+}
+
+func (m *PasswordHistoryDB) AddPasswordHistory(_ context.Context, e *user.PasswordHistoryEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[e.UserID] = append(m.entries[e.UserID], &passwordHistoryMem{
+		UserID:         e.UserID,
+		HashedPassword: append([]byte(nil), e.HashedPassword...),
+		CreatedAt:      e.CreatedAt,
+	})
+
+	return nil
+}
+
+func (m *PasswordHistoryDB) ListPasswordHistory(_ context.Context, userID string, limit int) ([]*user.PasswordHistoryEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := m.entries[userID]
+	if limit > len(entries) || limit < 0 {
+		limit = len(entries)
+	}
+
+	history := make([]*user.PasswordHistoryEntry, 0, limit)
+	for i := len(entries) - 1; i >= len(entries)-limit; i-- {
+		history = append(history, entries[i].ToDomain())
+	}
+
+	return history, nil
+}
+
+func (m *PasswordHistoryDB) PrunePasswordHistory(_ context.Context, userID string, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.entries[userID]
+	switch {
+	case keep <= 0:
+		delete(m.entries, userID)
+	case keep < len(entries):
+		m.entries[userID] = append([]*passwordHistoryMem(nil), entries[len(entries)-keep:]...)
+	}
+
+	return nil
+}
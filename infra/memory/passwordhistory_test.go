@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHistoryDB(t *testing.T) {
+	ctx := context.Background()
+	db := NewPasswordHistoryDB()
+
+	now := time.Now()
+	for i, hashed := range []string{"first", "second", "third"} {
+		assert.NoError(t, db.AddPasswordHistory(ctx, &user.PasswordHistoryEntry{
+			UserID:         "user",
+			HashedPassword: []byte(hashed),
+			CreatedAt:      now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	assert.NoError(t, db.AddPasswordHistory(ctx, &user.PasswordHistoryEntry{UserID: "other", HashedPassword: []byte("other")}))
+
+	hashes := func(entries []*user.PasswordHistoryEntry) []string {
+		res := make([]string, 0, len(entries))
+		for _, e := range entries {
+			res = append(res, string(e.HashedPassword))
+		}
+		return res
+	}
+
+	history, err := db.ListPasswordHistory(ctx, "user", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"third", "second", "first"}, hashes(history))
+	assert.Equal(t, now.Add(2*time.Minute), history[0].CreatedAt)
+
+	history[0].HashedPassword[0] = 'x'
+	history, err = db.ListPasswordHistory(ctx, "user", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"third", "second"}, hashes(history))
+
+	history, err = db.ListPasswordHistory(ctx, "unknown", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	assert.NoError(t, db.PrunePasswordHistory(ctx, "user", 2))
+	history, err = db.ListPasswordHistory(ctx, "user", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"third", "second"}, hashes(history))
+
+	assert.NoError(t, db.PrunePasswordHistory(ctx, "user", 0))
+	history, err = db.ListPasswordHistory(ctx, "user", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	history, err = db.ListPasswordHistory(ctx, "other", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other"}, hashes(history))
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/refresh"
+)
+
+type refreshTokenMem struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+func (t *refreshTokenMem) ToDomain() *refresh.Token {
+	return &refresh.Token{
+		ID:        t.ID,
+		FamilyID:  t.FamilyID,
+		UserID:    t.UserID,
+		Hash:      t.Hash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		RotatedAt: copyTime(t.RotatedAt),
+		RevokedAt: copyTime(t.RevokedAt),
+	}
+}
+
+// RefreshTokenDB stores refresh tokens in memory. It is safe for concurrent
+// use.
+type RefreshTokenDB struct {
+	mu       sync.RWMutex
+	tokens   map[string]*refreshTokenMem
+	byHash   map[string]*refreshTokenMem
+	byFamily map[string][]*refreshTokenMem
+	byUserID map[string][]*refreshTokenMem
+}
+
+func NewRefreshTokenDB() *RefreshTokenDB {
+	return &RefreshTokenDB{
+		tokens:   make(map[string]*refreshTokenMem),
+		byHash:   make(map[string]*refreshTokenMem),
+		byFamily: make(map[string][]*refreshTokenMem),
+		byUserID: make(map[string][]*refreshTokenMem),
+	}
+}
+
+func (m *RefreshTokenDB) Insert(_ context.Context, t *refresh.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.put(t)
+
+	return nil
+}
+
+func (m *RefreshTokenDB) Rotate(_ context.Context, id string, next *refresh.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.tokens[id]
+	if !ok {
+		return refresh.ErrDoesNotExist
+	}
+	if current.RotatedAt != nil || current.RevokedAt != nil {
+		return refresh.ErrNotActive
+	}
+
+	rotatedAt := next.CreatedAt
+	current.RotatedAt = &rotatedAt
+	m.put(next)
+
+	return nil
+}
+
+func (m *RefreshTokenDB) RevokeFamily(_ context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revoke(m.byFamily[familyID])
+
+	return nil
+}
+
+func (m *RefreshTokenDB) RevokeByUserID(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revoke(m.byUserID[userID])
+
+	return nil
+}
+
+func (m *RefreshTokenDB) DeleteInactive(_ context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make(map[string]bool)
+	for familyID, family := range m.byFamily {
+		if !inactive(family, now) {
+			continue
+		}
+
+		for _, t := range family {
+			delete(m.tokens, t.ID)
+			delete(m.byHash, string(t.Hash))
+			users[t.UserID] = true
+		}
+		delete(m.byFamily, familyID)
+	}
+
+	for userID := range users {
+		var kept []*refreshTokenMem
+		for _, t := range m.byUserID[userID] {
+			if _, ok := m.tokens[t.ID]; ok {
+				kept = append(kept, t)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(m.byUserID, userID)
+		} else {
+			m.byUserID[userID] = kept
+		}
+	}
+
+	return nil
+}
+
+func (m *RefreshTokenDB) GetByHash(_ context.Context, hash []byte) (*refresh.Token, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.byHash[string(hash)]
+	if !ok {
+		return nil, refresh.ErrDoesNotExist
+	}
+
+	return t.ToDomain(), nil
+}
+
+func (m *RefreshTokenDB) put(t *refresh.Token) {
+	tm := &refreshTokenMem{
+		ID:        t.ID,
+		FamilyID:  t.FamilyID,
+		UserID:    t.UserID,
+		Hash:      t.Hash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		RotatedAt: copyTime(t.RotatedAt),
+		RevokedAt: copyTime(t.RevokedAt),
+	}
+
+	m.tokens[tm.ID] = tm
+	m.byHash[string(tm.Hash)] = tm
+	m.byFamily[tm.FamilyID] = append(m.byFamily[tm.FamilyID], tm)
+	m.byUserID[tm.UserID] = append(m.byUserID[tm.UserID], tm)
+}
+
+func revoke(tokens []*refreshTokenMem) {
+	now := time.Now()
+	for _, t := range tokens {
+		if t.RevokedAt == nil {
+			revokedAt := now
+			t.RevokedAt = &revokedAt
+		}
+	}
+}
+
+// inactive reports whether every token of family is revoked or expired at now.
+func inactive(family []*refreshTokenMem, now time.Time) bool {
+	for _, t := range family {
+		if t.RevokedAt == nil && now.Before(t.ExpiresAt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+
+	return &c
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/refresh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenDB(t *testing.T) {
+	ctx := context.Background()
+	db := NewRefreshTokenDB()
+
+	now := time.Now()
+	first := &refresh.Token{
+		ID:        "1",
+		FamilyID:  "f",
+		UserID:    "user",
+		Hash:      []byte("hash1"),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	assert.NoError(t, db.Insert(ctx, first))
+
+	got, err := db.GetByHash(ctx, []byte("hash1"))
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	_, err = db.GetByHash(ctx, []byte("other"))
+	assert.ErrorIs(t, err, refresh.ErrDoesNotExist)
+
+	second := &refresh.Token{
+		ID:        "2",
+		FamilyID:  "f",
+		UserID:    "user",
+		Hash:      []byte("hash2"),
+		CreatedAt: now.Add(time.Minute),
+		ExpiresAt: now.Add(time.Hour),
+	}
+	assert.NoError(t, db.Rotate(ctx, "1", second))
+
+	got, err = db.GetByHash(ctx, []byte("hash1"))
+	assert.NoError(t, err)
+	assert.Equal(t, second.CreatedAt, *got.RotatedAt)
+
+	third := &refresh.Token{ID: "3", FamilyID: "f", Hash: []byte("hash3")}
+	assert.ErrorIs(t, db.Rotate(ctx, "1", third), refresh.ErrNotActive)
+	_, err = db.GetByHash(ctx, []byte("hash3"))
+	assert.ErrorIs(t, err, refresh.ErrDoesNotExist)
+
+	assert.ErrorIs(t, db.Rotate(ctx, "unknown", third), refresh.ErrDoesNotExist)
+
+	other := &refresh.Token{ID: "4", FamilyID: "g", Hash: []byte("hash4")}
+	assert.NoError(t, db.Insert(ctx, other))
+
+	assert.NoError(t, db.RevokeFamily(ctx, "f"))
+	for _, hash := range []string{"hash1", "hash2"} {
+		got, err = db.GetByHash(ctx, []byte(hash))
+		assert.NoError(t, err)
+		assert.NotNil(t, got.RevokedAt)
+	}
+	assert.ErrorIs(t, db.Rotate(ctx, "2", third), refresh.ErrNotActive)
+
+	got, err = db.GetByHash(ctx, []byte("hash4"))
+	assert.NoError(t, err)
+	assert.Nil(t, got.RevokedAt)
+
+	assert.NoError(t, db.Insert(ctx, &refresh.Token{ID: "5", FamilyID: "h", UserID: "user", Hash: []byte("hash5")}))
+	assert.NoError(t, db.RevokeByUserID(ctx, "user"))
+	got, err = db.GetByHash(ctx, []byte("hash5"))
+	assert.NoError(t, err)
+	assert.NotNil(t, got.RevokedAt)
+
+	got, err = db.GetByHash(ctx, []byte("hash4"))
+	assert.NoError(t, err)
+	assert.Nil(t, got.RevokedAt)
+}
+
+func TestRefreshTokenDB_DeleteInactive(t *testing.T) {
+	ctx := context.Background()
+	db := NewRefreshTokenDB()
+
+	now := time.Now()
+	active := &refresh.Token{ID: "1", FamilyID: "f", UserID: "user", Hash: []byte("hash1"), CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}
+	assert.NoError(t, db.Insert(ctx, active))
+	assert.NoError(t, db.Rotate(ctx, "1", &refresh.Token{ID: "2", FamilyID: "f", UserID: "user", Hash: []byte("hash2"), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	assert.NoError(t, db.Insert(ctx, &refresh.Token{ID: "3", FamilyID: "g", UserID: "user", Hash: []byte("hash3"), ExpiresAt: now.Add(-time.Minute)}))
+
+	assert.NoError(t, db.Insert(ctx, &refresh.Token{ID: "4", FamilyID: "h", UserID: "other", Hash: []byte("hash4"), ExpiresAt: now.Add(time.Hour)}))
+	assert.NoError(t, db.RevokeFamily(ctx, "h"))
+
+	assert.NoError(t, db.DeleteInactive(ctx, now))
+
+	for _, hash := range []string{"hash1", "hash2"} {
+		_, err := db.GetByHash(ctx, []byte(hash))
+		assert.NoError(t, err, "a family with a usable token is kept whole, for reuse detection")
+	}
+	for _, hash := range []string{"hash3", "hash4"} {
+		_, err := db.GetByHash(ctx, []byte(hash))
+		assert.ErrorIs(t, err, refresh.ErrDoesNotExist)
+	}
+	assert.Len(t, db.tokens, 2)
+	assert.Len(t, db.byFamily, 1)
+	assert.Len(t, db.byUserID["user"], 2)
+	assert.NotContains(t, db.byUserID, "other")
+
+	assert.NoError(t, db.DeleteInactive(ctx, now.Add(2*time.Hour)))
+	assert.Empty(t, db.tokens)
+	assert.Empty(t, db.byHash)
+	assert.Empty(t, db.byFamily)
+	assert.Empty(t, db.byUserID)
+}
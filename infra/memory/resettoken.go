@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/reset"
+)
+
+type resetTokenMem struct {
+	ID        string
+	UserID    string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (t *resetTokenMem) ToDomain() *reset.Token {
+	return &reset.Token{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Hash:      t.Hash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    copyTime(t.UsedAt),
+	}
+}
+
+// ResetTokenDB stores password reset tokens in memory. It is safe for
+// concurrent use.
+type ResetTokenDB struct {
+	mu       sync.RWMutex
+	tokens   map[string]*resetTokenMem
+	byHash   map[string]*resetTokenMem
+	byUserID map[string]map[string]*resetTokenMem
+}
+
+func NewResetTokenDB() *ResetTokenDB {
+	return &ResetTokenDB{
+		tokens:   make(map[string]*resetTokenMem),
+		byHash:   make(map[string]*resetTokenMem),
+		byUserID: make(map[string]map[string]*resetTokenMem),
+	}
+}
+
+func (m *ResetTokenDB) Insert(_ context.Context, t *reset.Token) error {
+	tm := &resetTokenMem{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Hash:      t.Hash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    copyTime(t.UsedAt),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[tm.ID] = tm
+	m.byHash[string(tm.Hash)] = tm
+	if m.byUserID[tm.UserID] == nil {
+		m.byUserID[tm.UserID] = make(map[string]*resetTokenMem)
+	}
+	m.byUserID[tm.UserID][tm.ID] = tm
+
+	return nil
+}
+
+func (m *ResetTokenDB) MarkUsed(_ context.Context, id string, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[id]
+	if !ok {
+		return reset.ErrDoesNotExist
+	}
+	if t.UsedAt != nil {
+		return reset.ErrNotActive
+	}
+	t.UsedAt = &usedAt
+
+	return nil
+}
+
+func (m *ResetTokenDB) DeleteByUserID(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.byUserID[userID] {
+		delete(m.tokens, t.ID)
+		delete(m.byHash, string(t.Hash))
+	}
+	delete(m.byUserID, userID)
+
+	return nil
+}
+
+func (m *ResetTokenDB) GetByHash(_ context.Context, hash []byte) (*reset.Token, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.byHash[string(hash)]
+	if !ok {
+		return nil, reset.ErrDoesNotExist
+	}
+
+	return t.ToDomain(), nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/reset"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResetTokenDB(t *testing.T) {
+	ctx := context.Background()
+	db := NewResetTokenDB()
+
+	now := time.Now()
+	first := &reset.Token{
+		ID:        "1",
+		UserID:    "user",
+		Hash:      []byte("hash1"),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	assert.NoError(t, db.Insert(ctx, first))
+	assert.NoError(t, db.Insert(ctx, &reset.Token{ID: "2", UserID: "other", Hash: []byte("hash2")}))
+
+	got, err := db.GetByHash(ctx, []byte("hash1"))
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	_, err = db.GetByHash(ctx, []byte("unknown"))
+	assert.ErrorIs(t, err, reset.ErrDoesNotExist)
+
+	usedAt := now.Add(time.Minute)
+	assert.NoError(t, db.MarkUsed(ctx, "1", usedAt))
+	assert.ErrorIs(t, db.MarkUsed(ctx, "1", usedAt), reset.ErrNotActive)
+	assert.ErrorIs(t, db.MarkUsed(ctx, "unknown", usedAt), reset.ErrDoesNotExist)
+
+	got, err = db.GetByHash(ctx, []byte("hash1"))
+	assert.NoError(t, err)
+	assert.Equal(t, usedAt, *got.UsedAt)
+
+	assert.NoError(t, db.DeleteByUserID(ctx, "user"))
+	_, err = db.GetByHash(ctx, []byte("hash1"))
+	assert.ErrorIs(t, err, reset.ErrDoesNotExist)
+	assert.ErrorIs(t, db.MarkUsed(ctx, "1", usedAt), reset.ErrDoesNotExist)
+
+	_, err = db.GetByHash(ctx, []byte("hash2"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+const roleFile = "roles.json"
+
+type roleMem struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Permissions []role.Permission `json:"permissions"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (r *roleMem) ToDomain() *role.Role {
+	return &role.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: append([]role.Permission{}, r.Permissions...),
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+func newRoleMem(r *role.Role) *roleMem {
+	return &roleMem{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: append([]role.Permission{}, r.Permissions...),
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+// RoleDB stores the custom roles of the users of a UserDB in memory, and in a
+// file when opened with OpenRoleDB. It is safe for concurrent use.
+type RoleDB struct {
+	mu    sync.RWMutex
+	roles map[string]*roleMem
+	users *UserDB
+	path  string
+}
+
+// NewRoleDB returns an empty RoleDB for the users of users, which from then on
+// can only be given the built-in roles and the roles stored in it. It must be
+// called before users is used.
+func NewRoleDB(users *UserDB) *RoleDB {
+	m := &RoleDB{
+		roles: make(map[string]*roleMem),
+		users: users,
+	}
+	users.roles = m
+
+	return m
+}
+
+// OpenRoleDB is like NewRoleDB, but keeps the roles in a file in dir, creating
+// it if needed. Every change is written to the file before it is applied.
+func OpenRoleDB(dir string, users *UserDB) (*RoleDB, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	m := NewRoleDB(users)
+	m.path = filepath.Join(dir, roleFile)
+
+	buff, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*roleMem
+	if err = json.Unmarshal(buff, &roles); err != nil {
+		return nil, fmt.Errorf("corrupted role file: %w", err)
+	}
+	for _, r := range roles {
+		m.roles[r.Name] = r
+	}
+
+	return m, nil
+}
+
+func (m *RoleDB) Insert(_ context.Context, r *role.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[r.Name]; ok {
+		return role.ErrAlreadyExists
+	}
+
+	roles := m.copyRoles()
+	roles[r.Name] = newRoleMem(r)
+
+	return m.commit(roles)
+}
+
+func (m *RoleDB) Update(_ context.Context, r *role.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[r.Name]; !ok {
+		return role.ErrDoesNotExist
+	}
+
+	roles := m.copyRoles()
+	roles[r.Name] = newRoleMem(r)
+
+	return m.commit(roles)
+}
+
+// Delete keeps the lock while it looks for users with the role, and users are
+// only given roles under the read lock, so nobody can be given the role in
+// between.
+func (m *RoleDB) Delete(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[name]; !ok {
+		return role.ErrDoesNotExist
+	}
+	if m.users.hasRole(name) {
+		return role.ErrInUse
+	}
+
+	roles := m.copyRoles()
+	delete(roles, name)
+
+	return m.commit(roles)
+}
+
+// exists reports whether name is a built-in or a stored role. The caller must
+// hold the lock.
+func (m *RoleDB) exists(name string) bool {
+	if name == user.RoleUser || name == user.RoleAdmin {
+		return true
+	}
+	_, ok := m.roles[name]
+
+	return ok
+}
+
+func (m *RoleDB) copyRoles() map[string]*roleMem {
+	roles := make(map[string]*roleMem, len(m.roles)+1)
+	for name, r := range m.roles {
+		roles[name] = r
+	}
+
+	return roles
+}
+
+// commit atomically replaces the role file, if any, with roles and then makes
+// them the current ones. The caller must hold the write lock.
+func (m *RoleDB) commit(roles map[string]*roleMem) error {
+	if m.path != "" {
+		list := make([]*roleMem, 0, len(roles))
+		for _, r := range roles {
+			list = append(list, r)
+		}
+
+		buff, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+
+		tmpPath := m.path + ".tmp"
+		if err = writeFileSync(tmpPath, buff); err != nil {
+			return err
+		}
+		if err = os.Rename(tmpPath, m.path); err != nil {
+			return err
+		}
+		if err = syncDir(filepath.Dir(m.path)); err != nil {
+			return err
+		}
+	}
+	m.roles = roles
+
+	return nil
+}
+
+func (m *RoleDB) Get(_ context.Context, name string) (*role.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, ok := m.roles[name]
+	if !ok {
+		return nil, role.ErrDoesNotExist
+	}
+
+	return r.ToDomain(), nil
+}
+
+func (m *RoleDB) List(_ context.Context) ([]*role.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	roles := make([]*role.Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		roles = append(roles, r.ToDomain())
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+
+	return roles, nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/role/roletest"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleDB(t *testing.T) {
+	roletest.RunRepositoryTests(t, func(t *testing.T) (roletest.Repository, roletest.GiveRole) {
+		users := NewUserDB()
+
+		return NewRoleDB(users), giveRole(users)
+	})
+}
+
+func TestRoleDB_Copies(t *testing.T) {
+	ctx := context.Background()
+	db := NewRoleDB(NewUserDB())
+
+	assert.NoError(t, db.Insert(ctx, &role.Role{Name: "support", Permissions: []role.Permission{role.PermUsersRead}}))
+
+	got, err := db.Get(ctx, "support")
+	assert.NoError(t, err)
+	got.Permissions[0] = role.PermRolesManage
+
+	got, err = db.Get(ctx, "support")
+	assert.NoError(t, err)
+	assert.Equal(t, []role.Permission{role.PermUsersRead}, got.Permissions)
+}
+
+func TestOpenRoleDB(t *testing.T) {
+	roletest.RunRepositoryTests(t, func(t *testing.T) (roletest.Repository, roletest.GiveRole) {
+		users := NewUserDB()
+		db, err := OpenRoleDB(t.TempDir(), users)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return db, giveRole(users)
+	})
+}
+
+func TestOpenRoleDB_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := OpenRoleDB(dir, NewUserDB())
+	assert.NoError(t, err)
+
+	now := time.Now()
+	support := &role.Role{
+		Name:        "support",
+		Description: "support staff",
+		Permissions: []role.Permission{role.PermUsersRead},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	assert.NoError(t, db.Insert(ctx, support))
+	assert.NoError(t, db.Insert(ctx, &role.Role{Name: "auditor"}))
+	assert.NoError(t, db.Delete(ctx, "auditor"))
+
+	db, err = OpenRoleDB(dir, NewUserDB())
+	assert.NoError(t, err)
+
+	got, err := db.Get(ctx, "support")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, support.Description, got.Description)
+		assert.Equal(t, support.Permissions, got.Permissions)
+		assert.True(t, support.CreatedAt.Equal(got.CreatedAt))
+	}
+	_, err = db.Get(ctx, "auditor")
+	assert.ErrorIs(t, err, role.ErrDoesNotExist)
+}
+
+func giveRole(users *UserDB) roletest.GiveRole {
+	n := 0
+
+	return func(ctx context.Context, name string, deleted bool) error {
+		n++
+		id := fmt.Sprint(n)
+
+		if err := users.Insert(ctx, &user.InsertParams{ID: id, Username: "user-" + id, Role: name}); err != nil {
+			return err
+		}
+		if deleted {
+			return users.Delete(ctx, id)
+		}
+
+		return nil
+	}
+}
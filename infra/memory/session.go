@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/session"
+)
+
+type sessionMem struct {
+	ID         string
+	UserID     string
+	TokenHash  []byte
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	IP         string
+}
+
+func (s *sessionMem) ToDomain() *session.Session {
+	return &session.Session{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		TokenHash:  s.TokenHash,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+	}
+}
+
+// SessionDB stores sessions in memory. It is safe for concurrent use.
+type SessionDB struct {
+	mu          sync.RWMutex
+	sessions    map[string]*sessionMem
+	byTokenHash map[string]*sessionMem
+	byUserID    map[string]map[string]*sessionMem
+}
+
+func NewSessionDB() *SessionDB {
+	return &SessionDB{
+		sessions:    make(map[string]*sessionMem),
+		byTokenHash: make(map[string]*sessionMem),
+		byUserID:    make(map[string]map[string]*sessionMem),
+	}
+}
+
+func (m *SessionDB) Insert(_ context.Context, s *session.Session) error {
+	sm := &sessionMem{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		TokenHash:  s.TokenHash,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[sm.ID] = sm
+	m.byTokenHash[string(sm.TokenHash)] = sm
+	if m.byUserID[sm.UserID] == nil {
+		m.byUserID[sm.UserID] = make(map[string]*sessionMem)
+	}
+	m.byUserID[sm.UserID][sm.ID] = sm
+
+	return nil
+}
+
+func (m *SessionDB) Touch(_ context.Context, id string, lastSeenAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return session.ErrDoesNotExist
+	}
+	s.LastSeenAt = lastSeenAt
+
+	return nil
+}
+
+func (m *SessionDB) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return session.ErrDoesNotExist
+	}
+	m.remove(s)
+
+	return nil
+}
+
+func (m *SessionDB) DeleteByUserID(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.byUserID[userID] {
+		m.remove(s)
+	}
+
+	return nil
+}
+
+func (m *SessionDB) DeleteExpired(_ context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if !now.Before(s.ExpiresAt) {
+			m.remove(s)
+		}
+	}
+
+	return nil
+}
+
+func (m *SessionDB) GetByID(_ context.Context, id string) (*session.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, session.ErrDoesNotExist
+	}
+
+	return s.ToDomain(), nil
+}
+
+func (m *SessionDB) GetByTokenHash(_ context.Context, tokenHash []byte) (*session.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.byTokenHash[string(tokenHash)]
+	if !ok {
+		return nil, session.ErrDoesNotExist
+	}
+
+	return s.ToDomain(), nil
+}
+
+func (m *SessionDB) ListByUserID(_ context.Context, userID string) ([]*session.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]*session.Session, 0, len(m.byUserID[userID]))
+	for _, s := range m.byUserID[userID] {
+		sessions = append(sessions, s.ToDomain())
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (m *SessionDB) remove(s *sessionMem) {
+	delete(m.sessions, s.ID)
+	delete(m.byTokenHash, string(s.TokenHash))
+	delete(m.byUserID[s.UserID], s.ID)
+	if len(m.byUserID[s.UserID]) == 0 {
+		delete(m.byUserID, s.UserID)
+	}
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionDB(t *testing.T) {
+	ctx := context.Background()
+	db := NewSessionDB()
+
+	now := time.Now()
+	s := &session.Session{
+		ID:         "1",
+		UserID:     "user",
+		TokenHash:  []byte("hash"),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+		UserAgent:  "agent",
+		IP:         "10.0.0.1",
+	}
+	assert.NoError(t, db.Insert(ctx, s))
+
+	got, err := db.GetByTokenHash(ctx, []byte("hash"))
+	assert.NoError(t, err)
+	assert.Equal(t, s, got)
+
+	got, err = db.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, s, got)
+
+	_, err = db.GetByTokenHash(ctx, []byte("other"))
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+	_, err = db.GetByID(ctx, "other")
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+
+	seen := now.Add(time.Minute)
+	assert.NoError(t, db.Touch(ctx, "1", seen))
+	got, err = db.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, seen, got.LastSeenAt)
+	assert.ErrorIs(t, db.Touch(ctx, "other", seen), session.ErrDoesNotExist)
+
+	second := &session.Session{ID: "2", UserID: "user", TokenHash: []byte("hash2"), CreatedAt: now.Add(time.Second)}
+	third := &session.Session{ID: "3", UserID: "user", TokenHash: []byte("hash3"), CreatedAt: now.Add(2 * time.Second)}
+	other := &session.Session{ID: "4", UserID: "other", TokenHash: []byte("hash4"), CreatedAt: now}
+	assert.NoError(t, db.Insert(ctx, third))
+	assert.NoError(t, db.Insert(ctx, second))
+	assert.NoError(t, db.Insert(ctx, other))
+
+	sessions, err := db.ListByUserID(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, sessionIDs(sessions))
+
+	assert.NoError(t, db.Delete(ctx, "2"))
+	assert.ErrorIs(t, db.Delete(ctx, "2"), session.ErrDoesNotExist)
+	_, err = db.GetByTokenHash(ctx, []byte("hash2"))
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+
+	assert.NoError(t, db.DeleteByUserID(ctx, "user"))
+	sessions, err = db.ListByUserID(ctx, "user")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = db.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+
+	sessions, err = db.ListByUserID(ctx, "other")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, sessionIDs(sessions))
+}
+
+func TestSessionDB_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	db := NewSessionDB()
+
+	now := time.Now()
+	assert.NoError(t, db.Insert(ctx, &session.Session{ID: "1", UserID: "user", TokenHash: []byte("hash1"), ExpiresAt: now.Add(-time.Minute)}))
+	assert.NoError(t, db.Insert(ctx, &session.Session{ID: "2", UserID: "user", TokenHash: []byte("hash2"), ExpiresAt: now.Add(time.Hour)}))
+	assert.NoError(t, db.Insert(ctx, &session.Session{ID: "3", UserID: "other", TokenHash: []byte("hash3"), ExpiresAt: now}))
+
+	assert.NoError(t, db.DeleteExpired(ctx, now))
+
+	sessions, err := db.ListByUserID(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, sessionIDs(sessions))
+	_, err = db.GetByTokenHash(ctx, []byte("hash1"))
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+	_, err = db.GetByID(ctx, "3")
+	assert.ErrorIs(t, err, session.ErrDoesNotExist)
+	assert.Len(t, db.sessions, 1)
+	assert.NotContains(t, db.byUserID, "other")
+}
+
+func sessionIDs(sessions []*session.Session) []string {
+	ids := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+
+	return ids
+}
@@ -1,19 +1,22 @@
 package memory
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/mabaro3009/example-architecture-go/user"
 )
 
 type userMem struct {
-	ID             string
-	Username       string
-	HashedPassword []byte
-	Role           string
-	CreatedAt      time.Time
-	DeletedAt      *time.Time
+	ID             string     `json:"id"`
+	Username       string     `json:"username"`
+	HashedPassword []byte     `json:"hashed_password"`
+	Role           string     `json:"role"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
 }
 
 func (u *userMem) ToDomain() *user.User {
@@ -27,14 +30,58 @@ func (u *userMem) ToDomain() *user.User {
 	}
 }
 
+// UserDB stores users in memory. Once a RoleDB is opened on it, users can only
+// be given the built-in roles and the roles stored there. It is safe for
+// concurrent use.
 type UserDB struct {
-	users map[string]*userMem
+	mu         sync.RWMutex
+	users      map[string]*userMem
+	byUsername map[string]*userMem
+	log        *userLog
+
+	// roles is locked before mu, so that a role cannot be deleted while it is
+	// given to a user.
+	roles *RoleDB
 }
 
 func NewUserDB() *UserDB {
 	return &UserDB{
-		users: make(map[string]*userMem),
+		users:      make(map[string]*userMem),
+		byUsername: make(map[string]*userMem),
+	}
+}
+
+// OpenUserDB returns a UserDB persisted in dir. The stored users are loaded on
+// open and every write is fsynced to disk before it is applied in memory.
+func OpenUserDB(dir string, snapshotInterval int) (*UserDB, error) {
+	l, err := openUserLog(dir, snapshotInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewUserDB()
+	if err = l.replay(m.put); err != nil {
+		_ = l.close()
+		return nil, err
 	}
+	m.log = l
+
+	return m, nil
+}
+
+// Close releases the files of a persisted UserDB. It is a no-op otherwise.
+func (m *UserDB) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.log == nil {
+		return nil
+	}
+
+	err := m.log.close()
+	m.log = nil
+
+	return err
 }
 
 func (m *UserDB) Insert(_ context.Context, params *user.InsertParams) error {
@@ -47,12 +94,121 @@ func (m *UserDB) Insert(_ context.Context, params *user.InsertParams) error {
 		DeletedAt:      nil,
 	}
 
-	m.users[params.ID] = u
+	unlock, err := m.lockRole(u.Role)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	return nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[u.ID]; ok {
+		return user.ErrIDAlreadyExists
+	}
+	if _, ok := m.byUsername[u.Username]; ok {
+		return user.ErrUsernameAlreadyExists
+	}
+
+	return m.save(u)
+}
+
+func (m *UserDB) Update(_ context.Context, params *user.UpdateParams) error {
+	if params.Role != nil {
+		unlock, err := m.lockRole(*params.Role)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.users[params.ID]
+	if !ok || old.DeletedAt != nil {
+		return user.ErrDoesNotExist
+	}
+
+	u := *old
+	if params.Username != nil {
+		if other, ok := m.byUsername[*params.Username]; ok && other.ID != u.ID {
+			return user.ErrUsernameAlreadyExists
+		}
+		u.Username = *params.Username
+	}
+	if params.Role != nil {
+		u.Role = *params.Role
+	}
+
+	return m.save(&u)
+}
+
+func (m *UserDB) UpdatePassword(_ context.Context, params *user.UpdatePasswordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.users[params.ID]
+	if !ok || old.DeletedAt != nil {
+		return user.ErrDoesNotExist
+	}
+	if params.OldHashedPassword != nil && !bytes.Equal(old.HashedPassword, params.OldHashedPassword) {
+		return user.ErrPasswordChanged
+	}
+
+	u := *old
+	u.HashedPassword = params.HashedPassword
+
+	return m.save(&u)
+}
+
+func (m *UserDB) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.users[id]
+	if !ok || old.DeletedAt != nil {
+		return user.ErrDoesNotExist
+	}
+
+	now := time.Now()
+	u := *old
+	u.DeletedAt = &now
+
+	return m.save(&u)
+}
+
+func (m *UserDB) Restore(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.users[id]
+	if !ok || old.DeletedAt == nil {
+		return user.ErrDoesNotExist
+	}
+
+	u := *old
+	u.DeletedAt = nil
+
+	return m.save(&u)
 }
 
 func (m *UserDB) GetByID(_ context.Context, id string) (*user.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return nil, user.ErrDoesNotExist
+	}
+
+	return u.ToDomain(), nil
+}
+
+func (m *UserDB) GetByIDIncludingDeleted(_ context.Context, id string) (*user.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	u, ok := m.users[id]
 	if !ok {
 		return nil, user.ErrDoesNotExist
@@ -62,11 +218,81 @@ func (m *UserDB) GetByID(_ context.Context, id string) (*user.User, error) {
 }
 
 func (m *UserDB) GetByUsername(_ context.Context, username string) (*user.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.byUsername[username]
+	if !ok || u.DeletedAt != nil {
+		return nil, user.ErrDoesNotExist
+	}
+
+	return u.ToDomain(), nil
+}
+
+// save durably records u, when persisted, and applies it. The caller must hold
+// the write lock.
+func (m *UserDB) save(u *userMem) error {
+	if m.log != nil {
+		if err := m.log.put(u); err != nil {
+			return err
+		}
+	}
+
+	m.put(u)
+
+	if m.log != nil && m.log.needsSnapshot() {
+		// The write is already in the log, so a failed snapshot only delays
+		// compaction until the next write.
+		_ = m.log.snapshot(m.all())
+	}
+
+	return nil
+}
+
+func (m *UserDB) put(u *userMem) {
+	if old, ok := m.users[u.ID]; ok && old.Username != u.Username {
+		delete(m.byUsername, old.Username)
+	}
+
+	m.users[u.ID] = u
+	m.byUsername[u.Username] = u
+}
+
+func (m *UserDB) all() []*userMem {
+	users := make([]*userMem, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+
+	return users
+}
+
+// lockRole keeps the role name from being deleted until unlock is called. It
+// returns ErrInvalidRole when the role does not exist.
+func (m *UserDB) lockRole(name string) (unlock func(), err error) {
+	if m.roles == nil {
+		return func() {}, nil
+	}
+
+	m.roles.mu.RLock()
+	if !m.roles.exists(name) {
+		m.roles.mu.RUnlock()
+		return nil, fmt.Errorf("%w: role %q does not exist", user.ErrInvalidRole, name)
+	}
+
+	return m.roles.mu.RUnlock, nil
+}
+
+// hasRole reports whether any user has the role name, deleted users included.
+func (m *UserDB) hasRole(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for _, u := range m.users {
-		if u.Username == username {
-			return u.ToDomain(), nil
+		if u.Role == name {
+			return true
 		}
 	}
 
-	return nil, user.ErrDoesNotExist
+	return false
 }
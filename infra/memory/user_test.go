@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/user/usertest"
+)
+
+func TestUserDB(t *testing.T) {
+	usertest.RunRepositoryTests(t, func(t *testing.T) usertest.Repository {
+		return NewUserDB()
+	})
+}
+
+func TestUserDB_File(t *testing.T) {
+	usertest.RunRepositoryTests(t, func(t *testing.T) usertest.Repository {
+		db, err := OpenUserDB(t.TempDir(), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		return db
+	})
+}
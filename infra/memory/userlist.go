@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+func (m *UserDB) List(_ context.Context, params *user.ListParams) (*user.ListResult, error) {
+	cursor, err := user.DecodeListCursor(params)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	matching := make([]*userMem, 0)
+	for _, u := range m.users {
+		if matchesListParams(u, params) {
+			matching = append(matching, u)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		return listLess(matching[i], matching[j], params)
+	})
+
+	start := 0
+	if cursor != nil {
+		after := &userMem{ID: cursor.ID, Username: cursor.Username, CreatedAt: cursor.CreatedAt}
+		start = sort.Search(len(matching), func(i int) bool {
+			return listLess(after, matching[i], params)
+		})
+	}
+
+	res := &user.ListResult{Users: make([]*user.User, 0, params.Limit)}
+	for i := start; i < len(matching) && len(res.Users) < params.Limit; i++ {
+		res.Users = append(res.Users, matching[i].ToDomain())
+	}
+
+	if len(res.Users) > 0 && start+len(res.Users) < len(matching) {
+		res.NextCursor = user.EncodeListCursor(res.Users[len(res.Users)-1], params)
+	}
+
+	return res, nil
+}
+
+func matchesListParams(u *userMem, params *user.ListParams) bool {
+	switch params.Deleted {
+	case user.DeletedExclude:
+		if u.DeletedAt != nil {
+			return false
+		}
+	case user.DeletedOnly:
+		if u.DeletedAt == nil {
+			return false
+		}
+	}
+
+	if params.Role != "" && u.Role != params.Role {
+		return false
+	}
+	if params.CreatedFrom != nil && u.CreatedAt.Before(*params.CreatedFrom) {
+		return false
+	}
+	if params.CreatedTo != nil && !u.CreatedAt.Before(*params.CreatedTo) {
+		return false
+	}
+
+	return strings.HasPrefix(u.Username, params.UsernamePrefix)
+}
+
+// listLess reports whether a comes before b in the order of the listing.
+func listLess(a, b *userMem, params *user.ListParams) bool {
+	if params.Descending {
+		a, b = b, a
+	}
+
+	switch params.Sort {
+	case user.SortUsername:
+		if a.Username != b.Username {
+			return a.Username < b.Username
+		}
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+
+	return a.ID < b.ID
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	userLogFile      = "users.wal"
+	userSnapshotFile = "users.snapshot"
+
+	DefaultSnapshotInterval = 1000
+)
+
+const opPut = "put"
+
+type userRecord struct {
+	Op   string   `json:"op"`
+	User *userMem `json:"user"`
+}
+
+// walFile is the file of the write-ahead log.
+type walFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// userLog persists the users of a UserDB in a directory. Every write is
+// appended to a write-ahead log and fsynced. After snapshotInterval writes the
+// whole state is written to a snapshot and the log is truncated.
+type userLog struct {
+	dir              string
+	wal              walFile
+	size             int64
+	writes           int
+	snapshotInterval int
+
+	// err is set when a failed write could not be undone, after which the log
+	// refuses every write.
+	err error
+}
+
+func openUserLog(dir string, snapshotInterval int) (*userLog, error) {
+	if snapshotInterval <= 0 {
+		snapshotInterval = DefaultSnapshotInterval
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, userLogFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userLog{
+		dir:              dir,
+		wal:              wal,
+		snapshotInterval: snapshotInterval,
+	}, nil
+}
+
+// replay calls apply for every user in the snapshot and then for every record
+// in the log. A partially written last record, left by a crash in the middle
+// of a write, is discarded, but any other unreadable record is an error since
+// the records after it were acknowledged.
+func (l *userLog) replay(apply func(u *userMem)) error {
+	snapshot, err := os.ReadFile(filepath.Join(l.dir, userSnapshotFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var users []*userMem
+		if err = json.Unmarshal(snapshot, &users); err != nil {
+			return fmt.Errorf("corrupted user snapshot: %w", err)
+		}
+		for _, u := range users {
+			apply(u)
+		}
+	}
+
+	if _, err = l.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var (
+		reader = bufio.NewReader(l.wal)
+		offset int64
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var r userRecord
+		if err = json.Unmarshal(bytes.TrimSpace(line), &r); err != nil || r.Op != opPut || r.User == nil {
+			if _, err = reader.Peek(1); errors.Is(err, io.EOF) {
+				break
+			}
+
+			return fmt.Errorf("corrupted user log at offset %d", offset)
+		}
+		apply(r.User)
+		offset += int64(len(line))
+		l.writes++
+	}
+
+	if err = l.wal.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err = l.wal.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	l.size = offset
+
+	return nil
+}
+
+// put durably records the new state of u. A failed write is undone, so that
+// the next record does not follow a partial one.
+func (l *userLog) put(u *userMem) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	buff, err := json.Marshal(&userRecord{Op: opPut, User: u})
+	if err != nil {
+		return err
+	}
+	buff = append(buff, '\n')
+
+	if _, err = l.wal.Write(buff); err != nil {
+		return l.rollback(err)
+	}
+	if err = l.wal.Sync(); err != nil {
+		return l.rollback(err)
+	}
+	l.size += int64(len(buff))
+	l.writes++
+
+	return nil
+}
+
+// rollback truncates the log back to its size before a write that failed with
+// cause.
+func (l *userLog) rollback(cause error) error {
+	err := l.wal.Truncate(l.size)
+	if err == nil {
+		_, err = l.wal.Seek(l.size, io.SeekStart)
+	}
+	if err == nil {
+		err = l.wal.Sync()
+	}
+	if err != nil {
+		l.err = fmt.Errorf("user log is unusable after a failed write (%v): %w", cause, err)
+	}
+
+	return cause
+}
+
+func (l *userLog) needsSnapshot() bool {
+	return l.writes >= l.snapshotInterval
+}
+
+// snapshot atomically replaces the snapshot with users and empties the log.
+func (l *userLog) snapshot(users []*userMem) error {
+	buff, err := json.Marshal(users)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(l.dir, userSnapshotFile+".tmp")
+	if err = writeFileSync(tmpPath, buff); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, filepath.Join(l.dir, userSnapshotFile)); err != nil {
+		return err
+	}
+	if err = syncDir(l.dir); err != nil {
+		return err
+	}
+
+	if err = l.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err = l.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err = l.wal.Sync(); err != nil {
+		return err
+	}
+	l.size = 0
+	l.writes = 0
+
+	return nil
+}
+
+func (l *userLog) close() error {
+	return l.wal.Close()
+}
+
+func writeFileSync(path string, buff []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(buff); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err = d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenUserDB_Reopen(t *testing.T) {
+	testCases := []struct {
+		description      string
+		snapshotInterval int
+	}{
+		{
+			description:      "log only",
+			snapshotInterval: 100,
+		},
+		{
+			description:      "snapshot and log",
+			snapshotInterval: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			db, err := OpenUserDB(dir, tc.snapshotInterval)
+			assert.NoError(t, err)
+			for i := 0; i < 5; i++ {
+				err = db.Insert(ctx, &user.InsertParams{
+					ID:             fmt.Sprint(i),
+					Username:       fmt.Sprintf("user-%d", i),
+					HashedPassword: []byte("hash"),
+					Role:           user.RoleAdmin,
+				})
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, db.Close())
+
+			db, err = OpenUserDB(dir, tc.snapshotInterval)
+			assert.NoError(t, err)
+			defer db.Close()
+
+			for i := 0; i < 5; i++ {
+				u, err := db.GetByUsername(ctx, fmt.Sprintf("user-%d", i))
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprint(i), u.ID)
+				assert.Equal(t, []byte("hash"), u.HashedPassword)
+				assert.Equal(t, user.Role(user.RoleAdmin), u.Role)
+				assert.False(t, u.CreatedAt.IsZero())
+			}
+
+			err = db.Insert(ctx, &user.InsertParams{ID: "0", Username: "other"})
+			assert.ErrorIs(t, err, user.ErrIDAlreadyExists)
+		})
+	}
+}
+
+func TestOpenUserDB_TornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc"}))
+	assert.NoError(t, db.Close())
+
+	f, err := os.OpenFile(filepath.Join(dir, userLogFile), os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"op":"put","user":{"id":"2","usern`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	db, err = OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+
+	_, err = db.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	_, err = db.GetByID(ctx, "2")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	assert.NoError(t, db.Insert(ctx, &user.InsertParams{ID: "3", Username: "def"}))
+	assert.NoError(t, db.Close())
+
+	db, err = OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.GetByID(ctx, "3")
+	assert.NoError(t, err)
+}
+
+func TestOpenUserDB_FailedWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeErr := errors.New("disk full")
+
+	db, err := OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc"}))
+
+	wal := db.log.wal
+	db.log.wal = &partialWAL{walFile: wal, err: writeErr}
+	err = db.Insert(ctx, &user.InsertParams{ID: "2", Username: "def"})
+	assert.ErrorIs(t, err, writeErr)
+	db.log.wal = wal
+
+	_, err = db.GetByID(ctx, "2")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	assert.NoError(t, db.Insert(ctx, &user.InsertParams{ID: "3", Username: "ghi"}))
+	assert.NoError(t, db.Close())
+
+	db, err = OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	_, err = db.GetByID(ctx, "2")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+	_, err = db.GetByID(ctx, "3")
+	assert.NoError(t, err)
+}
+
+func TestOpenUserDB_Corrupted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := OpenUserDB(dir, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc"}))
+	assert.NoError(t, db.Close())
+
+	f, err := os.OpenFile(filepath.Join(dir, userLogFile), os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(t, err)
+	_, err = f.WriteString("{\"op\":\"put\",\"user\":{\"id\":\"2\",\"usern\n{\"op\":\"put\",\"user\":{\"id\":\"3\",\"username\":\"ghi\"}}\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	_, err = OpenUserDB(dir, 100)
+	assert.Error(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, userLogFile))
+	assert.NoError(t, err)
+	assert.NotZero(t, info.Size())
+}
+
+// partialWAL writes half of every record and then fails.
+type partialWAL struct {
+	walFile
+	err error
+}
+
+func (w *partialWAL) Write(p []byte) (int, error) {
+	n, err := w.walFile.Write(p[:len(p)/2])
+	if err != nil {
+		return n, err
+	}
+
+	return n, w.err
+}
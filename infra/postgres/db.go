@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	// registers the "postgres" driver
+	_ "github.com/lib/pq"
+)
+
+type Config struct {
+	Host           string
+	Port           int
+	Name           string
+	User           string
+	Password       string
+	ConnectTimeout time.Duration
+
+	// SSLMode is the libpq sslmode, such as disable, require or verify-full.
+	// It defaults to require.
+	SSLMode string
+}
+
+func (c *Config) dsn() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:   c.Name,
+	}
+
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	q := url.Values{}
+	q.Set("sslmode", sslMode)
+	if c.ConnectTimeout > 0 {
+		q.Set("connect_timeout", fmt.Sprintf("%d", int(c.ConnectTimeout.Seconds())))
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+func Open(ctx context.Context, conf *Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", conf.dsn())
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.ConnectTimeout)
+		defer cancel()
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("could not connect to postgres: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSNSSLMode(t *testing.T) {
+	testCases := []struct {
+		description string
+		sslMode     string
+		exp         string
+	}{
+		{
+			description: "defaults to require",
+			exp:         "require",
+		},
+		{
+			description: "configured",
+			sslMode:     "verify-full",
+			exp:         "verify-full",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			conf := &Config{Host: "localhost", Port: 5432, Name: "postgres", User: "u", Password: "p", SSLMode: tc.sslMode}
+
+			u, err := url.Parse(conf.dsn())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, u.Query().Get("sslmode"))
+		})
+	}
+}
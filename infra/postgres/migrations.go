@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/mabaro3009/example-architecture-go/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsTableQuery looks the table up in the search path, like unqualified
+// queries do.
+const migrationsTableQuery = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db, fsys, migrationsTableQuery)
+}
@@ -0,0 +1,10 @@
+CREATE TABLE users (
+	id              TEXT        NOT NULL,
+	username        TEXT        NOT NULL,
+	hashed_password BYTEA       NOT NULL,
+	role            TEXT        NOT NULL,
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+	deleted_at      TIMESTAMPTZ,
+	CONSTRAINT users_pkey PRIMARY KEY (id),
+	CONSTRAINT users_username_key UNIQUE (username)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE roles (
+	name        TEXT        NOT NULL,
+	description TEXT        NOT NULL,
+	permissions TEXT[]      NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	CONSTRAINT roles_pkey PRIMARY KEY (name)
+);
@@ -0,0 +1,4 @@
+DROP INDEX users_role_idx;
+ALTER TABLE users DROP CONSTRAINT users_role_fkey;
+DELETE FROM roles WHERE builtin;
+ALTER TABLE roles DROP COLUMN builtin;
@@ -0,0 +1,14 @@
+ALTER TABLE roles ADD COLUMN builtin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The built-in roles are defined in the code. Their rows only exist so that
+-- users can reference them.
+INSERT INTO roles (name, description, permissions, created_at, updated_at, builtin)
+VALUES ('admin', '', '{}', now(), now(), TRUE), ('user', '', '{}', now(), now(), TRUE);
+
+-- Users can still have roles that were deleted before roles were checked. They
+-- are stored again, without permissions.
+INSERT INTO roles (name, description, permissions, created_at, updated_at)
+SELECT DISTINCT role, '', '{}', now(), now() FROM users WHERE role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles (name);
+CREATE INDEX users_role_idx ON users (role);
@@ -0,0 +1,13 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMigrator(t *testing.T) {
+	m, err := NewMigrator(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, m.Latest())
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mabaro3009/example-architecture-go/role"
+)
+
+const rolesPKey = "roles_pkey"
+
+const roleColumns = `name, description, permissions, created_at, updated_at`
+
+type roleRow struct {
+	Name        string
+	Description string
+	Permissions []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (r *roleRow) ToDomain() *role.Role {
+	permissions := make([]role.Permission, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		permissions = append(permissions, role.Permission(p))
+	}
+
+	return &role.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: permissions,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+// RoleDB stores custom roles in the roles table, which users reference. The
+// table also holds a row for each built-in role, which RoleDB leaves alone.
+type RoleDB struct {
+	db *sql.DB
+}
+
+func NewRoleDB(db *sql.DB) *RoleDB {
+	return &RoleDB{db: db}
+}
+
+func (p *RoleDB) Insert(ctx context.Context, r *role.Role) error {
+	const query = `INSERT INTO roles (` + roleColumns + `) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := p.db.ExecContext(ctx, query, r.Name, r.Description, permissionsArray(r.Permissions), r.CreatedAt, r.UpdatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == rolesPKey {
+		return fmt.Errorf("%w: %s", role.ErrAlreadyExists, pqErr.Message)
+	}
+
+	return err
+}
+
+func (p *RoleDB) Update(ctx context.Context, r *role.Role) error {
+	const query = `UPDATE roles SET description = $1, permissions = $2, updated_at = $3 WHERE name = $4 AND NOT builtin`
+
+	res, err := p.db.ExecContext(ctx, query, r.Description, permissionsArray(r.Permissions), r.UpdatedAt, r.Name)
+	if err != nil {
+		return err
+	}
+
+	return checkRoleAffected(res)
+}
+
+// Delete relies on the foreign key from users, which also makes giving the role
+// to a user wait for the delete, and the other way around.
+func (p *RoleDB) Delete(ctx context.Context, name string) error {
+	const query = `DELETE FROM roles WHERE name = $1 AND NOT builtin`
+
+	res, err := p.db.ExecContext(ctx, query, name)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && pqErr.Constraint == usersRoleFKey {
+		return fmt.Errorf("%w: %s", role.ErrInUse, pqErr.Message)
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkRoleAffected(res)
+}
+
+func (p *RoleDB) Get(ctx context.Context, name string) (*role.Role, error) {
+	const query = `SELECT ` + roleColumns + ` FROM roles WHERE name = $1 AND NOT builtin`
+
+	r, err := scanRole(p.db.QueryRowContext(ctx, query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, role.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (p *RoleDB) List(ctx context.Context) ([]*role.Role, error) {
+	const query = `SELECT ` + roleColumns + ` FROM roles WHERE NOT builtin ORDER BY name`
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*role.Role, 0)
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+
+	return roles, rows.Err()
+}
+
+func scanRole(row scanner) (*role.Role, error) {
+	var r roleRow
+	err := row.Scan(
+		&r.Name,
+		&r.Description,
+		pq.Array(&r.Permissions),
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ToDomain(), nil
+}
+
+func permissionsArray(permissions []role.Permission) interface{} {
+	values := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		values = append(values, string(p))
+	}
+
+	return pq.Array(values)
+}
+
+func checkRoleAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return role.ErrDoesNotExist
+	}
+
+	return nil
+}
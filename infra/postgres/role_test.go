@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/role/roletest"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+// TestRoleDB runs against the database at EXAMPLE_TEST_DB_HOST and is skipped
+// when it is not set. The users and the custom roles are deleted before every test.
+func TestRoleDB(t *testing.T) {
+	db := openTestDB(t)
+
+	roletest.RunRepositoryTests(t, func(t *testing.T) (roletest.Repository, roletest.GiveRole) {
+		if _, err := db.ExecContext(context.Background(), `TRUNCATE users; DELETE FROM roles WHERE NOT builtin`); err != nil {
+			t.Fatal(err)
+		}
+
+		users := NewUserDB(db)
+		n := 0
+		giveRole := func(ctx context.Context, name string, deleted bool) error {
+			n++
+			id := fmt.Sprint(n)
+			if err := users.Insert(ctx, &user.InsertParams{ID: id, Username: "user-" + id, HashedPassword: []byte{}, Role: name}); err != nil {
+				return err
+			}
+			if deleted {
+				return users.Delete(ctx, id)
+			}
+
+			return nil
+		}
+
+		return NewRoleDB(db), giveRole
+	})
+}
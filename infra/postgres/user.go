@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+
+	usersPKey        = "users_pkey"
+	usersUsernameKey = "users_username_key"
+	usersRoleFKey    = "users_role_fkey"
+)
+
+const userColumns = `id, username, hashed_password, role, created_at, deleted_at`
+
+type userRow struct {
+	ID             string
+	Username       string
+	HashedPassword []byte
+	Role           string
+	CreatedAt      time.Time
+	DeletedAt      sql.NullTime
+}
+
+func (u *userRow) ToDomain() *user.User {
+	var deletedAt *time.Time
+	if u.DeletedAt.Valid {
+		deletedAt = &u.DeletedAt.Time
+	}
+
+	return &user.User{
+		ID:             u.ID,
+		Username:       u.Username,
+		HashedPassword: u.HashedPassword,
+		Role:           user.Role(u.Role),
+		CreatedAt:      u.CreatedAt,
+		DeletedAt:      deletedAt,
+	}
+}
+
+type UserDB struct {
+	db *sql.DB
+}
+
+func NewUserDB(db *sql.DB) *UserDB {
+	return &UserDB{db: db}
+}
+
+func (p *UserDB) Insert(ctx context.Context, params *user.InsertParams) error {
+	const query = `INSERT INTO users (id, username, hashed_password, role) VALUES ($1, $2, $3, $4)`
+
+	_, err := p.db.ExecContext(ctx, query, params.ID, params.Username, params.HashedPassword, params.Role)
+
+	return mapViolation(err)
+}
+
+func (p *UserDB) Update(ctx context.Context, params *user.UpdateParams) error {
+	const query = `UPDATE users SET username = COALESCE($1, username), role = COALESCE($2, role) WHERE id = $3 AND deleted_at IS NULL`
+
+	res, err := p.db.ExecContext(ctx, query, params.Username, params.Role, params.ID)
+	if err != nil {
+		return mapViolation(err)
+	}
+
+	return checkAffected(res)
+}
+
+func (p *UserDB) UpdatePassword(ctx context.Context, params *user.UpdatePasswordParams) error {
+	if params.OldHashedPassword == nil {
+		const query = `UPDATE users SET hashed_password = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+		res, err := p.db.ExecContext(ctx, query, params.HashedPassword, params.ID)
+		if err != nil {
+			return err
+		}
+
+		return checkAffected(res)
+	}
+
+	const query = `UPDATE users SET hashed_password = $1 WHERE id = $2 AND deleted_at IS NULL AND hashed_password = $3`
+
+	res, err := p.db.ExecContext(ctx, query, params.HashedPassword, params.ID, params.OldHashedPassword)
+	if err != nil {
+		return err
+	}
+	if err = checkAffected(res); !errors.Is(err, user.ErrDoesNotExist) {
+		return err
+	}
+
+	// Nothing was updated: tell a missing user from a changed password.
+	if _, err = p.GetByID(ctx, params.ID); err != nil {
+		return err
+	}
+
+	return user.ErrPasswordChanged
+}
+
+func (p *UserDB) Delete(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+
+	res, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (p *UserDB) Restore(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	res, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (p *UserDB) GetByID(ctx context.Context, id string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	return p.getOne(ctx, query, id)
+}
+
+func (p *UserDB) GetByIDIncludingDeleted(ctx context.Context, id string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return p.getOne(ctx, query, id)
+}
+
+func (p *UserDB) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE username = $1 AND deleted_at IS NULL`
+
+	return p.getOne(ctx, query, username)
+}
+
+func (p *UserDB) getOne(ctx context.Context, query string, args ...interface{}) (*user.User, error) {
+	u, err := scanUser(p.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, user.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*user.User, error) {
+	var u userRow
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.HashedPassword,
+		&u.Role,
+		&u.CreatedAt,
+		&u.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.ToDomain(), nil
+}
+
+func mapViolation(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == uniqueViolation && pqErr.Constraint == usersPKey:
+		return fmt.Errorf("%w: %s", user.ErrIDAlreadyExists, pqErr.Message)
+	case pqErr.Code == uniqueViolation && pqErr.Constraint == usersUsernameKey:
+		return fmt.Errorf("%w: %s", user.ErrUsernameAlreadyExists, pqErr.Message)
+	case pqErr.Code == foreignKeyViolation && pqErr.Constraint == usersRoleFKey:
+		return fmt.Errorf("%w: %s", user.ErrInvalidRole, pqErr.Message)
+	default:
+		return err
+	}
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrDoesNotExist
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user/usertest"
+)
+
+// TestUserDB runs against the database at EXAMPLE_TEST_DB_HOST and is skipped
+// when it is not set. The users table is emptied before every test.
+func TestUserDB(t *testing.T) {
+	db := openTestDB(t)
+
+	usertest.RunRepositoryTests(t, func(t *testing.T) usertest.Repository {
+		if _, err := db.ExecContext(context.Background(), `TRUNCATE users`); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewUserDB(db)
+	})
+}
+
+// openTestDB opens and migrates the database at EXAMPLE_TEST_DB_HOST, skipping
+// the test when it is not set.
+func openTestDB(t *testing.T) *sql.DB {
+	host := os.Getenv("EXAMPLE_TEST_DB_HOST")
+	if host == "" {
+		t.Skip("EXAMPLE_TEST_DB_HOST is not set")
+	}
+
+	port, _ := strconv.Atoi(os.Getenv("EXAMPLE_TEST_DB_PORT"))
+	if port == 0 {
+		port = 5432
+	}
+
+	conf := &Config{
+		Host:           host,
+		Port:           port,
+		Name:           envOr("EXAMPLE_TEST_DB_NAME", "postgres"),
+		User:           envOr("EXAMPLE_TEST_DB_USER", "postgres"),
+		Password:       envOr("EXAMPLE_TEST_DB_PASSWORD", "postgres"),
+		ConnectTimeout: 5 * time.Second,
+		SSLMode:        envOr("EXAMPLE_TEST_DB_SSL_MODE", "disable"),
+	}
+
+	ctx := context.Background()
+	db, err := Open(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+func (p *UserDB) List(ctx context.Context, params *user.ListParams) (*user.ListResult, error) {
+	cursor, err := user.DecodeListCursor(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	switch params.Deleted {
+	case user.DeletedExclude:
+		conditions = append(conditions, "deleted_at IS NULL")
+	case user.DeletedOnly:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	}
+	if params.Role != "" {
+		conditions = append(conditions, "role = "+arg(params.Role))
+	}
+	if params.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+arg(*params.CreatedFrom))
+	}
+	if params.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+arg(*params.CreatedTo))
+	}
+	if params.UsernamePrefix != "" {
+		prefixLen := utf8.RuneCountInString(params.UsernamePrefix)
+		conditions = append(conditions, fmt.Sprintf("substr(username, 1, %s) = %s", arg(prefixLen), arg(params.UsernamePrefix)))
+	}
+
+	// The "C" collation orders by bytes, like the other backends do.
+	column := "created_at"
+	if params.Sort == user.SortUsername {
+		column = `username COLLATE "C"`
+	}
+	direction, comparison := "ASC", ">"
+	if params.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if cursor != nil {
+		var key interface{} = cursor.CreatedAt
+		if params.Sort == user.SortUsername {
+			key = cursor.Username
+		}
+		conditions = append(conditions, fmt.Sprintf(`(%s, id COLLATE "C") %s (%s, %s)`, column, comparison, arg(key), arg(cursor.ID)))
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, id COLLATE "C" %s LIMIT %s`, column, direction, direction, arg(params.Limit+1))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &user.ListResult{Users: make([]*user.User, 0, params.Limit)}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res.Users = append(res.Users, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Users) > params.Limit {
+		res.Users = res.Users[:params.Limit]
+		if params.Limit > 0 {
+			res.NextCursor = user.EncodeListCursor(res.Users[params.Limit-1], params)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	// registers the "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+const busyTimeoutMillis = 5000
+
+// Open opens the database file at path, creating it and its directory when
+// missing.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMillis))
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?%s", path, q.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite serializes writers anyway, and a single connection avoids
+	// SQLITE_BUSY errors between connections of the same process.
+	db.SetMaxOpenConns(1)
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("could not open sqlite database: %w", err)
+	}
+
+	return db, nil
+}
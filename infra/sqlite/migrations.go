@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/mabaro3009/example-architecture-go/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+const migrationsTableQuery = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`
+
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db, fsys, migrationsTableQuery)
+}
@@ -0,0 +1,8 @@
+CREATE TABLE users (
+	id              TEXT    NOT NULL PRIMARY KEY,
+	username        TEXT    NOT NULL UNIQUE,
+	hashed_password BLOB    NOT NULL,
+	role            TEXT    NOT NULL,
+	created_at      INTEGER NOT NULL,
+	deleted_at      INTEGER
+);
@@ -0,0 +1,7 @@
+CREATE TABLE roles (
+	name        TEXT    NOT NULL PRIMARY KEY,
+	description TEXT    NOT NULL,
+	permissions TEXT    NOT NULL,
+	created_at  INTEGER NOT NULL,
+	updated_at  INTEGER NOT NULL
+);
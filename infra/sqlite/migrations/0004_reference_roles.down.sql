@@ -0,0 +1,16 @@
+CREATE TABLE users_old (
+	id              TEXT    NOT NULL PRIMARY KEY,
+	username        TEXT    NOT NULL UNIQUE,
+	hashed_password BLOB    NOT NULL,
+	role            TEXT    NOT NULL,
+	created_at      INTEGER NOT NULL,
+	deleted_at      INTEGER
+);
+INSERT INTO users_old (id, username, hashed_password, role, created_at, deleted_at)
+SELECT id, username, hashed_password, role, created_at, deleted_at FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+
+DELETE FROM roles WHERE builtin;
+ALTER TABLE roles DROP COLUMN builtin;
@@ -0,0 +1,27 @@
+ALTER TABLE roles ADD COLUMN builtin INTEGER NOT NULL DEFAULT 0;
+
+-- The built-in roles are defined in the code. Their rows only exist so that
+-- users can reference them.
+INSERT INTO roles (name, description, permissions, created_at, updated_at, builtin)
+VALUES ('admin', '', '[]', 0, 0, 1), ('user', '', '[]', 0, 0, 1);
+
+-- Users can still have roles that were deleted before roles were checked. They
+-- are stored again, without permissions.
+INSERT INTO roles (name, description, permissions, created_at, updated_at)
+SELECT DISTINCT role, '', '[]', 0, 0 FROM users WHERE role NOT IN (SELECT name FROM roles);
+
+-- SQLite cannot add a foreign key to an existing table, so users is rebuilt.
+CREATE TABLE users_new (
+	id              TEXT    NOT NULL PRIMARY KEY,
+	username        TEXT    NOT NULL UNIQUE,
+	hashed_password BLOB    NOT NULL,
+	role            TEXT    NOT NULL REFERENCES roles (name),
+	created_at      INTEGER NOT NULL,
+	deleted_at      INTEGER
+);
+INSERT INTO users_new (id, username, hashed_password, role, created_at, deleted_at)
+SELECT id, username, hashed_password, role, created_at, deleted_at FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+CREATE INDEX users_role_idx ON users (role);
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/pkg/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, m.Check(ctx), migrate.ErrVersionMismatch)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, m.Latest())
+	for _, s := range statuses {
+		assert.False(t, s.Applied)
+	}
+
+	var tables int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'`).Scan(&tables)
+	assert.NoError(t, err)
+	assert.Zero(t, tables, "reading the version must not create the migrations table")
+
+	done, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, m.Latest())
+	assert.NoError(t, m.Check(ctx))
+
+	version, err := m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Latest(), version)
+
+	statuses, err = m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	done, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.ErrorIs(t, m.Check(ctx), migrate.ErrVersionMismatch)
+
+	done, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.NoError(t, m.Check(ctx))
+}
@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/role"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const roleColumns = `name, description, permissions, created_at, updated_at`
+
+// roleRow mirrors the roles table. Permissions are stored as a JSON array.
+type roleRow struct {
+	Name        string
+	Description string
+	Permissions string
+	CreatedAt   int64
+	UpdatedAt   int64
+}
+
+func (r *roleRow) ToDomain() (*role.Role, error) {
+	permissions := make([]role.Permission, 0)
+	if err := json.Unmarshal([]byte(r.Permissions), &permissions); err != nil {
+		return nil, fmt.Errorf("invalid permissions of role %q: %w", r.Name, err)
+	}
+
+	return &role.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: permissions,
+		CreatedAt:   time.Unix(0, r.CreatedAt),
+		UpdatedAt:   time.Unix(0, r.UpdatedAt),
+	}, nil
+}
+
+// RoleDB stores custom roles in the roles table, which users reference. The
+// table also holds a row for each built-in role, which RoleDB leaves alone.
+type RoleDB struct {
+	db *sql.DB
+}
+
+func NewRoleDB(db *sql.DB) *RoleDB {
+	return &RoleDB{db: db}
+}
+
+func (s *RoleDB) Insert(ctx context.Context, r *role.Role) error {
+	const query = `INSERT INTO roles (` + roleColumns + `) VALUES (?, ?, ?, ?, ?)`
+
+	permissions, err := marshalPermissions(r.Permissions)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, query, r.Name, r.Description, permissions, r.CreatedAt.UnixNano(), r.UpdatedAt.UnixNano())
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return fmt.Errorf("%w: %s", role.ErrAlreadyExists, sqliteErr.Error())
+	}
+
+	return err
+}
+
+func (s *RoleDB) Update(ctx context.Context, r *role.Role) error {
+	const query = `UPDATE roles SET description = ?, permissions = ?, updated_at = ? WHERE name = ? AND NOT builtin`
+
+	permissions, err := marshalPermissions(r.Permissions)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, query, r.Description, permissions, r.UpdatedAt.UnixNano(), r.Name)
+	if err != nil {
+		return err
+	}
+
+	return checkRoleAffected(res)
+}
+
+// Delete relies on the foreign key from users.
+func (s *RoleDB) Delete(ctx context.Context, name string) error {
+	const query = `DELETE FROM roles WHERE name = ? AND NOT builtin`
+
+	res, err := s.db.ExecContext(ctx, query, name)
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return fmt.Errorf("%w: %s", role.ErrInUse, sqliteErr.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkRoleAffected(res)
+}
+
+func (s *RoleDB) Get(ctx context.Context, name string) (*role.Role, error) {
+	const query = `SELECT ` + roleColumns + ` FROM roles WHERE name = ? AND NOT builtin`
+
+	r, err := scanRole(s.db.QueryRowContext(ctx, query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, role.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (s *RoleDB) List(ctx context.Context) ([]*role.Role, error) {
+	const query = `SELECT ` + roleColumns + ` FROM roles WHERE NOT builtin ORDER BY name`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*role.Role, 0)
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+
+	return roles, rows.Err()
+}
+
+func scanRole(row scanner) (*role.Role, error) {
+	var r roleRow
+	err := row.Scan(
+		&r.Name,
+		&r.Description,
+		&r.Permissions,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ToDomain()
+}
+
+func marshalPermissions(permissions []role.Permission) (string, error) {
+	if permissions == nil {
+		permissions = []role.Permission{}
+	}
+
+	buff, err := json.Marshal(permissions)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buff), nil
+}
+
+func checkRoleAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return role.ErrDoesNotExist
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/role/roletest"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+func TestRoleDB(t *testing.T) {
+	roletest.RunRepositoryTests(t, func(t *testing.T) (roletest.Repository, roletest.GiveRole) {
+		ctx := context.Background()
+		db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		m, err := NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = m.Up(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		users := NewUserDB(db)
+		n := 0
+		giveRole := func(ctx context.Context, name string, deleted bool) error {
+			n++
+			id := fmt.Sprint(n)
+			if err := users.Insert(ctx, &user.InsertParams{ID: id, Username: "user-" + id, Role: name}); err != nil {
+				return err
+			}
+			if deleted {
+				return users.Delete(ctx, id)
+			}
+
+			return nil
+		}
+
+		return NewRoleDB(db), giveRole
+	})
+}
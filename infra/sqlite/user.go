@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const userColumns = `id, username, hashed_password, role, created_at, deleted_at`
+
+// userRow mirrors the users table. Timestamps are stored as Unix nanoseconds
+// so they keep their precision and sort correctly.
+type userRow struct {
+	ID             string
+	Username       string
+	HashedPassword []byte
+	Role           string
+	CreatedAt      int64
+	DeletedAt      sql.NullInt64
+}
+
+func (u *userRow) ToDomain() *user.User {
+	var deletedAt *time.Time
+	if u.DeletedAt.Valid {
+		t := time.Unix(0, u.DeletedAt.Int64)
+		deletedAt = &t
+	}
+
+	return &user.User{
+		ID:             u.ID,
+		Username:       u.Username,
+		HashedPassword: u.HashedPassword,
+		Role:           user.Role(u.Role),
+		CreatedAt:      time.Unix(0, u.CreatedAt),
+		DeletedAt:      deletedAt,
+	}
+}
+
+type UserDB struct {
+	db *sql.DB
+}
+
+func NewUserDB(db *sql.DB) *UserDB {
+	return &UserDB{db: db}
+}
+
+func (s *UserDB) Insert(ctx context.Context, params *user.InsertParams) error {
+	const query = `INSERT INTO users (id, username, hashed_password, role, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	hashedPassword := params.HashedPassword
+	if hashedPassword == nil {
+		hashedPassword = []byte{}
+	}
+
+	_, err := s.db.ExecContext(ctx, query, params.ID, params.Username, hashedPassword, params.Role, time.Now().UnixNano())
+
+	return mapViolation(err)
+}
+
+func (s *UserDB) Update(ctx context.Context, params *user.UpdateParams) error {
+	const query = `UPDATE users SET username = COALESCE(?, username), role = COALESCE(?, role) WHERE id = ? AND deleted_at IS NULL`
+
+	res, err := s.db.ExecContext(ctx, query, params.Username, params.Role, params.ID)
+	if err != nil {
+		return mapViolation(err)
+	}
+
+	return checkAffected(res)
+}
+
+func (s *UserDB) UpdatePassword(ctx context.Context, params *user.UpdatePasswordParams) error {
+	if params.OldHashedPassword == nil {
+		const query = `UPDATE users SET hashed_password = ? WHERE id = ? AND deleted_at IS NULL`
+
+		res, err := s.db.ExecContext(ctx, query, params.HashedPassword, params.ID)
+		if err != nil {
+			return err
+		}
+
+		return checkAffected(res)
+	}
+
+	const query = `UPDATE users SET hashed_password = ? WHERE id = ? AND deleted_at IS NULL AND hashed_password = ?`
+
+	res, err := s.db.ExecContext(ctx, query, params.HashedPassword, params.ID, params.OldHashedPassword)
+	if err != nil {
+		return err
+	}
+	if err = checkAffected(res); !errors.Is(err, user.ErrDoesNotExist) {
+		return err
+	}
+
+	// Nothing was updated: tell a missing user from a changed password.
+	if _, err = s.GetByID(ctx, params.ID); err != nil {
+		return err
+	}
+
+	return user.ErrPasswordChanged
+}
+
+func (s *UserDB) Delete(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	res, err := s.db.ExecContext(ctx, query, time.Now().UnixNano(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (s *UserDB) Restore(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (s *UserDB) GetByID(ctx context.Context, id string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NULL`
+
+	return s.getOne(ctx, query, id)
+}
+
+func (s *UserDB) GetByIDIncludingDeleted(ctx context.Context, id string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	return s.getOne(ctx, query, id)
+}
+
+func (s *UserDB) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE username = ? AND deleted_at IS NULL`
+
+	return s.getOne(ctx, query, username)
+}
+
+func (s *UserDB) getOne(ctx context.Context, query string, args ...interface{}) (*user.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, user.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*user.User, error) {
+	var u userRow
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.HashedPassword,
+		&u.Role,
+		&u.CreatedAt,
+		&u.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.ToDomain(), nil
+}
+
+func mapViolation(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch {
+	case sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return fmt.Errorf("%w: %s", user.ErrIDAlreadyExists, sqliteErr.Error())
+	case sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteErr.Error(), "users.username"):
+		return fmt.Errorf("%w: %s", user.ErrUsernameAlreadyExists, sqliteErr.Error())
+	case sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		// The role is the only foreign key of users.
+		return fmt.Errorf("%w: %s", user.ErrInvalidRole, sqliteErr.Error())
+	default:
+		return err
+	}
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrDoesNotExist
+	}
+
+	return nil
+}
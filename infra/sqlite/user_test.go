@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/user/usertest"
+)
+
+func TestUserDB(t *testing.T) {
+	usertest.RunRepositoryTests(t, func(t *testing.T) usertest.Repository {
+		ctx := context.Background()
+		db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		m, err := NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = m.Up(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewUserDB(db)
+	})
+}
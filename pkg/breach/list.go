@@ -0,0 +1,188 @@
+// Package breach screens passwords against a local list of breached ones.
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrInvalidList = errors.New("invalid breached password list")
+
+// List is a set of SHA-1 hashes of breached passwords in a sorted file. The
+// file is binary searched on every lookup rather than loaded, so a list takes
+// the same little memory whatever its size. It is safe for concurrent use.
+type List struct {
+	f    *os.File
+	size int64
+	n    int
+}
+
+// Open opens the list in the file at path, which has the hex encoded SHA-1
+// hash of a password per line, optionally followed by a colon and the number of
+// times it was seen, as in the Pwned Passwords downloads ordered by hash. Empty
+// lines and lines starting with # are ignored. The hashes must be sorted, which
+// Open checks by reading the whole file once.
+func Open(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &List{f: f}
+	if err = l.check(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// check validates every line, counts the hashes and records the size of the
+// file to search.
+func (l *List) check() error {
+	var (
+		scanner = bufio.NewScanner(l.f)
+		prev    []byte
+		lineNum int
+	)
+	for scanner.Scan() {
+		lineNum++
+		h, ok, err := parseLine(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("%w: line %d is not a SHA-1 hash", ErrInvalidList, lineNum)
+		}
+		if !ok {
+			continue
+		}
+		if bytes.Compare(prev, h[:]) > 0 {
+			return fmt.Errorf("%w: line %d is not sorted", ErrInvalidList, lineNum)
+		}
+		prev = h[:]
+		l.n++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	info, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	l.size = info.Size()
+
+	return nil
+}
+
+// Contains reports whether password is in the list.
+func (l *List) Contains(password string) (bool, error) {
+	h := sha1.Sum([]byte(password))
+
+	// Every hash whose line starts before lo is smaller than h, and every hash
+	// whose line starts at or after hi is bigger.
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		got, start, end, err := l.hashAfter(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		switch c := bytes.Compare(got[:], h[:]); {
+		case c == 0:
+			return true, nil
+		case c < 0:
+			lo = end
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// hashAfter returns the first hash whose line starts at or after off, together
+// with the offsets where the line starts and ends. start is the size of the
+// file when there is no such hash.
+func (l *List) hashAfter(off int64) (h [sha1.Size]byte, start, end int64, err error) {
+	start = off
+	if off > 0 {
+		// Reading from the byte before off finds the first line that starts
+		// at or after it, even when off is the start of a line.
+		start--
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(l.f, start, l.size-start), 256)
+
+	if off > 0 {
+		skipped, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return h, l.size, l.size, nil
+		}
+		if err != nil {
+			return h, 0, 0, err
+		}
+		start += int64(len(skipped))
+	}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return h, 0, 0, err
+		}
+		if len(line) == 0 {
+			return h, l.size, l.size, nil
+		}
+		end = start + int64(len(line))
+
+		got, ok, parseErr := parseLine(line)
+		if parseErr != nil {
+			return h, 0, 0, fmt.Errorf("%w: changed since it was opened", ErrInvalidList)
+		}
+		if ok {
+			return got, start, end, nil
+		}
+		if err != nil {
+			return h, l.size, l.size, nil
+		}
+		start = end
+	}
+}
+
+// Len returns the number of passwords in the list.
+func (l *List) Len() int {
+	return l.n
+}
+
+// Close closes the file of the list.
+func (l *List) Close() error {
+	return l.f.Close()
+}
+
+// parseLine returns the hash in line, with ok false for lines to ignore.
+func parseLine(line []byte) (h [sha1.Size]byte, ok bool, err error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] == '#' {
+		return h, false, nil
+	}
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+
+	if len(line) != hex.EncodedLen(sha1.Size) {
+		return h, false, ErrInvalidList
+	}
+	if _, err = hex.Decode(h[:], line); err != nil {
+		return h, false, ErrInvalidList
+	}
+
+	return h, true, nil
+}
@@ -0,0 +1,113 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SHA-1 hash of "password".
+const passwordSHA1 = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		description string
+		content     string
+		expErr      error
+		expLen      int
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "hashes with counts, comments and blank lines",
+			content:     "# breached\n" + passwordSHA1 + ":9545824\n\n" + strings.Repeat("F", 40) + "\n",
+			expLen:      2,
+		},
+		{
+			description: "not a hash",
+			content:     passwordSHA1 + "\npassword\n",
+			expErr:      ErrInvalidList,
+		},
+		{
+			description: "not hex",
+			content:     strings.Repeat("z", 40) + "\n",
+			expErr:      ErrInvalidList,
+		},
+		{
+			description: "not sorted",
+			content:     passwordSHA1 + "\n" + strings.Repeat("0", 40) + "\n",
+			expErr:      ErrInvalidList,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breached.txt")
+			assert.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			l, err := Open(path)
+
+			assert.ErrorIs(t, err, tc.expErr)
+			if tc.expErr == nil {
+				assert.Equal(t, tc.expLen, l.Len())
+				assert.NoError(t, l.Close())
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := Open(filepath.Join(t.TempDir(), "missing.txt"))
+		assert.Error(t, err)
+	})
+}
+
+func TestList_Contains(t *testing.T) {
+	l, err := Open(filepath.Join("testdata", "breached.txt"))
+	assert.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, 6, l.Len())
+	for _, password := range []string{"password", "123456", "qwerty", "letmein"} {
+		found, err := l.Contains(password)
+		assert.NoError(t, err)
+		assert.True(t, found, password)
+	}
+	for _, password := range []string{"Password", "correct horse battery staple", ""} {
+		found, err := l.Contains(password)
+		assert.NoError(t, err)
+		assert.False(t, found, password)
+	}
+}
+
+// TestList_ContainsMany searches every hash of a list big enough for the
+// search to land in every part of a line.
+func TestList_ContainsMany(t *testing.T) {
+	var hashes []string
+	for i := 0; i < 1000; i++ {
+		hashes = append(hashes, fmt.Sprintf("%X:%d", sha1.Sum([]byte(fmt.Sprint("password-", i))), i))
+	}
+	sort.Strings(hashes)
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(hashes, "\n")), 0o600))
+
+	l, err := Open(path)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 1000; i++ {
+		found, err := l.Contains(fmt.Sprint("password-", i))
+		assert.NoError(t, err)
+		assert.True(t, found, i)
+
+		found, err = l.Contains(fmt.Sprint("other-", i))
+		assert.NoError(t, err)
+		assert.False(t, found, i)
+	}
+}
@@ -0,0 +1,120 @@
+package hash
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams are the cost parameters of Argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the second recommended option of RFC 9106
+// with a higher iteration count.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func (p Argon2idParams) validate() error {
+	switch {
+	case p.Iterations < 1:
+		return fmt.Errorf("%w: argon2id needs at least 1 iteration", ErrInvalidParams)
+	case p.Parallelism < 1:
+		return fmt.Errorf("%w: argon2id needs a parallelism of at least 1", ErrInvalidParams)
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("%w: argon2id needs at least 8 KiB of memory per lane", ErrInvalidParams)
+	case p.SaltLength < minSaltLength:
+		return fmt.Errorf("%w: salts must be at least %d bytes long", ErrInvalidParams, minSaltLength)
+	case p.KeyLength < minKeyLength:
+		return fmt.Errorf("%w: keys must be at least %d bytes long", ErrInvalidParams, minKeyLength)
+	}
+
+	return nil
+}
+
+// Argon2id hashes passwords with Argon2id and encodes them in the PHC string
+// format, $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(p Argon2idParams) (*Argon2id, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Argon2id{params: p}, nil
+}
+
+func (h *Argon2id) Hash(password string) ([]byte, error) {
+	salt, err := newSalt(h.params.SaltLength)
+	if err != nil {
+		return nil, err
+	}
+
+	p := h.params
+	encoded := &phcHash{
+		id:      AlgorithmArgon2id,
+		version: argon2.Version,
+		params: map[string]uint64{
+			"m": uint64(p.Memory),
+			"t": uint64(p.Iterations),
+			"p": uint64(p.Parallelism),
+		},
+		salt: salt,
+		hash: argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength),
+	}
+
+	return []byte(encoded.String()), nil
+}
+
+// Verify returns ErrMismatchedPassword when hashedPassword is not the
+// Argon2id hash of password. The parameters are read from hashedPassword, so
+// hashes made with other parameters still verify.
+func (h *Argon2id) Verify(hashedPassword []byte, password string) error {
+	encoded, err := parsePHC(hashedPassword, AlgorithmArgon2id)
+	if err != nil {
+		return err
+	}
+
+	m, t, p := encoded.params["m"], encoded.params["t"], encoded.params["p"]
+	if encoded.version != argon2.Version || t < 1 || p < 1 || p > math.MaxUint8 || m < 8*p {
+		return fmt.Errorf("%w: unsupported argon2id parameters", ErrInvalidHash)
+	}
+
+	key := argon2.IDKey([]byte(password), encoded.salt, uint32(t), uint32(m), uint8(p), uint32(len(encoded.hash)))
+	if subtle.ConstantTimeCompare(key, encoded.hash) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether hashedPassword is not an Argon2id hash of the
+// configured parameters.
+func (h *Argon2id) NeedsRehash(hashedPassword []byte) bool {
+	encoded, err := parsePHC(hashedPassword, AlgorithmArgon2id)
+	if err != nil {
+		return true
+	}
+
+	p := h.params
+	return encoded.version != argon2.Version ||
+		encoded.params["m"] != uint64(p.Memory) ||
+		encoded.params["t"] != uint64(p.Iterations) ||
+		encoded.params["p"] != uint64(p.Parallelism) ||
+		len(encoded.salt) != int(p.SaltLength) ||
+		len(encoded.hash) != int(p.KeyLength)
+}
@@ -0,0 +1,207 @@
+package hash
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestNewArgon2id(t *testing.T) {
+	testCases := []struct {
+		description string
+		params      func(p *Argon2idParams)
+		expErr      error
+	}{
+		{
+			description: "no iterations",
+			params:      func(p *Argon2idParams) { p.Iterations = 0 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "no parallelism",
+			params:      func(p *Argon2idParams) { p.Parallelism = 0 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "not enough memory",
+			params:      func(p *Argon2idParams) { p.Memory = 15 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "short salt",
+			params:      func(p *Argon2idParams) { p.SaltLength = 4 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "short key",
+			params:      func(p *Argon2idParams) { p.KeyLength = 8 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "success",
+			params:      func(p *Argon2idParams) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			p := testArgon2idParams
+			tc.params(&p)
+
+			_, err := NewArgon2id(p)
+
+			assert.ErrorIs(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestArgon2id_Hash(t *testing.T) {
+	h, err := NewArgon2id(testArgon2idParams)
+	assert.NoError(t, err)
+
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=2\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), string(hashed))
+
+	other, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashed, other)
+}
+
+func TestArgon2id_Verify(t *testing.T) {
+	h, err := NewArgon2id(testArgon2idParams)
+	assert.NoError(t, err)
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+
+	// Test vector of the reference implementation.
+	reference := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	testCases := []struct {
+		description string
+		hashed      string
+		password    string
+		expErr      error
+	}{
+		{
+			description: "success",
+			hashed:      string(hashed),
+			password:    "password",
+		},
+		{
+			description: "other parameters",
+			hashed:      reference,
+			password:    "password",
+		},
+		{
+			description: "mismatch",
+			hashed:      string(hashed),
+			password:    "other",
+			expErr:      ErrMismatchedPassword,
+		},
+		{
+			description: "other algorithm",
+			hashed:      "$scrypt$ln=4,r=8,p=1$c29tZXNhbHQ$aTRSodt0aG/qdDwGvBJmwaR2HhZ5svY6O7K+wpxuw0w",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "other version",
+			hashed:      "$argon2id$v=16$m=64,t=2,p=1$c29tZXNhbHQ$aTRSodt0aG/qdDwGvBJmwaR2HhZ5svY6O7K+wpxuw0w",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "missing parameter",
+			hashed:      "$argon2id$v=19$m=64,t=2$c29tZXNhbHQ$aTRSodt0aG/qdDwGvBJmwaR2HhZ5svY6O7K+wpxuw0w",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "zero parallelism",
+			hashed:      "$argon2id$v=19$m=64,t=2,p=0$c29tZXNhbHQ$aTRSodt0aG/qdDwGvBJmwaR2HhZ5svY6O7K+wpxuw0w",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "bad salt",
+			hashed:      "$argon2id$v=19$m=64,t=2,p=1$!!$aTRSodt0aG/qdDwGvBJmwaR2HhZ5svY6O7K+wpxuw0w",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "garbage",
+			hashed:      "not a hash",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := h.Verify([]byte(tc.hashed), tc.password)
+
+			assert.ErrorIs(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestArgon2id_NeedsRehash(t *testing.T) {
+	h, err := NewArgon2id(testArgon2idParams)
+	assert.NoError(t, err)
+	current, err := h.Hash("password")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		description string
+		params      func(p *Argon2idParams)
+		expRehash   bool
+	}{
+		{
+			description: "same parameters",
+			params:      func(p *Argon2idParams) {},
+		},
+		{
+			description: "other memory",
+			params:      func(p *Argon2idParams) { p.Memory *= 2 },
+			expRehash:   true,
+		},
+		{
+			description: "other iterations",
+			params:      func(p *Argon2idParams) { p.Iterations++ },
+			expRehash:   true,
+		},
+		{
+			description: "other parallelism",
+			params:      func(p *Argon2idParams) { p.Parallelism++ },
+			expRehash:   true,
+		},
+		{
+			description: "other key length",
+			params:      func(p *Argon2idParams) { p.KeyLength++ },
+			expRehash:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			p := testArgon2idParams
+			tc.params(&p)
+			other, err := NewArgon2id(p)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expRehash, other.NeedsRehash(current))
+		})
+	}
+
+	assert.True(t, h.NeedsRehash([]byte("$2a$04$abcdefghijklmnopqrstuu")))
+}
@@ -1,27 +1,72 @@
 package hash
 
 import (
+	"errors"
+	"fmt"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+// BCryptMaxPasswordBytes is the length after which bcrypt ignores the rest of
+// a password.
+const BCryptMaxPasswordBytes = 72
+
+// ErrPasswordTooLong is returned when hashing a password that bcrypt would
+// truncate.
+var ErrPasswordTooLong = fmt.Errorf("password is longer than %d bytes", BCryptMaxPasswordBytes)
+
 type BCrypt struct {
 	cost int
 }
 
+// NewBCrypt returns a bcrypt hasher. Costs out of range fall back to
+// bcrypt.DefaultCost.
 func NewBCrypt(cost int) *BCrypt {
-	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+	if validateBCryptCost(cost) != nil {
 		cost = bcrypt.DefaultCost
 	}
 
 	return &BCrypt{cost: cost}
 }
 
+// Hash returns ErrPasswordTooLong rather than hash only the start of password.
 func (h *BCrypt) Hash(password string) ([]byte, error) {
 	pass := []byte(password)
-	hashedPassword, err := bcrypt.GenerateFromPassword(pass, bcrypt.DefaultCost)
+	if len(pass) > BCryptMaxPasswordBytes {
+		return nil, ErrPasswordTooLong
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword(pass, h.cost)
 	if err != nil {
 		return nil, err
 	}
 
 	return hashedPassword, nil
 }
+
+// Verify returns ErrMismatchedPassword when hashedPassword is not the bcrypt
+// hash of password.
+func (h *BCrypt) Verify(hashedPassword []byte, password string) error {
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+
+	return err
+}
+
+// NeedsRehash reports whether hashedPassword is not a bcrypt hash of the
+// configured cost.
+func (h *BCrypt) NeedsRehash(hashedPassword []byte) bool {
+	cost, err := bcrypt.Cost(hashedPassword)
+
+	return err != nil || cost != h.cost
+}
+
+func validateBCryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("%w: bcrypt cost must be between %d and %d", ErrInvalidParams, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBCrypt_Hash(t *testing.T) {
+	testCases := []struct {
+		description string
+		cost        int
+		expCost     int
+	}{
+		{
+			description: "configured cost",
+			cost:        bcrypt.MinCost + 1,
+			expCost:     bcrypt.MinCost + 1,
+		},
+		{
+			description: "cost out of range",
+			cost:        bcrypt.MaxCost + 1,
+			expCost:     bcrypt.DefaultCost,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			hashed, err := NewBCrypt(tc.cost).Hash("password")
+			assert.NoError(t, err)
+
+			cost, err := bcrypt.Cost(hashed)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expCost, cost)
+		})
+	}
+}
+
+func TestBCrypt_HashTooLong(t *testing.T) {
+	h := NewBCrypt(bcrypt.MinCost)
+
+	_, err := h.Hash(strings.Repeat("a", BCryptMaxPasswordBytes))
+	assert.NoError(t, err)
+
+	_, err = h.Hash(strings.Repeat("a", BCryptMaxPasswordBytes+1))
+	assert.ErrorIs(t, err, ErrPasswordTooLong)
+}
+
+func TestBCrypt_Verify(t *testing.T) {
+	h := NewBCrypt(bcrypt.MinCost)
+
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+
+	assert.NoError(t, h.Verify(hashed, "password"))
+	assert.ErrorIs(t, h.Verify(hashed, "other"), ErrMismatchedPassword)
+	assert.Error(t, h.Verify([]byte("not a hash"), "password"))
+}
+
+func TestBCrypt_NeedsRehash(t *testing.T) {
+	h := NewBCrypt(bcrypt.MinCost)
+	current, err := h.Hash("password")
+	assert.NoError(t, err)
+	cheaper, err := NewBCrypt(bcrypt.MinCost + 1).Hash("password")
+	assert.NoError(t, err)
+
+	assert.False(t, h.NeedsRehash(current))
+	assert.True(t, h.NeedsRehash(cheaper))
+	assert.True(t, h.NeedsRehash([]byte("$scrypt$ln=4,r=8,p=1$c2FsdA$aGFzaA")))
+}
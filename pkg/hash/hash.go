@@ -0,0 +1,79 @@
+package hash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	AlgorithmBCrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+
+	minSaltLength = 8
+	minKeyLength  = 16
+)
+
+var (
+	ErrMismatchedPassword = errors.New("hashed password is not the hash of the given password")
+	ErrInvalidHash        = errors.New("invalid password hash")
+	ErrInvalidParams      = errors.New("invalid hashing parameters")
+	ErrUnknownAlgorithm   = errors.New("unknown hashing algorithm. Valid algorithms are bcrypt, argon2id and scrypt")
+)
+
+// PasswordVerifier checks a password against a hash produced by the matching
+// hasher.
+type PasswordVerifier interface {
+	Verify(hashedPassword []byte, password string) error
+}
+
+// Hasher hashes passwords and verifies them against its own hashes.
+// NeedsRehash reports whether a hash was made by another algorithm or with
+// other parameters than the ones the hasher is configured with.
+type Hasher interface {
+	Hash(password string) ([]byte, error)
+	NeedsRehash(hashedPassword []byte) bool
+	PasswordVerifier
+}
+
+// Params holds the parameters of every algorithm. Only the ones of the
+// selected algorithm are used.
+type Params struct {
+	BCryptCost int
+	Argon2id   Argon2idParams
+	Scrypt     ScryptParams
+}
+
+// New returns the hasher of algorithm configured with p.
+func New(algorithm string, p Params) (Hasher, error) {
+	switch algorithm {
+	case AlgorithmBCrypt:
+		if err := validateBCryptCost(p.BCryptCost); err != nil {
+			return nil, err
+		}
+		return NewBCrypt(p.BCryptCost), nil
+	case AlgorithmArgon2id:
+		return NewArgon2id(p.Argon2id)
+	case AlgorithmScrypt:
+		return NewScrypt(p.Scrypt)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// Identify returns the algorithm that made hashedPassword.
+func Identify(hashedPassword []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(hashedPassword, []byte("$2a$")),
+		bytes.HasPrefix(hashedPassword, []byte("$2b$")),
+		bytes.HasPrefix(hashedPassword, []byte("$2y$")):
+		return AlgorithmBCrypt, nil
+	case bytes.HasPrefix(hashedPassword, []byte("$"+AlgorithmArgon2id+"$")):
+		return AlgorithmArgon2id, nil
+	case bytes.HasPrefix(hashedPassword, []byte("$"+AlgorithmScrypt+"$")):
+		return AlgorithmScrypt, nil
+	default:
+		return "", ErrInvalidHash
+	}
+}
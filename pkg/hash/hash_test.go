@@ -0,0 +1,83 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNew(t *testing.T) {
+	params := Params{
+		BCryptCost: bcrypt.MinCost,
+		Argon2id:   testArgon2idParams,
+		Scrypt:     testScryptParams,
+	}
+
+	testCases := []struct {
+		description string
+		algorithm   string
+		params      Params
+		expErr      error
+		expPrefix   string
+	}{
+		{
+			description: "unknown algorithm",
+			algorithm:   "md5",
+			params:      params,
+			expErr:      ErrUnknownAlgorithm,
+		},
+		{
+			description: "invalid bcrypt cost",
+			algorithm:   AlgorithmBCrypt,
+			params:      Params{BCryptCost: bcrypt.MaxCost + 1},
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "invalid argon2id params",
+			algorithm:   AlgorithmArgon2id,
+			params:      Params{},
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "invalid scrypt params",
+			algorithm:   AlgorithmScrypt,
+			params:      Params{},
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "bcrypt",
+			algorithm:   AlgorithmBCrypt,
+			params:      params,
+			expPrefix:   "$2a$04$",
+		},
+		{
+			description: "argon2id",
+			algorithm:   AlgorithmArgon2id,
+			params:      params,
+			expPrefix:   "$argon2id$",
+		},
+		{
+			description: "scrypt",
+			algorithm:   AlgorithmScrypt,
+			params:      params,
+			expPrefix:   "$scrypt$",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			h, err := New(tc.algorithm, tc.params)
+			assert.ErrorIs(t, err, tc.expErr)
+			if tc.expErr != nil {
+				return
+			}
+
+			hashed, err := h.Hash("password")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(hashed), tc.expPrefix), string(hashed))
+			assert.NoError(t, h.Verify(hashed, "password"))
+		})
+	}
+}
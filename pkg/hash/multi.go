@@ -0,0 +1,43 @@
+package hash
+
+// MultiHasher hashes new passwords with its current hasher and verifies
+// hashes of every supported algorithm, whatever their parameters. Hashes that
+// were not made by the current hasher with its parameters need a rehash, so
+// stored passwords can be migrated to new algorithms or costs as their users
+// log in.
+type MultiHasher struct {
+	current   Hasher
+	verifiers map[string]PasswordVerifier
+}
+
+func NewMultiHasher(current Hasher) *MultiHasher {
+	// Verification reads the parameters from the hash, so the parameters of
+	// these hashers do not matter.
+	return &MultiHasher{
+		current: current,
+		verifiers: map[string]PasswordVerifier{
+			AlgorithmBCrypt:   NewBCrypt(0),
+			AlgorithmArgon2id: &Argon2id{},
+			AlgorithmScrypt:   &Scrypt{},
+		},
+	}
+}
+
+func (h *MultiHasher) Hash(password string) ([]byte, error) {
+	return h.current.Hash(password)
+}
+
+// Verify returns ErrMismatchedPassword when hashedPassword is not the hash of
+// password and ErrInvalidHash when its algorithm is not supported.
+func (h *MultiHasher) Verify(hashedPassword []byte, password string) error {
+	algorithm, err := Identify(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	return h.verifiers[algorithm].Verify(hashedPassword, password)
+}
+
+func (h *MultiHasher) NeedsRehash(hashedPassword []byte) bool {
+	return h.current.NeedsRehash(hashedPassword)
+}
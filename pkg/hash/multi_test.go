@@ -0,0 +1,78 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestIdentify(t *testing.T) {
+	testCases := []struct {
+		hashed       string
+		expAlgorithm string
+		expErr       error
+	}{
+		{"$2a$10$abc", AlgorithmBCrypt, nil},
+		{"$2b$10$abc", AlgorithmBCrypt, nil},
+		{"$2y$10$abc", AlgorithmBCrypt, nil},
+		{"$argon2id$v=19$m=64,t=1,p=1$abc$def", AlgorithmArgon2id, nil},
+		{"$scrypt$ln=4,r=8,p=1$abc$def", AlgorithmScrypt, nil},
+		{"$argon2i$v=19$m=64,t=1,p=1$abc$def", "", ErrInvalidHash},
+		{"plain", "", ErrInvalidHash},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hashed, func(t *testing.T) {
+			algorithm, err := Identify([]byte(tc.hashed))
+
+			assert.ErrorIs(t, err, tc.expErr)
+			assert.Equal(t, tc.expAlgorithm, algorithm)
+		})
+	}
+}
+
+func TestMultiHasher(t *testing.T) {
+	argon2id, err := NewArgon2id(testArgon2idParams)
+	assert.NoError(t, err)
+	scrypt, err := NewScrypt(testScryptParams)
+	assert.NoError(t, err)
+	h := NewMultiHasher(argon2id)
+
+	testCases := []struct {
+		description string
+		hasher      Hasher
+		expRehash   bool
+	}{
+		{
+			description: "current",
+			hasher:      argon2id,
+		},
+		{
+			description: "bcrypt",
+			hasher:      NewBCrypt(bcrypt.MinCost),
+			expRehash:   true,
+		},
+		{
+			description: "scrypt",
+			hasher:      scrypt,
+			expRehash:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			hashed, err := tc.hasher.Hash("password")
+			assert.NoError(t, err)
+
+			assert.NoError(t, h.Verify(hashed, "password"))
+			assert.ErrorIs(t, h.Verify(hashed, "other"), ErrMismatchedPassword)
+			assert.Equal(t, tc.expRehash, h.NeedsRehash(hashed))
+		})
+	}
+
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.NoError(t, argon2id.Verify(hashed, "password"))
+	assert.ErrorIs(t, h.Verify([]byte("plain"), "plain"), ErrInvalidHash)
+}
@@ -0,0 +1,116 @@
+package hash
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+)
+
+const (
+	pepperPrefix = "$pepper$k="
+
+	minPepperKeyLength = 32
+)
+
+var pepperKeyIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+var (
+	ErrInvalidPepper    = fmt.Errorf("%w: pepper keys need an ID of 1 to 32 letters, digits, _ or - and at least %d bytes", ErrInvalidParams, minPepperKeyLength)
+	ErrUnknownPepperKey = fmt.Errorf("%w: unknown pepper key", ErrInvalidHash)
+)
+
+// Pepper applies an HMAC-SHA256 keyed with a server side secret to passwords
+// before they are hashed by the inner hasher, so a leaked database is not
+// enough to crack them. The ID of the key is stored in front of the inner hash,
+// $pepper$k=<key id><inner hash>, so several keys can coexist while passwords
+// move to the current one. Hashes without a pepper are still verified.
+type Pepper struct {
+	inner   Hasher
+	current string
+	keys    map[string][]byte
+}
+
+// NewPepper returns a Pepper that peppers new hashes with the key
+// currentKeyID of keys.
+func NewPepper(inner Hasher, currentKeyID string, keys map[string][]byte) (*Pepper, error) {
+	for id, key := range keys {
+		if !pepperKeyIDRegexp.MatchString(id) || len(key) < minPepperKeyLength {
+			return nil, fmt.Errorf("%w: key %q", ErrInvalidPepper, id)
+		}
+	}
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("%w: the current key %q is not configured", ErrInvalidParams, currentKeyID)
+	}
+
+	copied := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		copied[id] = append([]byte(nil), key...)
+	}
+
+	return &Pepper{
+		inner:   inner,
+		current: currentKeyID,
+		keys:    copied,
+	}, nil
+}
+
+func (h *Pepper) Hash(password string) ([]byte, error) {
+	hashedPassword, err := h.inner.Hash(h.pepper(h.keys[h.current], password))
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(pepperPrefix+h.current), hashedPassword...), nil
+}
+
+// Verify returns ErrMismatchedPassword when hashedPassword is not the hash of
+// password and ErrUnknownPepperKey when it was peppered with a key that is
+// not configured.
+func (h *Pepper) Verify(hashedPassword []byte, password string) error {
+	keyID, inner, ok := splitPepper(hashedPassword)
+	if !ok {
+		return h.inner.Verify(hashedPassword, password)
+	}
+
+	key, ok := h.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownPepperKey, keyID)
+	}
+
+	return h.inner.Verify(inner, h.pepper(key, password))
+}
+
+// NeedsRehash reports whether hashedPassword is not peppered with the current
+// key or its inner hash needs a rehash.
+func (h *Pepper) NeedsRehash(hashedPassword []byte) bool {
+	keyID, inner, ok := splitPepper(hashedPassword)
+
+	return !ok || keyID != h.current || h.inner.NeedsRehash(inner)
+}
+
+// pepper returns the HMAC of password encoded in base64, which keeps it
+// printable and below the 72 bytes bcrypt uses.
+func (h *Pepper) pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// splitPepper returns the key ID and the inner hash of a peppered hash.
+func splitPepper(hashedPassword []byte) (string, []byte, bool) {
+	if !bytes.HasPrefix(hashedPassword, []byte(pepperPrefix)) {
+		return "", nil, false
+	}
+
+	rest := hashedPassword[len(pepperPrefix):]
+	i := bytes.IndexByte(rest, '$')
+	if i <= 0 {
+		return "", nil, false
+	}
+
+	return string(rest[:i]), rest[i:], true
+}
@@ -0,0 +1,139 @@
+package hash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	testPepperKey1 = bytes.Repeat([]byte("1"), 32)
+	testPepperKey2 = bytes.Repeat([]byte("2"), 32)
+)
+
+func TestNewPepper(t *testing.T) {
+	testCases := []struct {
+		description string
+		current     string
+		keys        map[string][]byte
+		expErr      error
+	}{
+		{
+			description: "short key",
+			current:     "k1",
+			keys:        map[string][]byte{"k1": []byte("short")},
+			expErr:      ErrInvalidPepper,
+		},
+		{
+			description: "invalid key id",
+			current:     "k 1",
+			keys:        map[string][]byte{"k 1": testPepperKey1},
+			expErr:      ErrInvalidPepper,
+		},
+		{
+			description: "missing current key",
+			current:     "k3",
+			keys:        map[string][]byte{"k1": testPepperKey1},
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "success",
+			current:     "k1",
+			keys:        map[string][]byte{"k1": testPepperKey1, "k2": testPepperKey2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := NewPepper(NewBCrypt(bcrypt.MinCost), tc.current, tc.keys)
+
+			assert.ErrorIs(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestPepper(t *testing.T) {
+	inner := NewMultiHasher(NewBCrypt(bcrypt.MinCost))
+	keys := map[string][]byte{"k1": testPepperKey1, "k2": testPepperKey2}
+	old, err := NewPepper(inner, "k1", keys)
+	assert.NoError(t, err)
+	current, err := NewPepper(inner, "k2", keys)
+	assert.NoError(t, err)
+	withoutOld, err := NewPepper(inner, "k2", map[string][]byte{"k2": testPepperKey2})
+	assert.NoError(t, err)
+
+	hashed, err := current.Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(hashed), "$pepper$k=k2$2a$04$"), string(hashed))
+	oldHashed, err := old.Hash("password")
+	assert.NoError(t, err)
+	unpeppered, err := inner.Hash("password")
+	assert.NoError(t, err)
+	otherKey := append([]byte("$pepper$k=k1"), hashed[len("$pepper$k=k2"):]...)
+
+	testCases := []struct {
+		description string
+		hasher      *Pepper
+		hashed      []byte
+		password    string
+		expErr      error
+		expRehash   bool
+	}{
+		{
+			description: "current key",
+			hasher:      current,
+			hashed:      hashed,
+			password:    "password",
+		},
+		{
+			description: "mismatch",
+			hasher:      current,
+			hashed:      hashed,
+			password:    "other",
+			expErr:      ErrMismatchedPassword,
+		},
+		{
+			description: "old key",
+			hasher:      current,
+			hashed:      oldHashed,
+			password:    "password",
+			expRehash:   true,
+		},
+		{
+			description: "unpeppered",
+			hasher:      current,
+			hashed:      unpeppered,
+			password:    "password",
+			expRehash:   true,
+		},
+		{
+			description: "wrong key",
+			hasher:      current,
+			hashed:      otherKey,
+			password:    "password",
+			expErr:      ErrMismatchedPassword,
+			expRehash:   true,
+		},
+		{
+			description: "removed key",
+			hasher:      withoutOld,
+			hashed:      oldHashed,
+			password:    "password",
+			expErr:      ErrUnknownPepperKey,
+			expRehash:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.ErrorIs(t, tc.hasher.Verify(tc.hashed, tc.password), tc.expErr)
+			assert.Equal(t, tc.expRehash, tc.hasher.NeedsRehash(tc.hashed))
+		})
+	}
+
+	// The inner hash alone, without the key, does not verify the password.
+	assert.ErrorIs(t, inner.Verify(hashed[len("$pepper$k=k2"):], "password"), ErrMismatchedPassword)
+}
@@ -0,0 +1,118 @@
+package hash
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// phcEncoding is the base64 variant of the PHC string format: standard
+// alphabet without padding.
+var phcEncoding = base64.RawStdEncoding
+
+// phcHash is a hash in the PHC string format
+// $<id>[$v=<version>]$<param>=<value>(,<param>=<value>)*$<salt>$<hash>.
+type phcHash struct {
+	id      string
+	version int
+	params  map[string]uint64
+	salt    []byte
+	hash    []byte
+}
+
+func (h *phcHash) String() string {
+	var b strings.Builder
+	b.WriteString("$" + h.id)
+	if h.version != 0 {
+		b.WriteString("$v=" + strconv.Itoa(h.version))
+	}
+
+	b.WriteString("$")
+	keys := h.paramOrder()
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(k + "=" + strconv.FormatUint(h.params[k], 10))
+	}
+
+	b.WriteString("$" + phcEncoding.EncodeToString(h.salt))
+	b.WriteString("$" + phcEncoding.EncodeToString(h.hash))
+
+	return b.String()
+}
+
+// paramOrder returns the parameter names in the order the algorithm
+// specifications use.
+func (h *phcHash) paramOrder() []string {
+	switch h.id {
+	case AlgorithmArgon2id:
+		return []string{"m", "t", "p"}
+	case AlgorithmScrypt:
+		return []string{"ln", "r", "p"}
+	default:
+		return nil
+	}
+}
+
+// parsePHC decodes encoded, which must have the id and the parameters of
+// paramOrder. Any problem is reported as ErrInvalidHash.
+func parsePHC(encoded []byte, id string) (*phcHash, error) {
+	fields := strings.Split(string(encoded), "$")
+	if len(fields) < 5 || fields[0] != "" || fields[1] != id {
+		return nil, ErrInvalidHash
+	}
+
+	h := &phcHash{id: id}
+	fields = fields[2:]
+	if strings.HasPrefix(fields[0], "v=") {
+		v, err := strconv.Atoi(strings.TrimPrefix(fields[0], "v="))
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad version", ErrInvalidHash)
+		}
+		h.version = v
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return nil, ErrInvalidHash
+	}
+
+	h.params = make(map[string]uint64)
+	for _, pair := range strings.Split(fields[0], ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w: bad parameter %q", ErrInvalidHash, pair)
+		}
+		v, err := strconv.ParseUint(kv[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad parameter %q", ErrInvalidHash, pair)
+		}
+		h.params[kv[0]] = v
+	}
+	for _, k := range h.paramOrder() {
+		if _, ok := h.params[k]; !ok {
+			return nil, fmt.Errorf("%w: missing parameter %q", ErrInvalidHash, k)
+		}
+	}
+
+	var err error
+	if h.salt, err = phcEncoding.DecodeString(fields[1]); err != nil || len(h.salt) == 0 {
+		return nil, fmt.Errorf("%w: bad salt", ErrInvalidHash)
+	}
+	if h.hash, err = phcEncoding.DecodeString(fields[2]); err != nil || len(h.hash) == 0 {
+		return nil, fmt.Errorf("%w: bad hash", ErrInvalidHash)
+	}
+
+	return h, nil
+}
+
+func newSalt(length uint32) ([]byte, error) {
+	salt := make([]byte, length)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
@@ -0,0 +1,125 @@
+package hash
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math/bits"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams are the cost parameters of scrypt. N must be a power of two
+// greater than 1.
+type ScryptParams struct {
+	N          int
+	R          int
+	P          int
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultScryptParams are the interactive login parameters recommended by
+// the scrypt paper, N=2^15, r=8, p=1.
+var DefaultScryptParams = ScryptParams{
+	N:          1 << 15,
+	R:          8,
+	P:          1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+func (p ScryptParams) validate() error {
+	switch {
+	case p.N <= 1 || p.N&(p.N-1) != 0:
+		return fmt.Errorf("%w: scrypt N must be a power of two greater than 1", ErrInvalidParams)
+	case p.R < 1 || p.P < 1 || uint64(p.R)*uint64(p.P) >= 1<<30:
+		return fmt.Errorf("%w: scrypt r and p must be positive and r*p less than 2^30", ErrInvalidParams)
+	case p.SaltLength < minSaltLength:
+		return fmt.Errorf("%w: salts must be at least %d bytes long", ErrInvalidParams, minSaltLength)
+	case p.KeyLength < minKeyLength:
+		return fmt.Errorf("%w: keys must be at least %d bytes long", ErrInvalidParams, minKeyLength)
+	}
+
+	return nil
+}
+
+// Scrypt hashes passwords with scrypt and encodes them in the PHC string
+// format, $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<hash>.
+type Scrypt struct {
+	params ScryptParams
+}
+
+func NewScrypt(p ScryptParams) (*Scrypt, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Scrypt{params: p}, nil
+}
+
+func (h *Scrypt) Hash(password string) ([]byte, error) {
+	salt, err := newSalt(h.params.SaltLength)
+	if err != nil {
+		return nil, err
+	}
+
+	p := h.params
+	key, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, int(p.KeyLength))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := &phcHash{
+		id: AlgorithmScrypt,
+		params: map[string]uint64{
+			"ln": uint64(bits.TrailingZeros(uint(p.N))),
+			"r":  uint64(p.R),
+			"p":  uint64(p.P),
+		},
+		salt: salt,
+		hash: key,
+	}
+
+	return []byte(encoded.String()), nil
+}
+
+// Verify returns ErrMismatchedPassword when hashedPassword is not the scrypt
+// hash of password. The parameters are read from hashedPassword, so hashes
+// made with other parameters still verify.
+func (h *Scrypt) Verify(hashedPassword []byte, password string) error {
+	encoded, err := parsePHC(hashedPassword, AlgorithmScrypt)
+	if err != nil {
+		return err
+	}
+
+	ln, r, p := encoded.params["ln"], encoded.params["r"], encoded.params["p"]
+	if encoded.version != 0 || ln < 1 || ln > 30 || r < 1 || p < 1 || r*p >= 1<<30 {
+		return fmt.Errorf("%w: unsupported scrypt parameters", ErrInvalidHash)
+	}
+
+	key, err := scrypt.Key([]byte(password), encoded.salt, 1<<ln, int(r), int(p), len(encoded.hash))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	if subtle.ConstantTimeCompare(key, encoded.hash) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether hashedPassword is not a scrypt hash of the
+// configured parameters.
+func (h *Scrypt) NeedsRehash(hashedPassword []byte) bool {
+	encoded, err := parsePHC(hashedPassword, AlgorithmScrypt)
+	if err != nil {
+		return true
+	}
+
+	p := h.params
+	return encoded.params["ln"] != uint64(bits.TrailingZeros(uint(p.N))) ||
+		encoded.params["r"] != uint64(p.R) ||
+		encoded.params["p"] != uint64(p.P) ||
+		len(encoded.salt) != int(p.SaltLength) ||
+		len(encoded.hash) != int(p.KeyLength)
+}
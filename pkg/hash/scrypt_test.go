@@ -0,0 +1,200 @@
+package hash
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testScryptParams = ScryptParams{
+	N:          16,
+	R:          8,
+	P:          1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+func TestNewScrypt(t *testing.T) {
+	testCases := []struct {
+		description string
+		params      func(p *ScryptParams)
+		expErr      error
+	}{
+		{
+			description: "N not a power of two",
+			params:      func(p *ScryptParams) { p.N = 1000 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "N too small",
+			params:      func(p *ScryptParams) { p.N = 1 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "no r",
+			params:      func(p *ScryptParams) { p.R = 0 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "r*p too large",
+			params:      func(p *ScryptParams) { p.R, p.P = 1<<15, 1<<15 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "short salt",
+			params:      func(p *ScryptParams) { p.SaltLength = 4 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "short key",
+			params:      func(p *ScryptParams) { p.KeyLength = 8 },
+			expErr:      ErrInvalidParams,
+		},
+		{
+			description: "success",
+			params:      func(p *ScryptParams) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			p := testScryptParams
+			tc.params(&p)
+
+			_, err := NewScrypt(p)
+
+			assert.ErrorIs(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestScrypt_Hash(t *testing.T) {
+	h, err := NewScrypt(testScryptParams)
+	assert.NoError(t, err)
+
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\$scrypt\$ln=4,r=8,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), string(hashed))
+
+	other, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashed, other)
+}
+
+func TestScrypt_Verify(t *testing.T) {
+	h, err := NewScrypt(testScryptParams)
+	assert.NoError(t, err)
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+
+	// Test vector of RFC 7914 with N=1024, r=8 and p=16.
+	reference := "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+
+	testCases := []struct {
+		description string
+		hashed      string
+		password    string
+		expErr      error
+	}{
+		{
+			description: "success",
+			hashed:      string(hashed),
+			password:    "password",
+		},
+		{
+			description: "other parameters",
+			hashed:      reference,
+			password:    "password",
+		},
+		{
+			description: "mismatch",
+			hashed:      string(hashed),
+			password:    "other",
+			expErr:      ErrMismatchedPassword,
+		},
+		{
+			description: "other algorithm",
+			hashed:      "$argon2id$v=19$m=64,t=1,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "ln too large",
+			hashed:      "$scrypt$ln=40,r=8,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "invalid r",
+			hashed:      "$scrypt$ln=4,r=0,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+		{
+			description: "bad hash",
+			hashed:      "$scrypt$ln=4,r=8,p=1$TmFDbA$",
+			password:    "password",
+			expErr:      ErrInvalidHash,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := h.Verify([]byte(tc.hashed), tc.password)
+
+			assert.ErrorIs(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestScrypt_NeedsRehash(t *testing.T) {
+	h, err := NewScrypt(testScryptParams)
+	assert.NoError(t, err)
+	current, err := h.Hash("password")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		description string
+		params      func(p *ScryptParams)
+		expRehash   bool
+	}{
+		{
+			description: "same parameters",
+			params:      func(p *ScryptParams) {},
+		},
+		{
+			description: "other N",
+			params:      func(p *ScryptParams) { p.N *= 2 },
+			expRehash:   true,
+		},
+		{
+			description: "other r",
+			params:      func(p *ScryptParams) { p.R++ },
+			expRehash:   true,
+		},
+		{
+			description: "other p",
+			params:      func(p *ScryptParams) { p.P++ },
+			expRehash:   true,
+		},
+		{
+			description: "other salt length",
+			params:      func(p *ScryptParams) { p.SaltLength++ },
+			expRehash:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			p := testScryptParams
+			tc.params(&p)
+			other, err := NewScrypt(p)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expRehash, other.NeedsRehash(current))
+		})
+	}
+
+	assert.True(t, h.NeedsRehash([]byte("not a hash")))
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0002_add_roles.up.sql":      {Data: []byte("up 2")},
+			"0002_add_roles.down.sql":    {Data: []byte("down 2")},
+			"0001_create_users.up.sql":   {Data: []byte("up 1")},
+			"0001_create_users.down.sql": {Data: []byte("down 1")},
+		}
+
+		migrations, err := Parse(fsys)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Migration{
+			{Version: 1, Name: "create_users", Up: "up 1", Down: "down 1"},
+			{Version: 2, Name: "add_roles", Up: "up 2", Down: "down 2"},
+		}, migrations)
+	})
+
+	testCases := []struct {
+		description string
+		fsys        fstest.MapFS
+	}{
+		{
+			description: "invalid file name",
+			fsys: fstest.MapFS{
+				"create_users.sql": {Data: []byte("up")},
+			},
+		},
+		{
+			description: "missing down",
+			fsys: fstest.MapFS{
+				"0001_create_users.up.sql": {Data: []byte("up")},
+			},
+		},
+		{
+			description: "conflicting names",
+			fsys: fstest.MapFS{
+				"0001_create_users.up.sql":   {Data: []byte("up")},
+				"0001_create_roles.down.sql": {Data: []byte("down")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := Parse(tc.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("up 1")},
+		"0001_create_users.down.sql": {Data: []byte("down 1")},
+		"0003_add_roles.up.sql":      {Data: []byte("up 3")},
+		"0003_add_roles.down.sql":    {Data: []byte("down 3")},
+	}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, m.Latest())
+}
@@ -0,0 +1,227 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+
+	DefaultTTL = 15 * time.Minute
+
+	minSecretLen = 32
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrExpiredToken      = errors.New("token has expired")
+	ErrInvalidKey        = errors.New("invalid signing key")
+	ErrUnknownAlgorithm  = errors.New("unknown signing algorithm. Valid algorithms are HS256, EdDSA and RS256")
+	ErrSecretTooShort    = fmt.Errorf("HS256 secrets must be at least %d bytes long", minSecretLen)
+	ErrUnsupportedKeyPEM = errors.New("unsupported PEM key. Use a PKCS#8 Ed25519 or RSA key, or a PKCS#1 RSA key")
+)
+
+type Options struct {
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+}
+
+// Claims are the contents of an access token.
+type Claims struct {
+	ID        string
+	UserID    string
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type jwtClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// JWT issues and verifies signed JSON Web Tokens.
+type JWT struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	opts      Options
+}
+
+// NewHS256 returns a JWT signing with HMAC-SHA256 and secret.
+func NewHS256(secret []byte, opts Options) (*JWT, error) {
+	if len(secret) < minSecretLen {
+		return nil, ErrSecretTooShort
+	}
+
+	return newJWT(jwt.SigningMethodHS256, secret, secret, opts), nil
+}
+
+// NewEdDSA returns a JWT signing with Ed25519 and key.
+func NewEdDSA(key ed25519.PrivateKey, opts Options) (*JWT, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return newJWT(jwt.SigningMethodEdDSA, key, key.Public(), opts), nil
+}
+
+// NewRS256 returns a JWT signing with RSASSA-PKCS1-v1_5 SHA-256 and key.
+func NewRS256(key *rsa.PrivateKey, opts Options) (*JWT, error) {
+	if key == nil || key.Validate() != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return newJWT(jwt.SigningMethodRS256, key, key.Public(), opts), nil
+}
+
+// New returns a JWT for algorithm. HS256 uses secret while EdDSA and RS256 use
+// the PEM encoded private key privateKeyPEM.
+func New(algorithm string, secret, privateKeyPEM []byte, opts Options) (*JWT, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return NewHS256(secret, opts)
+	case AlgorithmEdDSA, AlgorithmRS256:
+		key, err := ParsePrivateKey(privateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		if algorithm == AlgorithmEdDSA {
+			edKey, ok := key.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("%w: EdDSA needs an Ed25519 key", ErrInvalidKey)
+			}
+			return NewEdDSA(edKey, opts)
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: RS256 needs an RSA key", ErrInvalidKey)
+		}
+		return NewRS256(rsaKey, opts)
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+func newJWT(method jwt.SigningMethod, signKey, verifyKey interface{}, opts Options) *JWT {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+
+	return &JWT{
+		method:    method,
+		signKey:   signKey,
+		verifyKey: verifyKey,
+		opts:      opts,
+	}
+}
+
+// Issue returns a signed token for the user and its claims.
+func (j *JWT) Issue(userID, role string) (string, *Claims, error) {
+	now := time.Now().Truncate(time.Second)
+	c := &Claims{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Role:      role,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(j.opts.TTL),
+	}
+
+	registered := jwt.RegisteredClaims{
+		ID:        c.ID,
+		Subject:   c.UserID,
+		Issuer:    j.opts.Issuer,
+		IssuedAt:  jwt.NewNumericDate(c.IssuedAt),
+		NotBefore: jwt.NewNumericDate(c.IssuedAt),
+		ExpiresAt: jwt.NewNumericDate(c.ExpiresAt),
+	}
+	if j.opts.Audience != "" {
+		registered.Audience = jwt.ClaimStrings{j.opts.Audience}
+	}
+
+	signed, err := jwt.NewWithClaims(j.method, &jwtClaims{Role: role, RegisteredClaims: registered}).SignedString(j.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, c, nil
+}
+
+// Verify returns the claims of a token issued with the same algorithm, key,
+// issuer and audience. It returns ErrExpiredToken for expired tokens and
+// ErrInvalidToken for any other problem.
+func (j *JWT) Verify(signed string) (*Claims, error) {
+	var claims jwtClaims
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{j.method.Alg()}))
+	_, err := parser.ParseWithClaims(signed, &claims, func(*jwt.Token) (interface{}, error) {
+		return j.verifyKey, nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if j.opts.Issuer != "" && !claims.VerifyIssuer(j.opts.Issuer, true) {
+		return nil, ErrInvalidToken
+	}
+	if j.opts.Audience != "" && !claims.VerifyAudience(j.opts.Audience, true) {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		ID:        claims.ID,
+		UserID:    claims.Subject,
+		Role:      claims.Role,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#8 Ed25519 or RSA private key, or a
+// PKCS#1 RSA private key.
+func ParsePrivateKey(buff []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(buff)
+	if block == nil {
+		return nil, ErrUnsupportedKeyPEM
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case ed25519.PrivateKey:
+			return k, nil
+		case *rsa.PrivateKey:
+			return k, nil
+		}
+	}
+
+	return nil, ErrUnsupportedKeyPEM
+}
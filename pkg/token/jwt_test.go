@@ -0,0 +1,164 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testOpts = Options{Issuer: "issuer", Audience: "audience", TTL: time.Minute}
+
+func newTestJWTs(t *testing.T) map[string]*JWT {
+	t.Helper()
+
+	hs, err := NewHS256([]byte(strings.Repeat("s", minSecretLen)), testOpts)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	ed, err := NewEdDSA(edKey, testOpts)
+	assert.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rs, err := NewRS256(rsaKey, testOpts)
+	assert.NoError(t, err)
+
+	return map[string]*JWT{
+		AlgorithmHS256: hs,
+		AlgorithmEdDSA: ed,
+		AlgorithmRS256: rs,
+	}
+}
+
+func TestJWT_IssueVerify(t *testing.T) {
+	for alg, j := range newTestJWTs(t) {
+		t.Run(alg, func(t *testing.T) {
+			signed, issued, err := j.Issue("user", "admin")
+			assert.NoError(t, err)
+
+			claims, err := j.Verify(signed)
+			assert.NoError(t, err)
+			assert.Equal(t, issued, claims)
+			assert.Equal(t, "user", claims.UserID)
+			assert.Equal(t, "admin", claims.Role)
+			assert.Equal(t, testOpts.TTL, claims.ExpiresAt.Sub(claims.IssuedAt))
+
+			_, err = j.Verify(signed[:len(signed)-2])
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestJWT_Verify(t *testing.T) {
+	secret := []byte(strings.Repeat("s", minSecretLen))
+	j, err := NewHS256(secret, testOpts)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		description string
+		issuer      func() (*JWT, error)
+		expError    error
+	}{
+		{
+			description: "expired",
+			issuer: func() (*JWT, error) {
+				expired, err := NewHS256(secret, testOpts)
+				expired.opts.TTL = -time.Minute
+				return expired, err
+			},
+			expError: ErrExpiredToken,
+		},
+		{
+			description: "other secret",
+			issuer: func() (*JWT, error) {
+				return NewHS256([]byte(strings.Repeat("o", minSecretLen)), testOpts)
+			},
+			expError: ErrInvalidToken,
+		},
+		{
+			description: "other issuer",
+			issuer: func() (*JWT, error) {
+				return NewHS256(secret, Options{Issuer: "other", Audience: testOpts.Audience})
+			},
+			expError: ErrInvalidToken,
+		},
+		{
+			description: "other audience",
+			issuer: func() (*JWT, error) {
+				return NewHS256(secret, Options{Issuer: testOpts.Issuer, Audience: "other"})
+			},
+			expError: ErrInvalidToken,
+		},
+		{
+			description: "other algorithm",
+			issuer: func() (*JWT, error) {
+				_, key, _ := ed25519.GenerateKey(rand.Reader)
+				return NewEdDSA(key, testOpts)
+			},
+			expError: ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			issuer, err := tc.issuer()
+			assert.NoError(t, err)
+
+			signed, _, err := issuer.Issue("user", "user")
+			assert.NoError(t, err)
+
+			_, err = j.Verify(signed)
+			assert.ErrorIs(t, err, tc.expError)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER})
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	testCases := []struct {
+		description string
+		algorithm   string
+		secret      []byte
+		keyPEM      []byte
+		expError    bool
+	}{
+		{description: "hs256", algorithm: AlgorithmHS256, secret: []byte(strings.Repeat("s", minSecretLen))},
+		{description: "hs256 short secret", algorithm: AlgorithmHS256, secret: []byte("short"), expError: true},
+		{description: "eddsa", algorithm: AlgorithmEdDSA, keyPEM: edPEM},
+		{description: "eddsa with rsa key", algorithm: AlgorithmEdDSA, keyPEM: rsaPEM, expError: true},
+		{description: "rs256", algorithm: AlgorithmRS256, keyPEM: rsaPEM},
+		{description: "rs256 with ed25519 key", algorithm: AlgorithmRS256, keyPEM: edPEM, expError: true},
+		{description: "rs256 without key", algorithm: AlgorithmRS256, expError: true},
+		{description: "unknown algorithm", algorithm: "none", expError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			j, err := New(tc.algorithm, tc.secret, tc.keyPEM, testOpts)
+			if tc.expError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			signed, _, err := j.Issue("user", "user")
+			assert.NoError(t, err)
+			_, err = j.Verify(signed)
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package refresh
+
+import (
+	"context"
+	"time"
+)
+
+type Commands interface {
+	Insert
+	Rotate
+	RevokeFamily
+	RevokeByUserID
+	DeleteInactive
+}
+
+type Insert interface {
+	Insert(ctx context.Context, t *Token) error
+}
+
+// Rotate marks the token id as rotated and inserts next in a single step. It
+// returns ErrNotActive if id was already rotated or revoked, so two concurrent
+// rotations of the same token cannot both succeed.
+type Rotate interface {
+	Rotate(ctx context.Context, id string, next *Token) error
+}
+
+// RevokeFamily revokes every token of the family that is not revoked yet.
+type RevokeFamily interface {
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+// RevokeByUserID revokes every token of the user that is not revoked yet.
+type RevokeByUserID interface {
+	RevokeByUserID(ctx context.Context, userID string) error
+}
+
+// DeleteInactive deletes every family whose tokens are all revoked or expired
+// at now, since none of them can be used again.
+type DeleteInactive interface {
+	DeleteInactive(ctx context.Context, now time.Time) error
+}
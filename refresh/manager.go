@@ -0,0 +1,152 @@
+package refresh
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTTL = 30 * 24 * time.Hour
+
+	tokenBytes = 32
+)
+
+type ManagerQueries interface {
+	GetByHash
+}
+
+type ManagerCommands interface {
+	Insert
+	Rotate
+	RevokeFamily
+	RevokeByUserID
+	DeleteInactive
+}
+
+type Manager struct {
+	ttl time.Duration
+	q   ManagerQueries
+	cmd ManagerCommands
+}
+
+func NewManager(ttl time.Duration, q ManagerQueries, cmd ManagerCommands) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Manager{
+		ttl: ttl,
+		q:   q,
+		cmd: cmd,
+	}
+}
+
+// Issue starts a new token family for the user and returns its first token
+// together with the secret handed to the client.
+func (m *Manager) Issue(ctx context.Context, userID string) (*Token, string, error) {
+	t, secret, err := m.newToken(uuid.NewString(), userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = m.cmd.Insert(ctx, t); err != nil {
+		return nil, "", err
+	}
+
+	return t, secret, nil
+}
+
+// Rotate exchanges secret for a new token of the same family. Reusing a
+// secret that was already rotated revokes the family and returns ErrReused.
+func (m *Manager) Rotate(ctx context.Context, secret string) (*Token, string, error) {
+	current, err := m.q.GetByHash(ctx, HashToken(secret))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if current.RevokedAt != nil {
+		return nil, "", ErrRevoked
+	}
+	if current.RotatedAt != nil {
+		return nil, "", m.revokeReused(ctx, current.FamilyID)
+	}
+	if !time.Now().Before(current.ExpiresAt) {
+		return nil, "", ErrExpired
+	}
+
+	next, nextSecret, err := m.newToken(current.FamilyID, current.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = m.cmd.Rotate(ctx, current.ID, next); err != nil {
+		if errors.Is(err, ErrNotActive) {
+			return nil, "", m.revokeReused(ctx, current.FamilyID)
+		}
+		return nil, "", err
+	}
+
+	return next, nextSecret, nil
+}
+
+// Revoke revokes the family of secret, ending the login it belongs to.
+func (m *Manager) Revoke(ctx context.Context, secret string) error {
+	t, err := m.q.GetByHash(ctx, HashToken(secret))
+	if err != nil {
+		return err
+	}
+
+	return m.cmd.RevokeFamily(ctx, t.FamilyID)
+}
+
+// RevokeAll revokes every token of the user, ending all of its logins.
+func (m *Manager) RevokeAll(ctx context.Context, userID string) error {
+	return m.cmd.RevokeByUserID(ctx, userID)
+}
+
+// Sweep deletes the families that can no longer be used, so that the store
+// does not keep every token ever issued.
+func (m *Manager) Sweep(ctx context.Context) error {
+	return m.cmd.DeleteInactive(ctx, time.Now())
+}
+
+func (m *Manager) revokeReused(ctx context.Context, familyID string) error {
+	if err := m.cmd.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return ErrReused
+}
+
+func (m *Manager) newToken(familyID, userID string) (*Token, string, error) {
+	buff := make([]byte, tokenBytes)
+	if _, err := rand.Read(buff); err != nil {
+		return nil, "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buff)
+
+	now := time.Now()
+	t := &Token{
+		ID:        uuid.NewString(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		Hash:      HashToken(secret),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+
+	return t, secret, nil
+}
+
+// HashToken returns the hash under which the refresh token secret is stored.
+func HashToken(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+
+	return sum[:]
+}
@@ -0,0 +1,226 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssue(t *testing.T) {
+	userID := "1"
+	var inserted *Token
+	cmd := &mockManagerCMD{insert: func(ctx context.Context, t *Token) error {
+		inserted = t
+		return nil
+	}}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	tok, secret, err := m.Issue(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, inserted, tok)
+	assert.Equal(t, userID, tok.UserID)
+	assert.NotEmpty(t, tok.ID)
+	assert.NotEmpty(t, tok.FamilyID)
+	assert.Equal(t, HashToken(secret), tok.Hash)
+	assert.Equal(t, time.Hour, tok.ExpiresAt.Sub(tok.CreatedAt))
+
+	other, _, err := m.Issue(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tok.FamilyID, other.FamilyID)
+}
+
+func TestRotate(t *testing.T) {
+	randomErr := errors.New("random error")
+	past := time.Now().Add(-time.Minute)
+	testCases := []struct {
+		description string
+		getErr      error
+		rotatedAt   *time.Time
+		revokedAt   *time.Time
+		expiresIn   time.Duration
+		rotateErr   error
+		expRevoked  bool
+		expError    error
+	}{
+		{
+			description: "unknown token",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "revoked",
+			revokedAt:   &past,
+			expiresIn:   time.Hour,
+			expError:    ErrRevoked,
+		},
+		{
+			description: "reused",
+			rotatedAt:   &past,
+			expiresIn:   time.Hour,
+			expRevoked:  true,
+			expError:    ErrReused,
+		},
+		{
+			description: "expired",
+			expiresIn:   -time.Second,
+			expError:    ErrExpired,
+		},
+		{
+			description: "concurrently rotated",
+			expiresIn:   time.Hour,
+			rotateErr:   ErrNotActive,
+			expRevoked:  true,
+			expError:    ErrReused,
+		},
+		{
+			description: "random rotate error",
+			expiresIn:   time.Hour,
+			rotateErr:   randomErr,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			expiresIn:   time.Hour,
+			expError:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			secret := "secret"
+			current := &Token{
+				ID:        "t1",
+				FamilyID:  "f",
+				UserID:    "1",
+				ExpiresAt: time.Now().Add(tc.expiresIn),
+				RotatedAt: tc.rotatedAt,
+				RevokedAt: tc.revokedAt,
+			}
+			q := &mockManagerQueries{getByHash: func(ctx context.Context, hash []byte) (*Token, error) {
+				assert.Equal(t, HashToken(secret), hash)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return current, nil
+			}}
+			revoked := false
+			cmd := &mockManagerCMD{
+				rotate: func(ctx context.Context, id string, next *Token) error {
+					assert.Equal(t, "t1", id)
+					assert.Equal(t, "f", next.FamilyID)
+					assert.Equal(t, "1", next.UserID)
+
+					return tc.rotateErr
+				},
+				revokeFamily: func(ctx context.Context, familyID string) error {
+					assert.Equal(t, "f", familyID)
+					revoked = true
+
+					return nil
+				},
+			}
+
+			m := NewManager(time.Hour, q, cmd)
+
+			next, nextSecret, err := m.Rotate(context.Background(), secret)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expRevoked, revoked)
+			if tc.expError == nil {
+				assert.NotEqual(t, "t1", next.ID)
+				assert.Equal(t, HashToken(nextSecret), next.Hash)
+			}
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	q := &mockManagerQueries{getByHash: func(ctx context.Context, hash []byte) (*Token, error) {
+		if string(hash) != string(HashToken("secret")) {
+			return nil, ErrDoesNotExist
+		}
+
+		return &Token{ID: "t1", FamilyID: "f"}, nil
+	}}
+	var revoked string
+	cmd := &mockManagerCMD{revokeFamily: func(ctx context.Context, familyID string) error {
+		revoked = familyID
+		return nil
+	}}
+
+	m := NewManager(time.Hour, q, cmd)
+
+	assert.ErrorIs(t, m.Revoke(context.Background(), "other"), ErrDoesNotExist)
+	assert.Empty(t, revoked)
+
+	assert.NoError(t, m.Revoke(context.Background(), "secret"))
+	assert.Equal(t, "f", revoked)
+}
+
+func TestRevokeAll(t *testing.T) {
+	var revoked string
+	cmd := &mockManagerCMD{revokeByUserID: func(ctx context.Context, userID string) error {
+		revoked = userID
+		return nil
+	}}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	assert.NoError(t, m.RevokeAll(context.Background(), "1"))
+	assert.Equal(t, "1", revoked)
+}
+
+func TestSweep(t *testing.T) {
+	var swept time.Time
+	cmd := &mockManagerCMD{deleteInactive: func(ctx context.Context, now time.Time) error {
+		swept = now
+		return nil
+	}}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	before := time.Now()
+	assert.NoError(t, m.Sweep(context.Background()))
+	assert.False(t, swept.Before(before))
+}
+
+type mockManagerQueries struct {
+	getByHash func(ctx context.Context, hash []byte) (*Token, error)
+}
+
+func (m *mockManagerQueries) GetByHash(ctx context.Context, hash []byte) (*Token, error) {
+	return m.getByHash(ctx, hash)
+}
+
+type mockManagerCMD struct {
+	insert         func(ctx context.Context, t *Token) error
+	rotate         func(ctx context.Context, id string, next *Token) error
+	revokeFamily   func(ctx context.Context, familyID string) error
+	revokeByUserID func(ctx context.Context, userID string) error
+	deleteInactive func(ctx context.Context, now time.Time) error
+}
+
+func (m *mockManagerCMD) Insert(ctx context.Context, t *Token) error {
+	return m.insert(ctx, t)
+}
+
+func (m *mockManagerCMD) Rotate(ctx context.Context, id string, next *Token) error {
+	return m.rotate(ctx, id, next)
+}
+
+func (m *mockManagerCMD) RevokeFamily(ctx context.Context, familyID string) error {
+	return m.revokeFamily(ctx, familyID)
+}
+
+func (m *mockManagerCMD) RevokeByUserID(ctx context.Context, userID string) error {
+	return m.revokeByUserID(ctx, userID)
+}
+
+func (m *mockManagerCMD) DeleteInactive(ctx context.Context, now time.Time) error {
+	return m.deleteInactive(ctx, now)
+}
@@ -0,0 +1,29 @@
+package refresh
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDoesNotExist = errors.New("refresh token does not exist")
+	ErrExpired      = errors.New("refresh token has expired")
+	ErrRevoked      = errors.New("refresh token has been revoked")
+	ErrReused       = errors.New("refresh token was already used: every token of its family has been revoked")
+	ErrNotActive    = errors.New("refresh token was already rotated or revoked")
+)
+
+// Token is a refresh token. Logging in starts a family of tokens and each
+// rotation replaces the current token of the family with a new one. Using a
+// token that was already rotated means it leaked, so the whole family is
+// revoked. Like sessions, only the hash of the token is stored.
+type Token struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
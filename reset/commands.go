@@ -0,0 +1,28 @@
+package reset
+
+import (
+	"context"
+	"time"
+)
+
+type Commands interface {
+	Insert
+	MarkUsed
+	DeleteByUserID
+}
+
+type Insert interface {
+	Insert(ctx context.Context, t *Token) error
+}
+
+// MarkUsed records that the token id was used at usedAt. It returns
+// ErrNotActive if it was already used, so a token cannot be used twice even
+// concurrently.
+type MarkUsed interface {
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// DeleteByUserID deletes every token of the user.
+type DeleteByUserID interface {
+	DeleteByUserID(ctx context.Context, userID string) error
+}
@@ -0,0 +1,127 @@
+package reset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTTL = time.Hour
+
+	tokenBytes = 32
+)
+
+type ManagerQueries interface {
+	GetByHash
+}
+
+type ManagerCommands interface {
+	Insert
+	MarkUsed
+	DeleteByUserID
+}
+
+type Manager struct {
+	ttl time.Duration
+	q   ManagerQueries
+	cmd ManagerCommands
+}
+
+func NewManager(ttl time.Duration, q ManagerQueries, cmd ManagerCommands) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Manager{
+		ttl: ttl,
+		q:   q,
+		cmd: cmd,
+	}
+}
+
+// Issue creates a token for the user and returns the secret handed to it.
+// Previous tokens of the user stop working, so only the last one sent is
+// valid.
+func (m *Manager) Issue(ctx context.Context, userID string) (string, time.Time, error) {
+	buff := make([]byte, tokenBytes)
+	if _, err := rand.Read(buff); err != nil {
+		return "", time.Time{}, err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buff)
+
+	now := time.Now()
+	t := &Token{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Hash:      HashToken(secret),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+
+	if err := m.cmd.DeleteByUserID(ctx, userID); err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := m.cmd.Insert(ctx, t); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return secret, t.ExpiresAt, nil
+}
+
+// Check returns the user of secret without using it. It returns
+// ErrInvalidToken for unknown, used and expired tokens.
+func (m *Manager) Check(ctx context.Context, secret string) (string, error) {
+	t, err := m.active(ctx, secret)
+	if err != nil {
+		return "", err
+	}
+
+	return t.UserID, nil
+}
+
+// Redeem uses secret, which cannot be used again. It returns ErrInvalidToken
+// for unknown, used and expired tokens.
+func (m *Manager) Redeem(ctx context.Context, secret string) error {
+	t, err := m.active(ctx, secret)
+	if err != nil {
+		return err
+	}
+
+	err = m.cmd.MarkUsed(ctx, t.ID, time.Now())
+	if errors.Is(err, ErrNotActive) || errors.Is(err, ErrDoesNotExist) {
+		return ErrInvalidToken
+	}
+
+	return err
+}
+
+func (m *Manager) active(ctx context.Context, secret string) (*Token, error) {
+	t, err := m.q.GetByHash(ctx, HashToken(secret))
+	if errors.Is(err, ErrDoesNotExist) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t.UsedAt != nil || !time.Now().Before(t.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	return t, nil
+}
+
+// HashToken returns the hash under which the reset token secret is stored.
+// Tokens are random, so a fast hash is enough.
+func HashToken(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+
+	return sum[:]
+}
@@ -0,0 +1,148 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssue(t *testing.T) {
+	userID := "1"
+	var (
+		inserted *Token
+		deleted  string
+	)
+	cmd := &mockManagerCMD{
+		insert: func(ctx context.Context, t *Token) error {
+			inserted = t
+			return nil
+		},
+		deleteByUserID: func(ctx context.Context, id string) error {
+			assert.Nil(t, inserted)
+			deleted = id
+			return nil
+		},
+	}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	secret, expiresAt, err := m.Issue(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, userID, deleted)
+	assert.Equal(t, userID, inserted.UserID)
+	assert.NotEmpty(t, inserted.ID)
+	assert.Equal(t, HashToken(secret), inserted.Hash)
+	assert.Equal(t, expiresAt, inserted.ExpiresAt)
+	assert.Equal(t, time.Hour, inserted.ExpiresAt.Sub(inserted.CreatedAt))
+}
+
+func TestRedeem(t *testing.T) {
+	randomErr := errors.New("random error")
+	past := time.Now().Add(-time.Minute)
+	testCases := []struct {
+		description string
+		token       *Token
+		getErr      error
+		markErr     error
+		expMarked   bool
+		expError    error
+	}{
+		{
+			description: "unknown token",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrInvalidToken,
+		},
+		{
+			description: "random get error",
+			getErr:      randomErr,
+			expError:    randomErr,
+		},
+		{
+			description: "used token",
+			token:       &Token{ID: "t", UserID: "1", ExpiresAt: time.Now().Add(time.Hour), UsedAt: &past},
+			expError:    ErrInvalidToken,
+		},
+		{
+			description: "expired token",
+			token:       &Token{ID: "t", UserID: "1", ExpiresAt: past},
+			expError:    ErrInvalidToken,
+		},
+		{
+			description: "used concurrently",
+			token:       &Token{ID: "t", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+			markErr:     ErrNotActive,
+			expMarked:   true,
+			expError:    ErrInvalidToken,
+		},
+		{
+			description: "all good",
+			token:       &Token{ID: "t", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+			expMarked:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &mockManagerQueries{getByHash: func(ctx context.Context, hash []byte) (*Token, error) {
+				assert.Equal(t, HashToken("secret"), hash)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return tc.token, nil
+			}}
+			marked := false
+			cmd := &mockManagerCMD{markUsed: func(ctx context.Context, id string, usedAt time.Time) error {
+				assert.Equal(t, "t", id)
+				marked = true
+
+				return tc.markErr
+			}}
+
+			m := NewManager(time.Hour, q, cmd)
+
+			userID, err := m.Check(context.Background(), "secret")
+			if tc.markErr == nil {
+				assert.ErrorIs(t, err, tc.expError)
+			}
+			if err == nil {
+				assert.Equal(t, "1", userID)
+			}
+			assert.False(t, marked)
+
+			err = m.Redeem(context.Background(), "secret")
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expMarked, marked)
+		})
+	}
+}
+
+type mockManagerQueries struct {
+	getByHash func(ctx context.Context, hash []byte) (*Token, error)
+}
+
+func (m *mockManagerQueries) GetByHash(ctx context.Context, hash []byte) (*Token, error) {
+	return m.getByHash(ctx, hash)
+}
+
+type mockManagerCMD struct {
+	insert         func(ctx context.Context, t *Token) error
+	markUsed       func(ctx context.Context, id string, usedAt time.Time) error
+	deleteByUserID func(ctx context.Context, userID string) error
+}
+
+func (m *mockManagerCMD) Insert(ctx context.Context, t *Token) error {
+	return m.insert(ctx, t)
+}
+
+func (m *mockManagerCMD) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	return m.markUsed(ctx, id, usedAt)
+}
+
+func (m *mockManagerCMD) DeleteByUserID(ctx context.Context, userID string) error {
+	return m.deleteByUserID(ctx, userID)
+}
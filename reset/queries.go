@@ -0,0 +1,11 @@
+package reset
+
+import "context"
+
+type Queries interface {
+	GetByHash
+}
+
+type GetByHash interface {
+	GetByHash(ctx context.Context, hash []byte) (*Token, error)
+}
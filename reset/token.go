@@ -0,0 +1,23 @@
+package reset
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDoesNotExist = errors.New("password reset token does not exist")
+	ErrNotActive    = errors.New("password reset token was already used")
+	ErrInvalidToken = errors.New("password reset token is invalid or has expired")
+)
+
+// Token is a single use password reset token. Like sessions, only the hash of
+// the secret handed to the user is stored.
+type Token struct {
+	ID        string
+	UserID    string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
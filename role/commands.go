@@ -0,0 +1,25 @@
+package role
+
+import "context"
+
+type Commands interface {
+	Insert
+	Update
+	Delete
+}
+
+type Insert interface {
+	Insert(ctx context.Context, r *Role) error
+}
+
+type Update interface {
+	Update(ctx context.Context, r *Role) error
+}
+
+// Delete deletes the stored role name. It returns ErrDoesNotExist when there is
+// none and ErrInUse when a user has the role, deleted users included. Giving
+// roles to users must be atomic with it, see user.Insert and user.Update, so
+// that no user is ever left with a deleted role.
+type Delete interface {
+	Delete(ctx context.Context, name string) error
+}
@@ -0,0 +1,175 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+type ManagerQueries interface {
+	Get
+	List
+}
+
+type ManagerCommands interface {
+	Insert
+	Update
+	Delete
+}
+
+type Manager struct {
+	q   ManagerQueries
+	cmd ManagerCommands
+}
+
+func NewManager(q ManagerQueries, cmd ManagerCommands) *Manager {
+	return &Manager{
+		q:   q,
+		cmd: cmd,
+	}
+}
+
+// Get returns the built-in or stored role name.
+func (m *Manager) Get(ctx context.Context, name string) (*Role, error) {
+	if r, ok := builtin(name); ok {
+		return r, nil
+	}
+
+	return m.q.Get(ctx, name)
+}
+
+// List returns the built-in and stored roles sorted by name.
+func (m *Manager) List(ctx context.Context) ([]*Role, error) {
+	stored, err := m.q.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := append(builtins(), stored...)
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+
+	return roles, nil
+}
+
+// RoleExists reports whether name is a built-in or stored role.
+func (m *Manager) RoleExists(ctx context.Context, name string) (bool, error) {
+	_, err := m.Get(ctx, name)
+	if errors.Is(err, ErrDoesNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type CreateParams struct {
+	Name        string
+	Description string
+	Permissions []Permission
+}
+
+func (m *Manager) Create(ctx context.Context, params CreateParams) (*Role, error) {
+	if !nameRegexp.MatchString(params.Name) {
+		return nil, ErrInvalidName
+	}
+	if _, ok := builtin(params.Name); ok {
+		return nil, ErrAlreadyExists
+	}
+
+	permissions, err := normalizePermissions(params.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	r := &Role{
+		Name:        params.Name,
+		Description: params.Description,
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err = m.cmd.Insert(ctx, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// UpdateParams changes the fields of a role that are not nil. An empty,
+// non-nil Permissions removes every permission.
+type UpdateParams struct {
+	Name        string
+	Description *string
+	Permissions []Permission
+}
+
+func (m *Manager) Update(ctx context.Context, params UpdateParams) (*Role, error) {
+	if _, ok := builtin(params.Name); ok {
+		return nil, ErrBuiltin
+	}
+
+	r, err := m.q.Get(ctx, params.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Description != nil {
+		r.Description = *params.Description
+	}
+	if params.Permissions != nil {
+		if r.Permissions, err = normalizePermissions(params.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	r.UpdatedAt = time.Now()
+
+	if err = m.cmd.Update(ctx, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Delete removes a custom role. Roles still given to users cannot be deleted,
+// since those users would silently lose their permissions.
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	if _, ok := builtin(name); ok {
+		return ErrBuiltin
+	}
+
+	return m.cmd.Delete(ctx, name)
+}
+
+// normalizePermissions checks permissions against the catalog and returns them
+// sorted and without duplicates.
+func normalizePermissions(permissions []Permission) ([]Permission, error) {
+	seen := make(map[Permission]bool, len(permissions))
+	normalized := make([]Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if !isKnownPermission(p) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPermission, p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		normalized = append(normalized, p)
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i] < normalized[j]
+	})
+
+	return normalized, nil
+}
@@ -0,0 +1,297 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	randomErr := errors.New("random error")
+	q := &mockManagerQueries{get: func(ctx context.Context, name string) (*Role, error) {
+		switch name {
+		case "auditor":
+			return &Role{Name: "auditor"}, nil
+		case "broken":
+			return nil, randomErr
+		default:
+			return nil, ErrDoesNotExist
+		}
+	}}
+
+	m := NewManager(q, nil)
+
+	admin, err := m.Get(context.Background(), user.RoleAdmin)
+	assert.NoError(t, err)
+	assert.True(t, admin.Builtin)
+	for _, info := range Catalog() {
+		assert.True(t, admin.Has(info.Name))
+	}
+
+	u, err := m.Get(context.Background(), user.RoleUser)
+	assert.NoError(t, err)
+	assert.Empty(t, u.Permissions)
+
+	r, err := m.Get(context.Background(), "auditor")
+	assert.NoError(t, err)
+	assert.Equal(t, "auditor", r.Name)
+
+	testCases := []struct {
+		name      string
+		expExists bool
+		expError  error
+	}{
+		{name: user.RoleAdmin, expExists: true},
+		{name: "auditor", expExists: true},
+		{name: "unknown", expExists: false},
+		{name: "broken", expError: randomErr},
+	}
+	for _, tc := range testCases {
+		exists, err := m.RoleExists(context.Background(), tc.name)
+		assert.ErrorIs(t, err, tc.expError)
+		assert.Equal(t, tc.expExists, exists, tc.name)
+	}
+}
+
+func TestList(t *testing.T) {
+	q := &mockManagerQueries{list: func(ctx context.Context) ([]*Role, error) {
+		return []*Role{{Name: "auditor"}, {Name: "support"}}, nil
+	}}
+
+	m := NewManager(q, nil)
+
+	roles, err := m.List(context.Background())
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"admin", "auditor", "support", "user"}, names)
+}
+
+func TestCreate(t *testing.T) {
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description    string
+		params         CreateParams
+		insertErr      error
+		expInsert      bool
+		expPermissions []Permission
+		expError       error
+	}{
+		{
+			description: "invalid name",
+			params:      CreateParams{Name: "Not Valid"},
+			expError:    ErrInvalidName,
+		},
+		{
+			description: "built-in name",
+			params:      CreateParams{Name: user.RoleAdmin},
+			expError:    ErrAlreadyExists,
+		},
+		{
+			description: "unknown permission",
+			params:      CreateParams{Name: "auditor", Permissions: []Permission{"users:fly"}},
+			expError:    ErrUnknownPermission,
+		},
+		{
+			description: "already exists",
+			params:      CreateParams{Name: "auditor"},
+			insertErr:   ErrAlreadyExists,
+			expInsert:   true,
+			expError:    ErrAlreadyExists,
+		},
+		{
+			description: "random error",
+			params:      CreateParams{Name: "auditor"},
+			insertErr:   randomErr,
+			expInsert:   true,
+			expError:    randomErr,
+		},
+		{
+			description:    "all good",
+			params:         CreateParams{Name: "auditor", Permissions: []Permission{PermUsersRead, PermSessionsManage, PermUsersRead}},
+			expInsert:      true,
+			expPermissions: []Permission{PermSessionsManage, PermUsersRead},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			inserted := false
+			cmd := &mockManagerCMD{insert: func(ctx context.Context, r *Role) error {
+				inserted = true
+				assert.Equal(t, tc.params.Name, r.Name)
+
+				return tc.insertErr
+			}}
+
+			m := NewManager(nil, cmd)
+
+			r, err := m.Create(context.Background(), tc.params)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expInsert, inserted)
+			if tc.expError == nil {
+				assert.Equal(t, tc.expPermissions, r.Permissions)
+				assert.False(t, r.Builtin)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	description := "new description"
+	testCases := []struct {
+		description    string
+		params         UpdateParams
+		expUpdate      bool
+		expDescription string
+		expPermissions []Permission
+		expError       error
+	}{
+		{
+			description: "built-in",
+			params:      UpdateParams{Name: user.RoleAdmin, Description: &description},
+			expError:    ErrBuiltin,
+		},
+		{
+			description: "does not exist",
+			params:      UpdateParams{Name: "unknown", Description: &description},
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "unknown permission",
+			params:      UpdateParams{Name: "auditor", Permissions: []Permission{"users:fly"}},
+			expError:    ErrUnknownPermission,
+		},
+		{
+			description:    "description only",
+			params:         UpdateParams{Name: "auditor", Description: &description},
+			expUpdate:      true,
+			expDescription: description,
+			expPermissions: []Permission{PermUsersRead},
+		},
+		{
+			description:    "remove permissions",
+			params:         UpdateParams{Name: "auditor", Permissions: []Permission{}},
+			expUpdate:      true,
+			expDescription: "old",
+			expPermissions: []Permission{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &mockManagerQueries{get: func(ctx context.Context, name string) (*Role, error) {
+				if name != "auditor" {
+					return nil, ErrDoesNotExist
+				}
+
+				return &Role{Name: "auditor", Description: "old", Permissions: []Permission{PermUsersRead}}, nil
+			}}
+			updated := false
+			cmd := &mockManagerCMD{update: func(ctx context.Context, r *Role) error {
+				updated = true
+				return nil
+			}}
+
+			m := NewManager(q, cmd)
+
+			r, err := m.Update(context.Background(), tc.params)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expUpdate, updated)
+			if tc.expError == nil {
+				assert.Equal(t, tc.expDescription, r.Description)
+				assert.Equal(t, tc.expPermissions, r.Permissions)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	testCases := []struct {
+		description string
+		name        string
+		deleteErr   error
+		expDelete   bool
+		expError    error
+	}{
+		{
+			description: "built-in",
+			name:        user.RoleUser,
+			expError:    ErrBuiltin,
+		},
+		{
+			description: "in use",
+			name:        "auditor",
+			deleteErr:   ErrInUse,
+			expDelete:   true,
+			expError:    ErrInUse,
+		},
+		{
+			description: "does not exist",
+			name:        "auditor",
+			deleteErr:   ErrDoesNotExist,
+			expDelete:   true,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "all good",
+			name:        "auditor",
+			expDelete:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			deleted := false
+			cmd := &mockManagerCMD{delete: func(ctx context.Context, name string) error {
+				deleted = true
+				assert.Equal(t, tc.name, name)
+
+				return tc.deleteErr
+			}}
+
+			m := NewManager(nil, cmd)
+
+			err := m.Delete(context.Background(), tc.name)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expDelete, deleted)
+		})
+	}
+}
+
+type mockManagerQueries struct {
+	get  func(ctx context.Context, name string) (*Role, error)
+	list func(ctx context.Context) ([]*Role, error)
+}
+
+func (m *mockManagerQueries) Get(ctx context.Context, name string) (*Role, error) {
+	return m.get(ctx, name)
+}
+
+func (m *mockManagerQueries) List(ctx context.Context) ([]*Role, error) {
+	return m.list(ctx)
+}
+
+type mockManagerCMD struct {
+	insert func(ctx context.Context, r *Role) error
+	update func(ctx context.Context, r *Role) error
+	delete func(ctx context.Context, name string) error
+}
+
+func (m *mockManagerCMD) Insert(ctx context.Context, r *Role) error {
+	return m.insert(ctx, r)
+}
+
+func (m *mockManagerCMD) Update(ctx context.Context, r *Role) error {
+	return m.update(ctx, r)
+}
+
+func (m *mockManagerCMD) Delete(ctx context.Context, name string) error {
+	return m.delete(ctx, name)
+}
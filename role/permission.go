@@ -0,0 +1,54 @@
+package role
+
+// Permission allows an action. Users can always read, update and delete
+// themselves and manage their own sessions: permissions grant the same over
+// other users.
+type Permission string
+
+const (
+	PermUsersRead      Permission = "users:read"
+	PermUsersWrite     Permission = "users:write"
+	PermUsersDelete    Permission = "users:delete"
+	PermUsersUnlock    Permission = "users:unlock"
+	PermRolesAssign    Permission = "roles:assign"
+	PermRolesManage    Permission = "roles:manage"
+	PermSessionsManage Permission = "sessions:manage"
+)
+
+// PermissionInfo describes a permission of the catalog.
+type PermissionInfo struct {
+	Name        Permission
+	Description string
+}
+
+var catalog = []PermissionInfo{
+	{PermUsersRead, "List users and read any user, deleted ones included"},
+	{PermUsersWrite, "Update any user"},
+	{PermUsersDelete, "Delete and restore any user"},
+	{PermUsersUnlock, "See whether any user is locked out after failed logins and unlock it"},
+	{PermRolesAssign, "Give users any role other than the default one"},
+	{PermRolesManage, "Create, read, update and delete roles"},
+	{PermSessionsManage, "List and revoke the sessions of any user"},
+}
+
+// Catalog returns every known permission.
+func Catalog() []PermissionInfo {
+	c := make([]PermissionInfo, len(catalog))
+	copy(c, catalog)
+
+	return c
+}
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+func isKnownPermission(p Permission) bool {
+	for _, info := range catalog {
+		if info.Name == p {
+			return true
+		}
+	}
+
+	return false
+}
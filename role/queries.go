@@ -0,0 +1,17 @@
+package role
+
+import "context"
+
+type Queries interface {
+	Get
+	List
+}
+
+type Get interface {
+	Get(ctx context.Context, name string) (*Role, error)
+}
+
+// List returns the stored roles sorted by name.
+type List interface {
+	List(ctx context.Context) ([]*Role, error)
+}
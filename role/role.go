@@ -0,0 +1,71 @@
+package role
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+var (
+	ErrDoesNotExist      = errors.New("role does not exist")
+	ErrAlreadyExists     = errors.New("a role with this name already exists")
+	ErrInvalidName       = errors.New("invalid role name. Names are 2 to 32 lowercase letters, digits, _ or -, starting with a letter")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrBuiltin           = errors.New("built-in roles cannot be changed")
+	ErrInUse             = errors.New("role is given to users")
+)
+
+// Role is a named set of permissions. The built-in roles are defined in code
+// and cannot be changed; custom roles are stored.
+type Role struct {
+	Name        string
+	Description string
+	Permissions []Permission
+	Builtin     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Has reports whether the role grants p.
+func (r *Role) Has(p Permission) bool {
+	for _, perm := range r.Permissions {
+		if perm == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+func builtins() []*Role {
+	all := make([]Permission, 0, len(catalog))
+	for _, info := range catalog {
+		all = append(all, info.Name)
+	}
+
+	return []*Role{
+		{
+			Name:        user.RoleAdmin,
+			Description: "Every permission",
+			Permissions: all,
+			Builtin:     true,
+		},
+		{
+			Name:        user.RoleUser,
+			Description: "Default role: access to the own user only",
+			Permissions: []Permission{},
+			Builtin:     true,
+		},
+	}
+}
+
+func builtin(name string) (*Role, bool) {
+	for _, r := range builtins() {
+		if r.Name == name {
+			return r, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,239 @@
+// Package roletest provides a conformance suite that every storage backend of
+// custom roles must pass.
+package roletest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+type Repository interface {
+	role.Queries
+	role.Commands
+}
+
+// GiveRole gives a new user the role name, deleting the user when deleted is
+// true. It returns ErrInvalidRole, possibly wrapped, when the role is neither
+// built in nor stored in the repository.
+type GiveRole func(ctx context.Context, name string, deleted bool) error
+
+// Factory returns a new, empty repository together with a way to give its roles
+// to users. It is called once per test.
+type Factory func(t *testing.T) (Repository, GiveRole)
+
+// RunRepositoryTests checks that the repositories built by newRepository
+// behave like every other role storage backend.
+func RunRepositoryTests(t *testing.T, newRepository Factory) {
+	t.Run("insert and get", func(t *testing.T) {
+		repo, _ := newRepository(t)
+		testInsertAndGet(t, repo)
+	})
+	t.Run("update", func(t *testing.T) {
+		repo, _ := newRepository(t)
+		testUpdate(t, repo)
+	})
+	t.Run("list", func(t *testing.T) {
+		repo, _ := newRepository(t)
+		testList(t, repo)
+	})
+	t.Run("delete", func(t *testing.T) {
+		testDelete(t, newRepository)
+	})
+	t.Run("give", func(t *testing.T) {
+		testGive(t, newRepository)
+	})
+	t.Run("delete while giving", func(t *testing.T) {
+		testDeleteWhileGiving(t, newRepository)
+	})
+}
+
+func testInsertAndGet(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Microsecond)
+	r := &role.Role{
+		Name:        "support",
+		Description: "support staff",
+		Permissions: []role.Permission{role.PermUsersRead, role.PermSessionsManage},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	assert.NoError(t, repo.Insert(ctx, r))
+	assert.ErrorIs(t, repo.Insert(ctx, r), role.ErrAlreadyExists)
+
+	got, err := repo.Get(ctx, "support")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, r.Name, got.Name)
+		assert.Equal(t, r.Description, got.Description)
+		assert.Equal(t, r.Permissions, got.Permissions)
+		assert.True(t, r.CreatedAt.Equal(got.CreatedAt))
+		assert.True(t, r.UpdatedAt.Equal(got.UpdatedAt))
+	}
+
+	_, err = repo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, role.ErrDoesNotExist)
+
+	assert.NoError(t, repo.Insert(ctx, &role.Role{Name: "empty", CreatedAt: now, UpdatedAt: now}))
+	got, err = repo.Get(ctx, "empty")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Empty(t, got.Permissions)
+	}
+}
+
+func testUpdate(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Microsecond)
+	assert.NoError(t, repo.Insert(ctx, &role.Role{
+		Name:        "support",
+		Permissions: []role.Permission{role.PermUsersRead},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}))
+
+	later := now.Add(time.Hour)
+	err := repo.Update(ctx, &role.Role{
+		Name:        "support",
+		Description: "changed",
+		Permissions: []role.Permission{role.PermSessionsManage},
+		CreatedAt:   now,
+		UpdatedAt:   later,
+	})
+	assert.NoError(t, err)
+
+	got, err := repo.Get(ctx, "support")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "changed", got.Description)
+		assert.Equal(t, []role.Permission{role.PermSessionsManage}, got.Permissions)
+		assert.True(t, now.Equal(got.CreatedAt))
+		assert.True(t, later.Equal(got.UpdatedAt))
+	}
+
+	assert.ErrorIs(t, repo.Update(ctx, &role.Role{Name: "unknown"}), role.ErrDoesNotExist)
+}
+
+func testList(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	roles, err := repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, roles)
+
+	for _, name := range []string{"support", "auditor", "operator"} {
+		assert.NoError(t, repo.Insert(ctx, &role.Role{Name: name}))
+	}
+
+	roles, err = repo.List(ctx)
+	assert.NoError(t, err)
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"auditor", "operator", "support"}, names)
+}
+
+func testDelete(t *testing.T, newRepository Factory) {
+	testCases := []struct {
+		description string
+		given       bool
+		deleted     bool
+		expError    error
+	}{
+		{
+			description: "unused",
+		},
+		{
+			description: "given to a user",
+			given:       true,
+			expError:    role.ErrInUse,
+		},
+		{
+			description: "given to a deleted user",
+			given:       true,
+			deleted:     true,
+			expError:    role.ErrInUse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx := context.Background()
+			repo, giveRole := newRepository(t)
+
+			assert.NoError(t, repo.Insert(ctx, &role.Role{Name: "support"}))
+			if tc.given {
+				assert.NoError(t, giveRole(ctx, "support", tc.deleted))
+			}
+
+			err := repo.Delete(ctx, "support")
+			assert.ErrorIs(t, err, tc.expError)
+
+			_, err = repo.Get(ctx, "support")
+			if tc.expError != nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, role.ErrDoesNotExist)
+			}
+		})
+	}
+
+	t.Run("does not exist", func(t *testing.T) {
+		repo, _ := newRepository(t)
+		assert.ErrorIs(t, repo.Delete(context.Background(), "unknown"), role.ErrDoesNotExist)
+	})
+}
+
+func testGive(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo, giveRole := newRepository(t)
+
+	assert.NoError(t, giveRole(ctx, user.RoleUser, false))
+	assert.NoError(t, giveRole(ctx, user.RoleAdmin, false))
+	assert.ErrorIs(t, giveRole(ctx, "unknown", false), user.ErrInvalidRole)
+
+	assert.NoError(t, repo.Insert(ctx, &role.Role{Name: "support"}))
+	assert.NoError(t, repo.Delete(ctx, "support"))
+	assert.ErrorIs(t, giveRole(ctx, "support", false), user.ErrInvalidRole)
+}
+
+// testDeleteWhileGiving checks that a role is either deleted or given, never
+// both.
+func testDeleteWhileGiving(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo, giveRole := newRepository(t)
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("support-%d", i)
+		assert.NoError(t, repo.Insert(ctx, &role.Role{Name: name}))
+
+		var wg sync.WaitGroup
+		var deleteErr, giveErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			deleteErr = repo.Delete(ctx, name)
+		}()
+		go func() {
+			defer wg.Done()
+			giveErr = giveRole(ctx, name, false)
+		}()
+		wg.Wait()
+
+		if giveErr == nil {
+			assert.ErrorIs(t, deleteErr, role.ErrInUse)
+		} else {
+			assert.ErrorIs(t, giveErr, user.ErrInvalidRole)
+			assert.NoError(t, deleteErr)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/refresh"
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+func addAuthRoutes(router *mux.Router, auth *authMiddleware, authenticator Authenticator, sessions Sessions, refreshTokens RefreshTokens, users UserGetter, tokens TokenIssuer, cookie *sessionCookie) {
+	router.Methods(http.MethodPost).Path("/auth/login").HandlerFunc(handleLogin(authenticator, sessions, refreshTokens, tokens, cookie))
+	router.Methods(http.MethodPost).Path("/auth/refresh").HandlerFunc(handleRefresh(refreshTokens, users, tokens))
+	router.Methods(http.MethodPost).Path("/auth/logout").HandlerFunc(handleLogout(refreshTokens, sessions, cookie))
+	router.Methods(http.MethodGet).Path("/auth/me").Handler(auth.requireAuthentication(handleMe()))
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*user.User, error)
+}
+
+type SessionCreator interface {
+	Create(ctx context.Context, params session.CreateParams) (*session.Session, string, error)
+}
+
+type SessionTokenRevoker interface {
+	RevokeToken(ctx context.Context, token string) error
+}
+
+type RefreshIssuer interface {
+	Issue(ctx context.Context, userID string) (*refresh.Token, string, error)
+}
+
+type RefreshRotator interface {
+	Rotate(ctx context.Context, secret string) (*refresh.Token, string, error)
+}
+
+type RefreshRevoker interface {
+	Revoke(ctx context.Context, secret string) error
+}
+
+type RefreshTokens interface {
+	RefreshIssuer
+	RefreshRotator
+	RefreshRevoker
+}
+
+type TokenIssuer interface {
+	Issue(userID, role string) (string, *token.Claims, error)
+}
+
+func handleLogin(authenticator Authenticator, sessions SessionCreator, refreshTokens RefreshIssuer, tokens TokenIssuer, cookie *sessionCookie) http.HandlerFunc {
+	type loginRequest struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	type loginResponse struct {
+		Token                 string       `json:"token"`
+		SessionID             string       `json:"session_id"`
+		ExpiresAt             time.Time    `json:"expires_at"`
+		AccessToken           string       `json:"access_token"`
+		TokenType             string       `json:"token_type"`
+		AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+		RefreshToken          string       `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+		User                  userResponse `json:"user"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		u, err := authenticator.Authenticate(context.Background(), req.Username, req.Password)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			var locked *user.LockedError
+			switch {
+			case errors.As(err, &locked):
+				writeLocked(w, body, locked)
+			case errors.Is(err, user.ErrInvalidCredentials):
+				_ = httpx.WriteJSONResponse(w, http.StatusUnauthorized, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		sessionParams := session.CreateParams{
+			UserID:    u.ID,
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		}
+
+		s, sessionToken, err := sessions.Create(context.Background(), sessionParams)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		accessToken, claims, err := tokens.Issue(u.ID, u.Role.String())
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		rt, refreshToken, err := refreshTokens.Issue(context.Background(), u.ID)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		resp := loginResponse{
+			Token:                 sessionToken,
+			SessionID:             s.ID,
+			ExpiresAt:             s.ExpiresAt,
+			AccessToken:           accessToken,
+			TokenType:             "Bearer",
+			AccessTokenExpiresAt:  claims.ExpiresAt,
+			RefreshToken:          refreshToken,
+			RefreshTokenExpiresAt: rt.ExpiresAt,
+			User:                  newUserResponse(u),
+		}
+
+		cookie.set(w, sessionToken, s.ExpiresAt)
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+func handleRefresh(refreshTokens RefreshRotator, users UserGetter, tokens TokenIssuer) http.HandlerFunc {
+	type refreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	type refreshResponse struct {
+		AccessToken           string    `json:"access_token"`
+		TokenType             string    `json:"token_type"`
+		AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+		RefreshToken          string    `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		rt, refreshToken, err := refreshTokens.Rotate(context.Background(), req.RefreshToken)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, refresh.ErrDoesNotExist), errors.Is(err, refresh.ErrExpired),
+				errors.Is(err, refresh.ErrRevoked), errors.Is(err, refresh.ErrReused):
+				_ = httpx.WriteJSONResponse(w, http.StatusUnauthorized, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		// The role may have changed since the last token was issued.
+		u, err := users.GetByID(context.Background(), rt.UserID)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusUnauthorized, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		accessToken, claims, err := tokens.Issue(u.ID, u.Role.String())
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		resp := refreshResponse{
+			AccessToken:           accessToken,
+			TokenType:             "Bearer",
+			AccessTokenExpiresAt:  claims.ExpiresAt,
+			RefreshToken:          refreshToken,
+			RefreshTokenExpiresAt: rt.ExpiresAt,
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+// handleLogout ends the session of the cookie, if any, and revokes the refresh
+// token of the body, if any. The body may be empty for cookie clients.
+func handleLogout(refreshTokens RefreshRevoker, sessions SessionTokenRevoker, cookie *sessionCookie) http.HandlerFunc {
+	type logoutRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req logoutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		sessionToken, hasCookie := cookie.token(r)
+		if !hasCookie && req.RefreshToken == "" {
+			body := map[string]string{"error": "missing refresh token or session cookie"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if hasCookie {
+			if err := sessions.RevokeToken(context.Background(), sessionToken); err != nil {
+				body := map[string]string{"error": err.Error()}
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+				return
+			}
+			cookie.clear(w)
+		}
+
+		if req.RefreshToken == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if err := refreshTokens.Revoke(context.Background(), req.RefreshToken); err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, refresh.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusUnauthorized, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleMe() http.HandlerFunc {
+	type meResponse struct {
+		UserID string `json:"user_id"`
+		Role   string `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			writeUnauthorized(w, "not authenticated")
+			return
+		}
+
+		resp := meResponse{
+			UserID: p.UserID,
+			Role:   p.Role.String(),
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+// writeLocked answers requests of locked out users with the time they can try
+// again.
+func writeLocked(w http.ResponseWriter, body map[string]string, locked *user.LockedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(locked.Until).Seconds()))))
+	_ = httpx.WriteJSONResponse(w, http.StatusTooManyRequests, body)
+}
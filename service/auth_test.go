@@ -0,0 +1,376 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/refresh"
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLogin(t *testing.T) {
+	username := "usr"
+	password := "1234"
+	buff, _ := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	testCases := []struct {
+		description string
+		body        []byte
+		authErr     error
+		sessionErr  error
+		tokenErr    error
+		refreshErr  error
+		expStatus   int
+	}{
+		{
+			description: "invalid body",
+			body:        []byte("{"),
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid credentials",
+			body:        buff,
+			authErr:     user.ErrInvalidCredentials,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "locked out",
+			body:        buff,
+			authErr:     &user.LockedError{Until: time.Now().Add(90 * time.Second)},
+			expStatus:   http.StatusTooManyRequests,
+		},
+		{
+			description: "random auth err",
+			body:        buff,
+			authErr:     errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "random session err",
+			body:        buff,
+			sessionErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "random token err",
+			body:        buff,
+			tokenErr:    errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "random refresh token err",
+			body:        buff,
+			refreshErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			body:        buff,
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			a := &mockAuthenticator{func(ctx context.Context, name, pass string) (*user.User, error) {
+				assert.Equal(t, username, name)
+				assert.Equal(t, password, pass)
+
+				return &user.User{ID: "1"}, tc.authErr
+			}}
+			r.Header.Set("User-Agent", "agent")
+			s := &mockSessions{create: func(ctx context.Context, params session.CreateParams) (*session.Session, string, error) {
+				assert.Equal(t, "1", params.UserID)
+				assert.Equal(t, "agent", params.UserAgent)
+				assert.Equal(t, "192.0.2.1", params.IP)
+
+				return &session.Session{ID: "s", ExpiresAt: time.Now()}, "token", tc.sessionErr
+			}}
+
+			tokens := &mockTokenIssuer{func(userID, role string) (string, *token.Claims, error) {
+				assert.Equal(t, "1", userID)
+
+				return "access", &token.Claims{ExpiresAt: time.Now()}, tc.tokenErr
+			}}
+
+			rts := &mockRefreshTokens{issue: func(ctx context.Context, userID string) (*refresh.Token, string, error) {
+				assert.Equal(t, "1", userID)
+
+				return &refresh.Token{ExpiresAt: time.Now()}, "refresh", tc.refreshErr
+			}}
+
+			handleLogin(a, s, rts, tokens, testCookie)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "90", w.Result().Header.Get("Retry-After"))
+			}
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			assert.Equal(t, "token", response["token"])
+			assert.Equal(t, "s", response["session_id"])
+			assert.Equal(t, "access", response["access_token"])
+			assert.Equal(t, "Bearer", response["token_type"])
+			assert.Equal(t, "refresh", response["refresh_token"])
+
+			cookies := w.Result().Cookies()
+			if assert.Len(t, cookies, 1) {
+				assert.Equal(t, testCookie.name, cookies[0].Name)
+				assert.Equal(t, "token", cookies[0].Value)
+				assert.True(t, cookies[0].HttpOnly)
+				assert.True(t, cookies[0].Secure)
+			}
+
+			expKeys := []string{"expires_at", "access_token_expires_at", "refresh_token_expires_at", "user"}
+			for _, key := range expKeys {
+				_, ok := response[key]
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestHandleRefresh(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{"refresh_token": "old"})
+	testCases := []struct {
+		description string
+		body        []byte
+		rotateErr   error
+		getErr      error
+		tokenErr    error
+		expStatus   int
+	}{
+		{
+			description: "invalid body",
+			body:        []byte("{"),
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "unknown token",
+			body:        buff,
+			rotateErr:   refresh.ErrDoesNotExist,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "expired token",
+			body:        buff,
+			rotateErr:   refresh.ErrExpired,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "revoked token",
+			body:        buff,
+			rotateErr:   refresh.ErrRevoked,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "reused token",
+			body:        buff,
+			rotateErr:   refresh.ErrReused,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "random rotate err",
+			body:        buff,
+			rotateErr:   errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "deleted user",
+			body:        buff,
+			getErr:      user.ErrDoesNotExist,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "random token err",
+			body:        buff,
+			tokenErr:    errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			body:        buff,
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			rts := &mockRefreshTokens{rotate: func(ctx context.Context, secret string) (*refresh.Token, string, error) {
+				assert.Equal(t, "old", secret)
+
+				return &refresh.Token{UserID: "1", ExpiresAt: time.Now()}, "new", tc.rotateErr
+			}}
+			users := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
+				assert.Equal(t, "1", id)
+
+				return &user.User{ID: "1", Role: user.RoleAdmin}, tc.getErr
+			}}
+			tokens := &mockTokenIssuer{func(userID, role string) (string, *token.Claims, error) {
+				assert.Equal(t, "1", userID)
+				assert.Equal(t, user.RoleAdmin, role)
+
+				return "access", &token.Claims{ExpiresAt: time.Now()}, tc.tokenErr
+			}}
+
+			handleRefresh(rts, users, tokens)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			assert.Equal(t, "access", response["access_token"])
+			assert.Equal(t, "new", response["refresh_token"])
+		})
+	}
+}
+
+func TestHandleLogout(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{"refresh_token": "old"})
+	testCases := []struct {
+		description      string
+		body             []byte
+		cookie           bool
+		revokeErr        error
+		revokeCookieErr  error
+		expStatus        int
+		expClearedCookie bool
+	}{
+		{
+			description: "invalid body",
+			body:        []byte("{"),
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "no credentials",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "unknown token",
+			body:        buff,
+			revokeErr:   refresh.ErrDoesNotExist,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "random err",
+			body:        buff,
+			revokeErr:   errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description:     "random cookie err",
+			cookie:          true,
+			revokeCookieErr: errors.New("random error"),
+			expStatus:       http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			body:        buff,
+			expStatus:   http.StatusNoContent,
+		},
+		{
+			description:      "success with cookie",
+			cookie:           true,
+			expStatus:        http.StatusNoContent,
+			expClearedCookie: true,
+		},
+		{
+			description:      "success with cookie and refresh token",
+			body:             buff,
+			cookie:           true,
+			expStatus:        http.StatusNoContent,
+			expClearedCookie: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/logout", bytes.NewReader(tc.body))
+			if tc.cookie {
+				r.AddCookie(&http.Cookie{Name: testCookie.name, Value: "token"})
+			}
+			w := httptest.NewRecorder()
+			rts := &mockRefreshTokens{revoke: func(ctx context.Context, secret string) error {
+				assert.Equal(t, "old", secret)
+
+				return tc.revokeErr
+			}}
+			s := &mockSessions{revokeToken: func(ctx context.Context, token string) error {
+				assert.Equal(t, "token", token)
+
+				return tc.revokeCookieErr
+			}}
+
+			handleLogout(rts, s, testCookie)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			cookies := w.Result().Cookies()
+			if tc.expClearedCookie && assert.Len(t, cookies, 1) {
+				assert.Equal(t, testCookie.name, cookies[0].Name)
+				assert.Empty(t, cookies[0].Value)
+				assert.Equal(t, -1, cookies[0].MaxAge)
+			}
+		})
+	}
+}
+
+type mockAuthenticator struct {
+	authenticate func(ctx context.Context, username, password string) (*user.User, error)
+}
+
+func (m *mockAuthenticator) Authenticate(ctx context.Context, username, password string) (*user.User, error) {
+	return m.authenticate(ctx, username, password)
+}
+
+type mockTokenIssuer struct {
+	issue func(userID, role string) (string, *token.Claims, error)
+}
+
+func (m *mockTokenIssuer) Issue(userID, role string) (string, *token.Claims, error) {
+	return m.issue(userID, role)
+}
+
+type mockRefreshTokens struct {
+	issue     func(ctx context.Context, userID string) (*refresh.Token, string, error)
+	rotate    func(ctx context.Context, secret string) (*refresh.Token, string, error)
+	revoke    func(ctx context.Context, secret string) error
+	revokeAll func(ctx context.Context, userID string) error
+}
+
+func (m *mockRefreshTokens) Issue(ctx context.Context, userID string) (*refresh.Token, string, error) {
+	return m.issue(ctx, userID)
+}
+
+func (m *mockRefreshTokens) Rotate(ctx context.Context, secret string) (*refresh.Token, string, error) {
+	return m.rotate(ctx, secret)
+}
+
+func (m *mockRefreshTokens) Revoke(ctx context.Context, secret string) error {
+	return m.revoke(ctx, secret)
+}
+
+func (m *mockRefreshTokens) RevokeAll(ctx context.Context, userID string) error {
+	return m.revokeAll(ctx, userID)
+}
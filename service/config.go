@@ -6,12 +6,20 @@ import (
 	"github.com/mabaro3009/example-architecture-go/data"
 )
 
+// EnvironmentLocalDev is the environment of local runs.
+const EnvironmentLocalDev = "local-dev"
+
 type Config struct {
 	Environment string `envconfig:"environment" default:"local-dev"`
 
 	ListenAddress string `envconfig:"listen_address" default:":8081"`
 
-	DatabaseType data.Type `envconfig:"database_type" default:"0"`
+	DatabaseType data.Type `envconfig:"database_type" default:"in_memory"`
+
+	DatabaseDir              string `envconfig:"db_dir" default:"db"`
+	DatabaseSnapshotInterval int    `envconfig:"db_snapshot_interval" default:"1000"`
+
+	DatabasePath string `envconfig:"db_path" default:"db/users.db"`
 
 	DatabaseHost           string        `envconfig:"db_host" default:"localhost"`
 	DatabasePort           int           `envconfig:"db_port" default:"5432"`
@@ -19,4 +27,85 @@ type Config struct {
 	DatabaseUser           string        `envconfig:"db_user" default:"postgres"`
 	DatabasePassword       string        `envconfig:"db_password" default:"postgres"`
 	DatabaseConnectTimeout time.Duration `envconfig:"db_connect_timeout" default:"15s"`
+	DatabaseSSLMode        string        `envconfig:"db_ssl_mode" default:"require"`
+
+	SessionTTL          time.Duration `envconfig:"session_ttl" default:"24h"`
+	SessionCookieName   string        `envconfig:"session_cookie_name" default:"session"`
+	SessionCookieSecure bool          `envconfig:"session_cookie_secure" default:"true"`
+
+	// TokenSecret is used by HS256 and TokenPrivateKeyPath, a PEM file, by
+	// EdDSA and RS256. Without a secret, HS256 uses a random one that does
+	// not survive restarts.
+	TokenAlgorithm      string        `envconfig:"token_algorithm" default:"HS256"`
+	TokenSecret         string        `envconfig:"token_secret"`
+	TokenPrivateKeyPath string        `envconfig:"token_private_key_path"`
+	TokenIssuer         string        `envconfig:"token_issuer" default:"example-architecture-go"`
+	TokenAudience       string        `envconfig:"token_audience" default:"example-architecture-go"`
+	TokenTTL            time.Duration `envconfig:"token_ttl" default:"15m"`
+
+	RefreshTokenTTL time.Duration `envconfig:"refresh_token_ttl" default:"720h"`
+
+	// SweepInterval is how often expired sessions and refresh tokens that can
+	// no longer be used are deleted. 0 keeps them until they are used again.
+	SweepInterval time.Duration `envconfig:"sweep_interval" default:"10m"`
+
+	PasswordResetTokenTTL time.Duration `envconfig:"password_reset_token_ttl" default:"1h"`
+
+	// PasswordResetLogTokens writes password reset tokens to the log, which is
+	// always done in the local-dev environment. There is no other way to send
+	// them, so without it password resets are disabled.
+	PasswordResetLogTokens bool `envconfig:"password_reset_log_tokens"`
+
+	// Users are locked out for LockoutDuration after LockoutThreshold
+	// consecutive failed logins, twice as long on every further lock up to
+	// LockoutMaxDuration. A threshold of 0 disables lockouts.
+	LockoutThreshold   int           `envconfig:"lockout_threshold" default:"5"`
+	LockoutDuration    time.Duration `envconfig:"lockout_duration" default:"1m"`
+	LockoutMaxDuration time.Duration `envconfig:"lockout_max_duration" default:"1h"`
+
+	// The password policy. Lengths are counted in characters and zero values
+	// disable a rule. PasswordMinEntropy is a rough estimate in bits.
+	PasswordMinLength        int     `envconfig:"password_min_length" default:"8"`
+	PasswordMaxLength        int     `envconfig:"password_max_length" default:"128"`
+	PasswordRequireLower     bool    `envconfig:"password_require_lower" default:"false"`
+	PasswordRequireUpper     bool    `envconfig:"password_require_upper" default:"false"`
+	PasswordRequireDigit     bool    `envconfig:"password_require_digit" default:"false"`
+	PasswordRequireSymbol    bool    `envconfig:"password_require_symbol" default:"false"`
+	PasswordDisallowUsername bool    `envconfig:"password_disallow_username" default:"true"`
+	PasswordMaxRepeated      int     `envconfig:"password_max_repeated" default:"3"`
+	PasswordMaxSequential    int     `envconfig:"password_max_sequential" default:"4"`
+	PasswordMinEntropy       float64 `envconfig:"password_min_entropy" default:"30"`
+
+	// BreachedPasswordsPath is a file with the SHA-1 hashes of breached
+	// passwords, one per line and sorted, that new passwords are screened
+	// against.
+	BreachedPasswordsPath string `envconfig:"breached_passwords_path"`
+
+	// PasswordHistorySize is how many of their last passwords, the current one
+	// included, users cannot reuse when changing it. 0 allows any.
+	PasswordHistorySize int `envconfig:"password_history_size" default:"5"`
+
+	// PasswordHashAlgorithm is bcrypt, argon2id or scrypt. Only the parameters
+	// of the selected algorithm are used. Argon2idMemory is in KiB and ScryptN
+	// must be a power of two.
+	PasswordHashAlgorithm string `envconfig:"password_hash_algorithm" default:"bcrypt"`
+	BCryptCost            int    `envconfig:"bcrypt_cost" default:"10"`
+	Argon2idMemory        uint32 `envconfig:"argon2id_memory" default:"65536"`
+	Argon2idIterations    uint32 `envconfig:"argon2id_iterations" default:"3"`
+	Argon2idParallelism   uint8  `envconfig:"argon2id_parallelism" default:"2"`
+	ScryptN               int    `envconfig:"scrypt_n" default:"32768"`
+	ScryptR               int    `envconfig:"scrypt_r" default:"8"`
+	ScryptP               int    `envconfig:"scrypt_p" default:"1"`
+
+	// PasswordPepperKeys and PasswordPepperKeyFiles map key IDs to pepper keys
+	// or to files holding them, as id1:value1,id2:value2. New hashes are
+	// peppered with PasswordPepperKeyID and older keys are kept to verify the
+	// passwords that were not rehashed yet. Without keys, passwords are not
+	// peppered.
+	PasswordPepperKeyID    string            `envconfig:"password_pepper_key_id"`
+	PasswordPepperKeys     map[string]string `envconfig:"password_pepper_keys"`
+	PasswordPepperKeyFiles map[string]string `envconfig:"password_pepper_key_files"`
+
+	BootstrapAdminUsername string `envconfig:"bootstrap_admin_username"`
+	BootstrapAdminPassword string `envconfig:"bootstrap_admin_password"`
 }
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mabaro3009/example-architecture-go/data"
+	"github.com/mabaro3009/example-architecture-go/infra/memory"
+	"github.com/mabaro3009/example-architecture-go/infra/postgres"
+	"github.com/mabaro3009/example-architecture-go/infra/sqlite"
+	"github.com/mabaro3009/example-architecture-go/pkg/migrate"
+)
+
+var ErrNoSchema = errors.New("the configured database type has no schema to migrate")
+
+// newDBs opens the user and role storage selected by the configuration.
+// Sessions, refresh tokens, password reset tokens, password histories and login
+// lockouts are always kept in memory.
+func newDBs(conf *Config) (*dbs, []func() error, error) {
+	users, roles, closers, err := newUserDB(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dbs{
+		user:    users,
+		session: memory.NewSessionDB(),
+		refresh: memory.NewRefreshTokenDB(),
+		role:    roles,
+		reset:   memory.NewResetTokenDB(),
+		lockout: memory.NewLockoutDB(),
+
+		passwordHistory: memory.NewPasswordHistoryDB(),
+	}, closers, nil
+}
+
+// newUserDB opens the users and the custom roles, which live in the same
+// database so a role is only deleted when no user has it.
+func newUserDB(conf *Config) (userDB, roleDB, []func() error, error) {
+	switch conf.DatabaseType {
+	case data.TypeInMemory:
+		db := memory.NewUserDB()
+
+		return db, memory.NewRoleDB(db), nil, nil
+	case data.TypeFile:
+		db, err := memory.OpenUserDB(conf.DatabaseDir, conf.DatabaseSnapshotInterval)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		roles, err := memory.OpenRoleDB(conf.DatabaseDir, db)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, nil, err
+		}
+
+		return db, roles, []func() error{db.Close}, nil
+	case data.TypePostgres, data.TypeSQLite:
+		ctx := context.Background()
+		db, m, err := openSQL(ctx, conf)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err = m.Check(ctx); err != nil {
+			_ = db.Close()
+			return nil, nil, nil, fmt.Errorf("%w (run the migrate up command)", err)
+		}
+
+		if conf.DatabaseType == data.TypeSQLite {
+			return sqlite.NewUserDB(db), sqlite.NewRoleDB(db), []func() error{db.Close}, nil
+		}
+
+		return postgres.NewUserDB(db), postgres.NewRoleDB(db), []func() error{db.Close}, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported database type %q", conf.DatabaseType)
+	}
+}
+
+// NewMigrator returns the schema migrator of the configured database together
+// with a function that releases its connection.
+func NewMigrator(conf *Config) (*migrate.Migrator, func() error, error) {
+	switch conf.DatabaseType {
+	case data.TypePostgres, data.TypeSQLite:
+		db, m, err := openSQL(context.Background(), conf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return m, db.Close, nil
+	case data.TypeInMemory, data.TypeFile:
+		return nil, nil, ErrNoSchema
+	default:
+		return nil, nil, fmt.Errorf("unsupported database type %q", conf.DatabaseType)
+	}
+}
+
+func openSQL(ctx context.Context, conf *Config) (*sql.DB, *migrate.Migrator, error) {
+	var (
+		db          *sql.DB
+		newMigrator func(*sql.DB) (*migrate.Migrator, error)
+		err         error
+	)
+	switch conf.DatabaseType {
+	case data.TypePostgres:
+		newMigrator = postgres.NewMigrator
+		db, err = postgres.Open(ctx, &postgres.Config{
+			Host:           conf.DatabaseHost,
+			Port:           conf.DatabasePort,
+			Name:           conf.DatabaseName,
+			User:           conf.DatabaseUser,
+			Password:       conf.DatabasePassword,
+			ConnectTimeout: conf.DatabaseConnectTimeout,
+			SSLMode:        conf.DatabaseSSLMode,
+		})
+	case data.TypeSQLite:
+		newMigrator = sqlite.NewMigrator
+		db, err = sqlite.Open(ctx, conf.DatabasePath)
+	default:
+		return nil, nil, fmt.Errorf("%q is not a SQL database type", conf.DatabaseType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := newMigrator(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+
+	return db, m, nil
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+var (
+	errUnauthenticated = errors.New("not authenticated")
+	errNoCredentials   = fmt.Errorf("%w: missing bearer token or session cookie", errUnauthenticated)
+)
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// Principal is the authenticated caller of a request. SessionID is only set
+// for callers authenticated with a session cookie.
+type Principal struct {
+	UserID      string
+	Role        user.Role
+	Permissions []role.Permission
+	SessionID   string
+}
+
+// Can reports whether p is granted perm. It is safe to call on a nil
+// principal.
+func (p *Principal) Can(perm role.Permission) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, granted := range p.Permissions {
+		if granted == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+func withPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFromContext returns the principal stored by the authentication
+// middleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+
+	return p, ok
+}
+
+type TokenVerifier interface {
+	Verify(signed string) (*token.Claims, error)
+}
+
+type SessionAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*session.Session, error)
+}
+
+type RoleGetter interface {
+	Get(ctx context.Context, name string) (*role.Role, error)
+}
+
+// authMiddleware authenticates requests with a bearer access token or a
+// session cookie. A bearer token takes precedence over the cookie. The user
+// and the permissions of its role are looked up on every request, so deleting
+// a user or changing its role or the role's permissions applies to tokens
+// issued before.
+type authMiddleware struct {
+	verifier TokenVerifier
+	sessions SessionAuthenticator
+	users    user.GetByID
+	roles    RoleGetter
+	cookie   *sessionCookie
+}
+
+func newAuthMiddleware(verifier TokenVerifier, sessions SessionAuthenticator, users user.GetByID, roles RoleGetter, cookie *sessionCookie) *authMiddleware {
+	return &authMiddleware{
+		verifier: verifier,
+		sessions: sessions,
+		users:    users,
+		roles:    roles,
+		cookie:   cookie,
+	}
+}
+
+// requireAuthentication rejects requests without valid credentials with 401
+// and stores the principal in the request context.
+func (a *authMiddleware) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.principal(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// allowAnonymous lets requests without credentials through without a
+// principal. Invalid credentials are still rejected with 401.
+func (a *authMiddleware) allowAnonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.principal(r)
+		if errors.Is(err, errNoCredentials) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// principal returns the caller of r. Errors caused by the credentials wrap
+// errUnauthenticated.
+func (a *authMiddleware) principal(r *http.Request) (*Principal, error) {
+	p, err := a.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rl, err := a.roles.Get(r.Context(), p.Role.String())
+	switch {
+	case errors.Is(err, role.ErrDoesNotExist):
+		p.Permissions = nil
+	case err != nil:
+		return nil, err
+	default:
+		p.Permissions = rl.Permissions
+	}
+
+	return p, nil
+}
+
+func (a *authMiddleware) authenticate(r *http.Request) (*Principal, error) {
+	if r.Header.Get("Authorization") == "" {
+		if sessionToken, ok := a.cookie.token(r); ok {
+			return a.sessionPrincipal(r.Context(), sessionToken)
+		}
+		return nil, errNoCredentials
+	}
+
+	signed, ok := bearerToken(r)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported authorization scheme", errUnauthenticated)
+	}
+
+	claims, err := a.verifier.Verify(signed)
+	if err != nil {
+		if errors.Is(err, token.ErrExpiredToken) || errors.Is(err, token.ErrInvalidToken) {
+			return nil, fmt.Errorf("%w: %v", errUnauthenticated, err)
+		}
+		return nil, err
+	}
+
+	// The role in the token may be stale and the user may have been deleted.
+	return a.userPrincipal(r.Context(), claims.UserID, "")
+}
+
+func (a *authMiddleware) sessionPrincipal(ctx context.Context, sessionToken string) (*Principal, error) {
+	s, err := a.sessions.Authenticate(ctx, sessionToken)
+	if err != nil {
+		if errors.Is(err, session.ErrDoesNotExist) || errors.Is(err, session.ErrExpired) {
+			return nil, fmt.Errorf("%w: %v", errUnauthenticated, err)
+		}
+		return nil, err
+	}
+
+	// Sessions do not carry the role, and the user may have been deleted.
+	return a.userPrincipal(ctx, s.UserID, s.ID)
+}
+
+// userPrincipal returns the principal of the stored user userID, rejecting
+// deleted users.
+func (a *authMiddleware) userPrincipal(ctx context.Context, userID, sessionID string) (*Principal, error) {
+	u, err := a.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrDoesNotExist) {
+			return nil, fmt.Errorf("%w: %v", errUnauthenticated, err)
+		}
+		return nil, err
+	}
+
+	return &Principal{
+		UserID:    u.ID,
+		Role:      u.Role,
+		SessionID: sessionID,
+	}, nil
+}
+
+// requirePermission rejects callers without perm with 403. It must run after
+// the authentication middleware.
+func requirePermission(perm role.Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w, errUnauthenticated.Error())
+				return
+			}
+
+			if !p.Can(perm) {
+				writeMissingPermission(w, perm)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireSelfOr rejects with 403 callers that are not the user of the id url
+// variable and lack perm. It must run after the authentication middleware.
+func requireSelfOr(perm role.Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w, errUnauthenticated.Error())
+				return
+			}
+
+			if p.UserID != mux.Vars(r)["id"] && !p.Can(perm) {
+				writeMissingPermission(w, perm)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnauthenticated) {
+		writeUnauthorized(w, err.Error())
+		return
+	}
+
+	body := map[string]string{"error": err.Error()}
+	_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+}
+
+func writeUnauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	body := map[string]string{"error": msg}
+	_ = httpx.WriteJSONResponse(w, http.StatusUnauthorized, body)
+}
+
+func writeMissingPermission(w http.ResponseWriter, perm role.Permission) {
+	writeForbidden(w, fmt.Sprintf("missing permission %s", perm))
+}
+
+func writeForbidden(w http.ResponseWriter, msg string) {
+	body := map[string]string{"error": msg}
+	_ = httpx.WriteJSONResponse(w, http.StatusForbidden, body)
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/infra/memory"
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = bytes.Repeat([]byte("s"), 32)
+
+func newTestJWT(t *testing.T) *token.JWT {
+	jwt, err := token.NewHS256(testSecret, token.Options{Issuer: "iss", Audience: "aud"})
+	assert.NoError(t, err)
+
+	return jwt
+}
+
+// newTestRoles returns the built-in roles plus an auditor role that can only
+// read users.
+func newTestRoles(t *testing.T) *role.Manager {
+	db := memory.NewRoleDB(memory.NewUserDB())
+	roles := role.NewManager(db, db)
+	_, err := roles.Create(context.Background(), role.CreateParams{
+		Name:        "auditor",
+		Permissions: []role.Permission{role.PermUsersRead},
+	})
+	assert.NoError(t, err)
+
+	return roles
+}
+
+// testPrincipal returns a principal with the permissions of roleName.
+func testPrincipal(t *testing.T, userID, roleName string) *Principal {
+	rl, err := newTestRoles(t).Get(context.Background(), roleName)
+	assert.NoError(t, err)
+
+	return &Principal{UserID: userID, Role: user.Role(roleName), Permissions: rl.Permissions}
+}
+
+func newTestAuthMiddleware(t *testing.T) *authMiddleware {
+	sessions := &mockSessions{authenticate: func(ctx context.Context, token string) (*session.Session, error) {
+		switch token {
+		case "valid":
+			return &session.Session{ID: "s", UserID: "1"}, nil
+		case "expired":
+			return nil, session.ErrExpired
+		case "deleted":
+			return &session.Session{ID: "d", UserID: "2"}, nil
+		case "broken":
+			return nil, errors.New("random error")
+		default:
+			return nil, session.ErrDoesNotExist
+		}
+	}}
+	users := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
+		switch id {
+		case "1":
+			return &user.User{ID: "1", Role: user.RoleAdmin}, nil
+		case "3":
+			return &user.User{ID: "3", Role: user.RoleUser}, nil
+		case "broken":
+			return nil, errors.New("random error")
+		default:
+			return nil, user.ErrDoesNotExist
+		}
+	}}
+
+	return newAuthMiddleware(newTestJWT(t), sessions, users, newTestRoles(t), testCookie)
+}
+
+func bearer(t *testing.T, userID, role string) string {
+	signed, _, err := newTestJWT(t).Issue(userID, role)
+	assert.NoError(t, err)
+
+	return "Bearer " + signed
+}
+
+func TestRequireAuthentication(t *testing.T) {
+	other, err := token.NewHS256(bytes.Repeat([]byte("o"), 32), token.Options{Issuer: "iss", Audience: "aud"})
+	assert.NoError(t, err)
+	expired, err := token.NewHS256(testSecret, token.Options{Issuer: "iss", Audience: "aud", TTL: time.Nanosecond})
+	assert.NoError(t, err)
+
+	forged, _, err := other.Issue("1", user.RoleAdmin)
+	assert.NoError(t, err)
+	old, _, err := expired.Issue("1", user.RoleAdmin)
+	assert.NoError(t, err)
+	time.Sleep(time.Second)
+
+	testCases := []struct {
+		description  string
+		header       string
+		cookie       string
+		expStatus    int
+		expSessionID string
+	}{
+		{
+			description: "missing header",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "not bearer",
+			header:      "Basic dXNyOnB3ZA==",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "garbage token",
+			header:      "Bearer garbage",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "other secret",
+			header:      "Bearer " + forged,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "expired",
+			header:      "Bearer " + old,
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "success",
+			header:      bearer(t, "1", user.RoleAdmin),
+			expStatus:   http.StatusOK,
+		},
+		{
+			description: "role of the token is stale",
+			header:      bearer(t, "1", user.RoleUser),
+			expStatus:   http.StatusOK,
+		},
+		{
+			description: "token of deleted user",
+			header:      bearer(t, "2", user.RoleAdmin),
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "random user error",
+			header:      bearer(t, "broken", user.RoleAdmin),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "case insensitive scheme",
+			header:      "bearer " + strings.TrimPrefix(bearer(t, "1", user.RoleAdmin), "Bearer "),
+			expStatus:   http.StatusOK,
+		},
+		{
+			description:  "session cookie",
+			cookie:       "valid",
+			expStatus:    http.StatusOK,
+			expSessionID: "s",
+		},
+		{
+			description: "unknown session cookie",
+			cookie:      "unknown",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "expired session cookie",
+			cookie:      "expired",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "session cookie of deleted user",
+			cookie:      "deleted",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description: "random session error",
+			cookie:      "broken",
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "bearer token takes precedence",
+			header:      "Bearer garbage",
+			cookie:      "valid",
+			expStatus:   http.StatusUnauthorized,
+		},
+	}
+
+	auth := newTestAuthMiddleware(t)
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if tc.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: testCookie.name, Value: tc.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, ok := PrincipalFromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, "1", p.UserID)
+				assert.Equal(t, user.Role(user.RoleAdmin), p.Role)
+				assert.True(t, p.Can(role.PermRolesManage))
+				assert.Equal(t, tc.expSessionID, p.SessionID)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			auth.requireAuthentication(next).ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Result().Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequireAuthentication_Demoted(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	r.Header.Set("Authorization", bearer(t, "3", user.RoleAdmin))
+	w := httptest.NewRecorder()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, user.Role(user.RoleUser), p.Role)
+		assert.Empty(t, p.Permissions)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newTestAuthMiddleware(t).requireAuthentication(next).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestAllowAnonymous(t *testing.T) {
+	testCases := []struct {
+		description  string
+		header       string
+		expStatus    int
+		expPrincipal bool
+	}{
+		{
+			description: "anonymous",
+			expStatus:   http.StatusOK,
+		},
+		{
+			description: "invalid token",
+			header:      "Bearer garbage",
+			expStatus:   http.StatusUnauthorized,
+		},
+		{
+			description:  "authenticated",
+			header:       bearer(t, "1", user.RoleUser),
+			expStatus:    http.StatusOK,
+			expPrincipal: true,
+		},
+	}
+
+	auth := newTestAuthMiddleware(t)
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, ok := PrincipalFromContext(r.Context())
+				assert.Equal(t, tc.expPrincipal, ok)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			auth.allowAnonymous(next).ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestPermissionMiddlewares(t *testing.T) {
+	testCases := []struct {
+		description string
+		principal   *Principal
+		expPerm     int
+		expSelfOr   int
+	}{
+		{
+			description: "no principal",
+			expPerm:     http.StatusUnauthorized,
+			expSelfOr:   http.StatusUnauthorized,
+		},
+		{
+			description: "self",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			expPerm:     http.StatusForbidden,
+			expSelfOr:   http.StatusOK,
+		},
+		{
+			description: "other user",
+			principal:   &Principal{UserID: "2", Role: user.RoleUser},
+			expPerm:     http.StatusForbidden,
+			expSelfOr:   http.StatusForbidden,
+		},
+		{
+			description: "other permission",
+			principal:   &Principal{UserID: "2", Permissions: []role.Permission{role.PermUsersWrite}},
+			expPerm:     http.StatusForbidden,
+			expSelfOr:   http.StatusForbidden,
+		},
+		{
+			description: "granted",
+			principal:   &Principal{UserID: "2", Permissions: []role.Permission{role.PermUsersRead}},
+			expPerm:     http.StatusOK,
+			expSelfOr:   http.StatusOK,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			middlewares := map[string]mux.MiddlewareFunc{
+				"permission": requirePermission(role.PermUsersRead),
+				"self or":    requireSelfOr(role.PermUsersRead),
+			}
+			for name, middleware := range middlewares {
+				r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+				r = mux.SetURLVars(r, map[string]string{"id": "1"})
+				if tc.principal != nil {
+					r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+				}
+				w := httptest.NewRecorder()
+
+				middleware(next).ServeHTTP(w, r)
+
+				expStatus := tc.expPerm
+				if name == "self or" {
+					expStatus = tc.expSelfOr
+				}
+				assert.Equal(t, expStatus, w.Result().StatusCode, name)
+			}
+		})
+	}
+}
+
+// TestRoutesAuthorization checks the access rules of every protected route
+// through the router, with handlers that always succeed.
+func TestRoutesAuthorization(t *testing.T) {
+	var (
+		anonymous = ""
+		self      = bearer(t, "1", user.RoleUser)
+		other     = bearer(t, "2", user.RoleUser)
+		admin     = bearer(t, "9", user.RoleAdmin)
+		auditor   = bearer(t, "8", "auditor")
+		unknown   = bearer(t, "7", "removed")
+		assigner  = bearer(t, "6", "assigner")
+	)
+	testCases := []struct {
+		method    string
+		path      string
+		body      string
+		caller    string
+		expStatus int
+	}{
+		{http.MethodPost, "/users", `{"username":"usr","role":"user"}`, anonymous, http.StatusCreated},
+		{http.MethodPost, "/users", `{"username":"usr","role":"admin"}`, anonymous, http.StatusForbidden},
+		{http.MethodPost, "/users", `{"username":"usr","role":"admin"}`, self, http.StatusForbidden},
+		{http.MethodPost, "/users", `{"username":"usr","role":"admin"}`, admin, http.StatusCreated},
+		{http.MethodPost, "/users", `{"username":"usr","role":"admin"}`, assigner, http.StatusForbidden},
+		{http.MethodPost, "/users", `{"username":"usr","role":"auditor"}`, assigner, http.StatusCreated},
+		{http.MethodPost, "/users", `{"username":"usr","role":"user"}`, "Bearer garbage", http.StatusUnauthorized},
+		{http.MethodGet, "/users", "", anonymous, http.StatusUnauthorized},
+		{http.MethodGet, "/users", "", self, http.StatusForbidden},
+		{http.MethodGet, "/users", "", admin, http.StatusOK},
+		{http.MethodGet, "/users", "", auditor, http.StatusOK},
+		{http.MethodGet, "/users", "", unknown, http.StatusForbidden},
+		{http.MethodGet, "/users/1", "", anonymous, http.StatusUnauthorized},
+		{http.MethodGet, "/users/1", "", self, http.StatusOK},
+		{http.MethodGet, "/users/1", "", other, http.StatusForbidden},
+		{http.MethodGet, "/users/1", "", admin, http.StatusOK},
+		{http.MethodGet, "/users/1?include_deleted=true", "", self, http.StatusForbidden},
+		{http.MethodGet, "/users/1?include_deleted=true", "", admin, http.StatusOK},
+		{http.MethodPatch, "/users/1", `{"username":"new"}`, anonymous, http.StatusUnauthorized},
+		{http.MethodPatch, "/users/1", `{"username":"new"}`, self, http.StatusOK},
+		{http.MethodPatch, "/users/1", `{"username":"new"}`, other, http.StatusForbidden},
+		{http.MethodPatch, "/users/1", `{"role":"admin"}`, self, http.StatusForbidden},
+		{http.MethodPatch, "/users/1", `{"role":"admin"}`, admin, http.StatusOK},
+		{http.MethodPatch, "/users/1", `{"username":"new"}`, auditor, http.StatusForbidden},
+		{http.MethodPatch, "/users/6", `{"role":"admin"}`, assigner, http.StatusForbidden},
+		{http.MethodPatch, "/users/6", `{"role":"auditor"}`, assigner, http.StatusOK},
+		{http.MethodPatch, "/users/6", `{"role":"unknown"}`, assigner, http.StatusOK},
+		{http.MethodDelete, "/users/1", "", anonymous, http.StatusUnauthorized},
+		{http.MethodDelete, "/users/1", "", self, http.StatusNoContent},
+		{http.MethodDelete, "/users/1", "", other, http.StatusForbidden},
+		{http.MethodDelete, "/users/1", "", admin, http.StatusNoContent},
+		{http.MethodPost, "/users/1/restore", "", self, http.StatusForbidden},
+		{http.MethodPost, "/users/1/restore", "", admin, http.StatusOK},
+		{http.MethodPost, "/users/1/password", `{"current_password":"old","new_password":"new"}`, anonymous, http.StatusUnauthorized},
+		{http.MethodPost, "/users/1/password", `{"current_password":"old","new_password":"new"}`, self, http.StatusNoContent},
+		{http.MethodPost, "/users/1/password", `{"current_password":"old","new_password":"new"}`, other, http.StatusForbidden},
+		{http.MethodPost, "/users/1/password", `{"current_password":"old","new_password":"new"}`, auditor, http.StatusForbidden},
+		{http.MethodPost, "/users/1/password", `{"current_password":"old","new_password":"new"}`, admin, http.StatusNoContent},
+		{http.MethodPost, "/users/1/unlock", "", anonymous, http.StatusUnauthorized},
+		{http.MethodPost, "/users/1/unlock", "", self, http.StatusForbidden},
+		{http.MethodPost, "/users/1/unlock", "", auditor, http.StatusForbidden},
+		{http.MethodPost, "/users/1/unlock", "", admin, http.StatusNoContent},
+		{http.MethodGet, "/users/1/sessions", "", anonymous, http.StatusUnauthorized},
+		{http.MethodGet, "/users/1/sessions", "", self, http.StatusOK},
+		{http.MethodGet, "/users/1/sessions", "", other, http.StatusForbidden},
+		{http.MethodGet, "/users/1/sessions", "", admin, http.StatusOK},
+		{http.MethodDelete, "/users/1/sessions", "", other, http.StatusForbidden},
+		{http.MethodDelete, "/users/1/sessions", "", self, http.StatusNoContent},
+		{http.MethodDelete, "/users/1/sessions/s", "", other, http.StatusForbidden},
+		{http.MethodDelete, "/users/1/sessions/s", "", admin, http.StatusNoContent},
+		{http.MethodGet, "/permissions", "", anonymous, http.StatusUnauthorized},
+		{http.MethodGet, "/permissions", "", auditor, http.StatusForbidden},
+		{http.MethodGet, "/permissions", "", admin, http.StatusOK},
+		{http.MethodGet, "/roles", "", self, http.StatusForbidden},
+		{http.MethodGet, "/roles", "", admin, http.StatusOK},
+		{http.MethodPost, "/roles", `{"name":"support"}`, auditor, http.StatusForbidden},
+		{http.MethodPost, "/roles", `{"name":"support"}`, admin, http.StatusCreated},
+		{http.MethodGet, "/roles/auditor", "", self, http.StatusForbidden},
+		{http.MethodGet, "/roles/auditor", "", admin, http.StatusOK},
+		{http.MethodPatch, "/roles/auditor", `{"description":"new"}`, auditor, http.StatusForbidden},
+		{http.MethodDelete, "/roles/auditor", "", self, http.StatusForbidden},
+		{http.MethodGet, "/auth/me", "", anonymous, http.StatusUnauthorized},
+		{http.MethodGet, "/auth/me", "", self, http.StatusOK},
+	}
+
+	roleByID := map[string]string{"1": user.RoleUser, "2": user.RoleUser, "9": user.RoleAdmin, "8": "auditor", "7": "removed", "6": "assigner"}
+	getUser := func(ctx context.Context, id string) (*user.User, error) {
+		return &user.User{ID: id, Role: user.Role(roleByID[id])}, nil
+	}
+	query := &mockQuery{getByID: getUser, getByIDIncludingDeleted: getUser}
+	sessions := &mockSessions{
+		list: func(ctx context.Context, userID string) ([]*session.Session, error) {
+			return nil, nil
+		},
+		revoke: func(ctx context.Context, userID, id string) error {
+			return nil
+		},
+		revokeAll: func(ctx context.Context, userID string) error {
+			return nil
+		},
+	}
+
+	roles := &mockRoles{
+		get: func(ctx context.Context, name string) (*role.Role, error) {
+			if name == "assigner" {
+				return &role.Role{Name: name, Permissions: []role.Permission{role.PermRolesAssign, role.PermUsersRead}}, nil
+			}
+
+			return newTestRoles(t).Get(ctx, name)
+		},
+		list: func(ctx context.Context) ([]*role.Role, error) {
+			return nil, nil
+		},
+		create: func(ctx context.Context, params role.CreateParams) (*role.Role, error) {
+			return &role.Role{Name: params.Name}, nil
+		},
+	}
+
+	router := mux.NewRouter()
+	auth := newAuthMiddleware(newTestJWT(t), sessions, query, roles, testCookie)
+	addUserRoutes(router, auth,
+		&mockCreator{func(ctx context.Context, params user.CreateParams) (*user.User, error) {
+			return &user.User{}, nil
+		}},
+		&mockUpdater{func(ctx context.Context, params user.UpdateParams) (*user.User, error) {
+			return &user.User{}, nil
+		}},
+		&mockDeleter{
+			delete: func(ctx context.Context, id string) error {
+				return nil
+			},
+			restore: getUser,
+		},
+		&mockLister{func(ctx context.Context, params user.ListParams) (*user.ListResult, error) {
+			return &user.ListResult{}, nil
+		}},
+		&mockPasswordChanger{func(ctx context.Context, params user.ChangePasswordParams) error {
+			return nil
+		}},
+		&mockLockouts{
+			get: func(ctx context.Context, userID string) (*user.Lockout, error) {
+				return &user.Lockout{UserID: userID}, nil
+			},
+			unlock: func(ctx context.Context, userID string) error {
+				return nil
+			},
+		},
+		query,
+		roles,
+	)
+	addSessionRoutes(router, auth, sessions)
+	addRoleRoutes(router, auth, roles)
+	router.Methods(http.MethodGet).Path("/auth/me").Handler(auth.requireAuthentication(handleMe()))
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.caller != anonymous {
+			r.Header.Set("Authorization", tc.caller)
+		}
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, tc.expStatus, w.Result().StatusCode, "%s %s %s", tc.method, tc.path, tc.body)
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/reset"
+	"github.com/mabaro3009/example-architecture-go/user"
+)
+
+// addPasswordResetRoutes registers the routes of the forgotten password flow,
+// which are open to anybody: the reset token is the credential.
+func addPasswordResetRoutes(router *mux.Router, resetter PasswordResetter) {
+	router.Methods(http.MethodPost).Path("/auth/password-reset/request").HandlerFunc(handlePasswordResetRequest(resetter))
+	router.Methods(http.MethodPost).Path("/auth/password-reset/confirm").HandlerFunc(handlePasswordResetConfirm(resetter))
+}
+
+type PasswordResetter interface {
+	Request(ctx context.Context, username string) error
+	Confirm(ctx context.Context, params user.ConfirmResetParams) error
+}
+
+// logNotifier writes password reset tokens to a log instead of sending them,
+// which is only meant for local runs.
+type logNotifier struct {
+	logger *log.Logger
+}
+
+func newLogNotifier(w io.Writer) *logNotifier {
+	return &logNotifier{logger: log.New(w, "", log.LstdFlags)}
+}
+
+func (n *logNotifier) NotifyPasswordReset(_ context.Context, u *user.User, secret string, expiresAt time.Time) error {
+	n.logger.Printf("password reset token for user %s (%s), valid until %s: %s",
+		u.Username, u.ID, expiresAt.Format(time.RFC3339), secret)
+
+	return nil
+}
+
+// handlePasswordResetRequest always answers 202 unless something fails, so
+// callers cannot tell whether the username exists.
+func handlePasswordResetRequest(resetter PasswordResetter) http.HandlerFunc {
+	type resetRequest struct {
+		Username string `json:"username"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req resetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if req.Username == "" {
+			body := map[string]string{"error": user.ErrInvalidUsername.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if err := resetter.Request(context.Background(), req.Username); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func handlePasswordResetConfirm(resetter PasswordResetter) http.HandlerFunc {
+	type confirmRequest struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req confirmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		params := user.ConfirmResetParams{
+			Token:       req.Token,
+			NewPassword: req.NewPassword,
+		}
+
+		if err := resetter.Confirm(context.Background(), params); err != nil {
+			body := map[string]string{"error": err.Error()}
+			var policyErr *user.PasswordPolicyError
+			switch {
+			case errors.As(err, &policyErr):
+				writePasswordPolicyError(w, err, policyErr)
+			case errors.Is(err, reset.ErrInvalidToken), errors.Is(err, user.ErrDoesNotExist):
+				// The user of the token was deleted since it was issued.
+				body["error"] = reset.ErrInvalidToken.Error()
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			case errors.Is(err, user.ErrPasswordTooSmall), errors.Is(err, user.ErrPasswordReused):
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			case errors.Is(err, user.ErrPasswordChanged):
+				_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/reset"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePasswordResetRequest(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		requestErr  error
+		expStatus   int
+	}{
+		{
+			description: "invalid body",
+			body:        "{",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "missing username",
+			body:        `{}`,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "random err",
+			body:        `{"username":"usr"}`,
+			requestErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			body:        `{"username":"usr"}`,
+			expStatus:   http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/password-reset/request", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			m := &mockPasswordResetter{request: func(ctx context.Context, username string) error {
+				assert.Equal(t, "usr", username)
+				return tc.requestErr
+			}}
+
+			handlePasswordResetRequest(m)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandlePasswordResetConfirm(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{
+		"token":        "secret",
+		"new_password": "new",
+	})
+	testCases := []struct {
+		description string
+		confirmErr  error
+		expStatus   int
+		expError    string
+	}{
+		{
+			description: "weak password",
+			confirmErr:  &user.PasswordPolicyError{Violations: []user.PasswordViolation{{Rule: user.RuleMinLength}}},
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid token",
+			confirmErr:  reset.ErrInvalidToken,
+			expStatus:   http.StatusBadRequest,
+			expError:    reset.ErrInvalidToken.Error(),
+		},
+		{
+			description: "deleted user",
+			confirmErr:  user.ErrDoesNotExist,
+			expStatus:   http.StatusBadRequest,
+			expError:    reset.ErrInvalidToken.Error(),
+		},
+		{
+			description: "reused password",
+			confirmErr:  user.ErrPasswordReused,
+			expStatus:   http.StatusBadRequest,
+			expError:    user.ErrPasswordReused.Error(),
+		},
+		{
+			description: "changed concurrently",
+			confirmErr:  user.ErrPasswordChanged,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "random err",
+			confirmErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/password-reset/confirm", bytes.NewReader(buff))
+			w := httptest.NewRecorder()
+			m := &mockPasswordResetter{confirm: func(ctx context.Context, params user.ConfirmResetParams) error {
+				assert.Equal(t, "secret", params.Token)
+				assert.Equal(t, "new", params.NewPassword)
+				return tc.confirmErr
+			}}
+
+			handlePasswordResetConfirm(m)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expError != "" {
+				var response map[string]string
+				_ = json.NewDecoder(w.Result().Body).Decode(&response)
+				assert.Equal(t, tc.expError, response["error"])
+			}
+		})
+	}
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buff bytes.Buffer
+	n := newLogNotifier(&buff)
+
+	expiresAt := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+	err := n.NotifyPasswordReset(context.Background(), &user.User{ID: "1", Username: "usr"}, "secret", expiresAt)
+	assert.NoError(t, err)
+	assert.Contains(t, buff.String(), "password reset token for user usr (1), valid until 2022-04-01T10:00:00Z: secret")
+}
+
+type mockPasswordResetter struct {
+	request func(ctx context.Context, username string) error
+	confirm func(ctx context.Context, params user.ConfirmResetParams) error
+}
+
+func (m *mockPasswordResetter) Request(ctx context.Context, username string) error {
+	return m.request(ctx, username)
+}
+
+func (m *mockPasswordResetter) Confirm(ctx context.Context, params user.ConfirmResetParams) error {
+	return m.confirm(ctx, params)
+}
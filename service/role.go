@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/role"
+)
+
+func addRoleRoutes(router *mux.Router, auth *authMiddleware, roles Roles) {
+	manage := func(h http.Handler) http.Handler {
+		return auth.requireAuthentication(requirePermission(role.PermRolesManage)(h))
+	}
+
+	router.Methods(http.MethodGet).Path("/permissions").Handler(manage(handlePermissionList()))
+	router.Methods(http.MethodGet).Path("/roles").Handler(manage(handleRoleList(roles)))
+	router.Methods(http.MethodPost).Path("/roles").Handler(manage(handleRoleCreate(roles)))
+	router.Methods(http.MethodGet).Path("/roles/{name}").Handler(manage(handleRoleGet(roles)))
+	router.Methods(http.MethodPatch).Path("/roles/{name}").Handler(manage(handleRoleUpdate(roles)))
+	router.Methods(http.MethodDelete).Path("/roles/{name}").Handler(manage(handleRoleDelete(roles)))
+}
+
+type Roles interface {
+	RoleGetter
+	List(ctx context.Context) ([]*role.Role, error)
+	Create(ctx context.Context, params role.CreateParams) (*role.Role, error)
+	Update(ctx context.Context, params role.UpdateParams) (*role.Role, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type roleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newRoleResponse(r *role.Role) roleResponse {
+	permissions := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		permissions = append(permissions, p.String())
+	}
+
+	return roleResponse{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: permissions,
+		Builtin:     r.Builtin,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+func handlePermissionList() http.HandlerFunc {
+	type permissionResponse struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		catalog := role.Catalog()
+		resp := make([]permissionResponse, 0, len(catalog))
+		for _, info := range catalog {
+			resp = append(resp, permissionResponse{
+				Name:        info.Name.String(),
+				Description: info.Description,
+			})
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+func handleRoleList(roles Roles) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rs, err := roles.List(context.Background())
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		resp := make([]roleResponse, 0, len(rs))
+		for _, rl := range rs {
+			resp = append(resp, newRoleResponse(rl))
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+func handleRoleCreate(roles Roles) http.HandlerFunc {
+	type roleCreateRequest struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req roleCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		params := role.CreateParams{
+			Name:        req.Name,
+			Description: req.Description,
+			Permissions: toPermissions(req.Permissions),
+		}
+
+		p, _ := PrincipalFromContext(r.Context())
+		if missing := missingPermission(p, params.Permissions); missing != "" {
+			writeMissingPermission(w, missing)
+			return
+		}
+
+		rl, err := roles.Create(context.Background(), params)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusCreated, newRoleResponse(rl))
+	}
+}
+
+func handleRoleGet(roles Roles) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := mux.Vars(r)["name"]
+		if !ok {
+			body := map[string]string{"error": "missing name in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		rl, err := roles.Get(context.Background(), name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, newRoleResponse(rl))
+	}
+}
+
+func handleRoleUpdate(roles Roles) http.HandlerFunc {
+	type roleUpdateRequest struct {
+		Description *string  `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := mux.Vars(r)["name"]
+		if !ok {
+			body := map[string]string{"error": "missing name in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		var req roleUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		params := role.UpdateParams{
+			Name:        name,
+			Description: req.Description,
+			Permissions: toPermissions(req.Permissions),
+		}
+
+		p, _ := PrincipalFromContext(r.Context())
+		if missing := missingPermission(p, params.Permissions); missing != "" {
+			writeMissingPermission(w, missing)
+			return
+		}
+
+		rl, err := roles.Update(context.Background(), params)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, newRoleResponse(rl))
+	}
+}
+
+func handleRoleDelete(roles Roles) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := mux.Vars(r)["name"]
+		if !ok {
+			body := map[string]string{"error": "missing name in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if err := roles.Delete(context.Background(), name); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// missingPermission returns a permission of permissions that the caller p does
+// not hold, if any, so that nobody can put more into a role, their own
+// included, than they have. Unknown permissions are left to the role manager to
+// reject.
+func missingPermission(p *Principal, permissions []role.Permission) role.Permission {
+	known := make(map[role.Permission]bool)
+	for _, info := range role.Catalog() {
+		known[info.Name] = true
+	}
+
+	for _, perm := range permissions {
+		if known[perm] && !p.Can(perm) {
+			return perm
+		}
+	}
+
+	return ""
+}
+
+// toPermissions keeps a nil slice nil, so updates can tell a missing
+// permissions field from an empty one.
+func toPermissions(names []string) []role.Permission {
+	if names == nil {
+		return nil
+	}
+
+	permissions := make([]role.Permission, 0, len(names))
+	for _, name := range names {
+		permissions = append(permissions, role.Permission(name))
+	}
+
+	return permissions
+}
+
+func writeRoleError(w http.ResponseWriter, err error) {
+	body := map[string]string{"error": err.Error()}
+	switch {
+	case errors.Is(err, role.ErrInvalidName), errors.Is(err, role.ErrUnknownPermission):
+		_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+	case errors.Is(err, role.ErrDoesNotExist):
+		_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+	case errors.Is(err, role.ErrAlreadyExists), errors.Is(err, role.ErrInUse):
+		_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
+	case errors.Is(err, role.ErrBuiltin):
+		_ = httpx.WriteJSONResponse(w, http.StatusForbidden, body)
+	default:
+		_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+	}
+}
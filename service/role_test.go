@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePermissionList(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/permissions", nil)
+	w := httptest.NewRecorder()
+
+	handlePermissionList()(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []map[string]string
+	_ = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.Len(t, response, len(role.Catalog()))
+	assert.Equal(t, role.PermUsersRead.String(), response[0]["name"])
+}
+
+func TestHandleRoleCreate(t *testing.T) {
+	manager := &Principal{UserID: "5", Role: "manager", Permissions: []role.Permission{role.PermRolesManage, role.PermUsersRead}}
+
+	testCases := []struct {
+		description    string
+		principal      *Principal
+		body           string
+		createErr      error
+		expCreate      bool
+		expStatus      int
+		expPermissions []role.Permission
+	}{
+		{
+			description: "invalid body",
+			body:        "{",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid name",
+			body:        `{"name":"A"}`,
+			createErr:   role.ErrInvalidName,
+			expCreate:   true,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description:    "unknown permission",
+			body:           `{"name":"auditor","permissions":["users:fly"]}`,
+			createErr:      role.ErrUnknownPermission,
+			expCreate:      true,
+			expStatus:      http.StatusBadRequest,
+			expPermissions: []role.Permission{"users:fly"},
+		},
+		{
+			description: "already exists",
+			body:        `{"name":"auditor"}`,
+			createErr:   role.ErrAlreadyExists,
+			expCreate:   true,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "random err",
+			body:        `{"name":"auditor"}`,
+			createErr:   errors.New("random error"),
+			expCreate:   true,
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "permission the caller lacks",
+			principal:   manager,
+			body:        `{"name":"auditor","permissions":["users:read","users:delete"]}`,
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description:    "permissions the caller holds",
+			principal:      manager,
+			body:           `{"name":"auditor","description":"Reads users","permissions":["users:read"]}`,
+			expCreate:      true,
+			expStatus:      http.StatusCreated,
+			expPermissions: []role.Permission{role.PermUsersRead},
+		},
+		{
+			description:    "success",
+			body:           `{"name":"auditor","description":"Reads users","permissions":["users:read"]}`,
+			expCreate:      true,
+			expStatus:      http.StatusCreated,
+			expPermissions: []role.Permission{role.PermUsersRead},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			principal := tc.principal
+			if principal == nil {
+				principal = testPrincipal(t, "1", user.RoleAdmin)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/roles", strings.NewReader(tc.body))
+			r = r.WithContext(withPrincipal(r.Context(), principal))
+			w := httptest.NewRecorder()
+			created := false
+			roles := &mockRoles{create: func(ctx context.Context, params role.CreateParams) (*role.Role, error) {
+				created = true
+				assert.Equal(t, tc.expPermissions, params.Permissions)
+				if tc.createErr != nil {
+					return nil, tc.createErr
+				}
+
+				return &role.Role{Name: params.Name, Description: params.Description, Permissions: params.Permissions}, nil
+			}}
+
+			handleRoleCreate(roles)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			assert.Equal(t, tc.expCreate, created)
+			if tc.expStatus != http.StatusCreated {
+				return
+			}
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			assert.Equal(t, "auditor", response["name"])
+			assert.Equal(t, "Reads users", response["description"])
+			assert.Equal(t, []interface{}{"users:read"}, response["permissions"])
+			assert.Equal(t, false, response["builtin"])
+		})
+	}
+}
+
+func TestHandleRoleGet(t *testing.T) {
+	testCases := []struct {
+		description string
+		getErr      error
+		expStatus   int
+	}{
+		{
+			description: "does not exist",
+			getErr:      role.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "random err",
+			getErr:      errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/roles/user", nil)
+			r = mux.SetURLVars(r, map[string]string{"name": "user"})
+			w := httptest.NewRecorder()
+			roles := &mockRoles{get: func(ctx context.Context, name string) (*role.Role, error) {
+				assert.Equal(t, "user", name)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &role.Role{Name: name, Permissions: []role.Permission{}, Builtin: true}, nil
+			}}
+
+			handleRoleGet(roles)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			assert.Equal(t, []interface{}{}, response["permissions"])
+			assert.Equal(t, true, response["builtin"])
+		})
+	}
+}
+
+func TestHandleRoleUpdate(t *testing.T) {
+	manager := &Principal{UserID: "5", Role: "manager", Permissions: []role.Permission{role.PermRolesManage, role.PermUsersRead}}
+
+	testCases := []struct {
+		description    string
+		principal      *Principal
+		body           string
+		updateErr      error
+		expUpdate      bool
+		expStatus      int
+		expPermissions []role.Permission
+	}{
+		{
+			description: "invalid body",
+			body:        "{",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "builtin",
+			body:        `{"description":"new"}`,
+			updateErr:   role.ErrBuiltin,
+			expUpdate:   true,
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "does not exist",
+			body:        `{"description":"new"}`,
+			updateErr:   role.ErrDoesNotExist,
+			expUpdate:   true,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "permissions unchanged",
+			body:        `{"description":"new"}`,
+			expUpdate:   true,
+			expStatus:   http.StatusOK,
+		},
+		{
+			description:    "permissions cleared",
+			body:           `{"permissions":[]}`,
+			expUpdate:      true,
+			expStatus:      http.StatusOK,
+			expPermissions: []role.Permission{},
+		},
+		{
+			description: "self escalation with roles:manage only",
+			principal:   manager,
+			body:        `{"permissions":["roles:manage","users:read","users:write","users:delete","users:unlock","roles:assign","sessions:manage"]}`,
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description:    "permissions the caller holds",
+			principal:      manager,
+			body:           `{"permissions":["users:read"]}`,
+			expUpdate:      true,
+			expStatus:      http.StatusOK,
+			expPermissions: []role.Permission{role.PermUsersRead},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			principal := tc.principal
+			if principal == nil {
+				principal = testPrincipal(t, "1", user.RoleAdmin)
+			}
+
+			r := httptest.NewRequest(http.MethodPatch, "/roles/auditor", strings.NewReader(tc.body))
+			r = mux.SetURLVars(r, map[string]string{"name": "auditor"})
+			r = r.WithContext(withPrincipal(r.Context(), principal))
+			w := httptest.NewRecorder()
+			updated := false
+			roles := &mockRoles{update: func(ctx context.Context, params role.UpdateParams) (*role.Role, error) {
+				updated = true
+				assert.Equal(t, "auditor", params.Name)
+				assert.Equal(t, tc.expPermissions, params.Permissions)
+				if tc.updateErr != nil {
+					return nil, tc.updateErr
+				}
+
+				return &role.Role{Name: params.Name}, nil
+			}}
+
+			handleRoleUpdate(roles)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			assert.Equal(t, tc.expUpdate, updated)
+		})
+	}
+}
+
+func TestHandleRoleDelete(t *testing.T) {
+	testCases := []struct {
+		description string
+		deleteErr   error
+		expStatus   int
+	}{
+		{
+			description: "builtin",
+			deleteErr:   role.ErrBuiltin,
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "in use",
+			deleteErr:   role.ErrInUse,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "does not exist",
+			deleteErr:   role.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "success",
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/roles/auditor", nil)
+			r = mux.SetURLVars(r, map[string]string{"name": "auditor"})
+			w := httptest.NewRecorder()
+			roles := &mockRoles{delete: func(ctx context.Context, name string) error {
+				assert.Equal(t, "auditor", name)
+
+				return tc.deleteErr
+			}}
+
+			handleRoleDelete(roles)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+type mockRoles struct {
+	get    func(ctx context.Context, name string) (*role.Role, error)
+	list   func(ctx context.Context) ([]*role.Role, error)
+	create func(ctx context.Context, params role.CreateParams) (*role.Role, error)
+	update func(ctx context.Context, params role.UpdateParams) (*role.Role, error)
+	delete func(ctx context.Context, name string) error
+}
+
+func (m *mockRoles) Get(ctx context.Context, name string) (*role.Role, error) {
+	return m.get(ctx, name)
+}
+
+func (m *mockRoles) List(ctx context.Context) ([]*role.Role, error) {
+	return m.list(ctx)
+}
+
+func (m *mockRoles) Create(ctx context.Context, params role.CreateParams) (*role.Role, error) {
+	return m.create(ctx, params)
+}
+
+func (m *mockRoles) Update(ctx context.Context, params role.UpdateParams) (*role.Role, error) {
+	return m.update(ctx, params)
+}
+
+func (m *mockRoles) Delete(ctx context.Context, name string) error {
+	return m.delete(ctx, name)
+}
@@ -1,52 +1,315 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/mabaro3009/example-architecture-go/infra/memory"
+	"github.com/mabaro3009/example-architecture-go/pkg/breach"
 	"github.com/mabaro3009/example-architecture-go/pkg/hash"
 	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/refresh"
+	"github.com/mabaro3009/example-architecture-go/reset"
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/session"
 	"github.com/mabaro3009/example-architecture-go/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	srv *http.Server
+	srv     *http.Server
+	closers []func() error
 }
 
-func NewService(conf *Config) (*Service, error) {
-	dbs := &memoryDBs{
-		user: memory.NewUserDB(),
+func NewService(conf *Config) (s *Service, err error) {
+	dbs, closers, err := newDBs(conf)
+	if err != nil {
+		return nil, err
 	}
+	// The databases are only handed over to the service once it is built.
+	defer func() {
+		if err != nil {
+			closeAll(closers)
+		}
+	}()
 	q := &queries{
-		user: dbs.user,
+		user:    dbs.user,
+		session: dbs.session,
+		refresh: dbs.refresh,
+		role:    dbs.role,
+		reset:   dbs.reset,
+		lockout: dbs.lockout,
+
+		passwordHistory: dbs.passwordHistory,
 	}
 	cmd := &commands{
-		user: dbs.user,
+		user:    dbs.user,
+		session: dbs.session,
+		refresh: dbs.refresh,
+		role:    dbs.role,
+		reset:   dbs.reset,
+		lockout: dbs.lockout,
+
+		passwordHistory: dbs.passwordHistory,
+	}
+	tokens, err := newJWT(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := newHasher(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	validator, closeValidator, err := newPasswordValidator(conf)
+	if err != nil {
+		return nil, err
+	}
+	closers = append(closers, closeValidator)
+
+	userLister := user.NewLister(q.user)
+	roleManager := role.NewManager(q.role, cmd.role)
+	sessionManager := session.NewManager(conf.SessionTTL, q.session, cmd.session)
+	refreshManager := refresh.NewManager(conf.RefreshTokenTTL, q.refresh, cmd.refresh)
+	history := user.NewPasswordHistory(conf.PasswordHistorySize, hasher, q.passwordHistory, cmd.passwordHistory)
+	revoker := &credentialRevoker{sessions: sessionManager, refreshTokens: refreshManager}
+	resetTokens := reset.NewManager(conf.PasswordResetTokenTTL, q.reset, cmd.reset)
+	lockouts := user.NewLockouts(user.LockoutPolicy{
+		Threshold:   conf.LockoutThreshold,
+		Duration:    conf.LockoutDuration,
+		MaxDuration: conf.LockoutMaxDuration,
+	}, q.lockout, cmd.lockout)
+	var resetter PasswordResetter
+	if notifier := newResetNotifier(conf); notifier != nil {
+		resetter = user.NewPasswordResetter(resetTokens, notifier, validator, hasher, history, revoker, lockouts, q.user, cmd.user)
 	}
 	svc := &services{
-		userCreator: user.NewCreator(user.NewSimplePasswordValidator(user.DefaultMinLen), hash.NewBCrypt(bcrypt.DefaultCost), q.user, cmd.user),
+		userCreator:          user.NewCreator(validator, hasher, roleManager, cmd.user),
+		userUpdater:          user.NewUpdater(q.user, roleManager, cmd.user),
+		userDeleter:          user.NewDeleter(q.user, cmd.user),
+		userLister:           userLister,
+		userAuthenticator:    user.NewAuthenticator(hasher, hasher, lockouts, q.user, cmd.user),
+		userPasswordChanger:  user.NewPasswordChanger(validator, hasher, hasher, history, revoker, lockouts, q.user, cmd.user),
+		userPasswordResetter: resetter,
+		userLockouts:         lockouts,
+		sessionManager:       sessionManager,
+		refreshManager:       refreshManager,
+		roleManager:          roleManager,
 	}
 
+	if err = bootstrapAdmin(context.Background(), conf, svc.userCreator, q.user); err != nil {
+		return nil, fmt.Errorf("could not create bootstrap admin: %w", err)
+	}
+
+	cookie := &sessionCookie{
+		name:   conf.SessionCookieName,
+		secure: conf.SessionCookieSecure,
+	}
+	auth := newAuthMiddleware(tokens, svc.sessionManager, q.user, svc.roleManager, cookie)
+
 	router := mux.NewRouter()
 
 	router.Methods(http.MethodGet).Path("/ping").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_ = httpx.WriteJSONResponse(w, http.StatusOK, "pong")
 	})
 
-	addUserRoutes(router, svc.userCreator, q.user)
+	addUserRoutes(router, auth, svc.userCreator, svc.userUpdater, svc.userDeleter, svc.userLister, svc.userPasswordChanger, svc.userLockouts, q.user, svc.roleManager)
+	addAuthRoutes(router, auth, svc.userAuthenticator, svc.sessionManager, svc.refreshManager, q.user, tokens, cookie)
+	if svc.userPasswordResetter != nil {
+		addPasswordResetRoutes(router, svc.userPasswordResetter)
+	}
+	addSessionRoutes(router, auth, svc.sessionManager)
+	addRoleRoutes(router, auth, svc.roleManager)
 
 	srv := &http.Server{
 		Handler: router,
 		Addr:    conf.ListenAddress,
 	}
 
-	return &Service{srv: srv}, nil
+	// The sweeps stop before the databases they use are closed.
+	closers = append([]func() error{startSweeping(conf.SweepInterval, sessionManager, refreshManager)}, closers...)
+
+	return &Service{srv: srv, closers: closers}, nil
+}
+
+// newResetNotifier returns what sends password reset tokens, or nil when they
+// cannot be sent. The only notifier writes them to the log, so anywhere but in
+// local runs it has to be enabled explicitly.
+func newResetNotifier(conf *Config) user.ResetNotifier {
+	if conf.Environment != EnvironmentLocalDev && !conf.PasswordResetLogTokens {
+		return nil
+	}
+
+	return newLogNotifier(os.Stderr)
+}
+
+// errBootstrapAdminTaken is returned when the username of the bootstrap admin
+// belongs to a user that is not an admin, or that is deleted.
+var errBootstrapAdminTaken = errors.New("username is taken by a user that is not an admin")
+
+// bootstrapAdmin creates the configured admin unless an admin with its username
+// already exists. It fails when somebody else has the username, so that the
+// configured admin is never silently missing.
+func bootstrapAdmin(ctx context.Context, conf *Config, creator Creator, users user.GetByUsername) error {
+	if conf.BootstrapAdminUsername == "" {
+		return nil
+	}
+
+	_, err := creator.Create(ctx, user.CreateParams{
+		Username: conf.BootstrapAdminUsername,
+		Password: conf.BootstrapAdminPassword,
+		Role:     user.RoleAdmin,
+	})
+	if !errors.Is(err, user.ErrUsernameAlreadyExists) {
+		return err
+	}
+
+	u, err := users.GetByUsername(ctx, conf.BootstrapAdminUsername)
+	if errors.Is(err, user.ErrDoesNotExist) {
+		return fmt.Errorf("%w: %q is deleted", errBootstrapAdminTaken, conf.BootstrapAdminUsername)
+	}
+	if err != nil {
+		return err
+	}
+	if u.Role != user.RoleAdmin {
+		return fmt.Errorf("%w: %q has the role %q", errBootstrapAdminTaken, u.Username, u.Role)
+	}
+
+	return nil
+}
+
+// newPasswordValidator returns a validator of the configured password policy
+// that also rejects the configured breached passwords, and a function that
+// closes their list.
+func newPasswordValidator(conf *Config) (user.PasswordValidator, func() error, error) {
+	policy, err := user.NewPolicyPasswordValidator(passwordPolicy(conf))
+	if err != nil {
+		return nil, nil, err
+	}
+	if conf.BreachedPasswordsPath == "" {
+		return policy, func() error { return nil }, nil
+	}
+
+	breached, err := breach.Open(conf.BreachedPasswordsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open breached passwords: %w", err)
+	}
+
+	return user.NewChainPasswordValidator(policy, user.NewBreachedPasswordValidator(breached)), breached.Close, nil
+}
+
+// passwordPolicy returns the configured password policy. bcrypt ignores what
+// comes after the first 72 bytes, so unless a pepper hashes passwords to a
+// fixed length first, longer ones are rejected.
+func passwordPolicy(conf *Config) user.PasswordPolicy {
+	maxBytes := 0
+	if conf.PasswordHashAlgorithm == hash.AlgorithmBCrypt && len(conf.PasswordPepperKeys)+len(conf.PasswordPepperKeyFiles) == 0 {
+		maxBytes = hash.BCryptMaxPasswordBytes
+	}
+
+	return user.PasswordPolicy{
+		MinLength:        conf.PasswordMinLength,
+		MaxLength:        conf.PasswordMaxLength,
+		MaxBytes:         maxBytes,
+		RequireLower:     conf.PasswordRequireLower,
+		RequireUpper:     conf.PasswordRequireUpper,
+		RequireDigit:     conf.PasswordRequireDigit,
+		RequireSymbol:    conf.PasswordRequireSymbol,
+		DisallowUsername: conf.PasswordDisallowUsername,
+		MaxRepeated:      conf.PasswordMaxRepeated,
+		MaxSequential:    conf.PasswordMaxSequential,
+		MinEntropy:       conf.PasswordMinEntropy,
+	}
+}
+
+// newHasher returns a hasher of the configured algorithm, parameters and
+// pepper that also verifies passwords hashed with any other, so they can be
+// rehashed.
+func newHasher(conf *Config) (hash.Hasher, error) {
+	argon2idParams := hash.DefaultArgon2idParams
+	argon2idParams.Memory = conf.Argon2idMemory
+	argon2idParams.Iterations = conf.Argon2idIterations
+	argon2idParams.Parallelism = conf.Argon2idParallelism
+
+	scryptParams := hash.DefaultScryptParams
+	scryptParams.N = conf.ScryptN
+	scryptParams.R = conf.ScryptR
+	scryptParams.P = conf.ScryptP
+
+	current, err := hash.New(conf.PasswordHashAlgorithm, hash.Params{
+		BCryptCost: conf.BCryptCost,
+		Argon2id:   argon2idParams,
+		Scrypt:     scryptParams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := loadPepperKeys(conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return hash.NewMultiHasher(current), nil
+	}
+
+	return hash.NewPepper(hash.NewMultiHasher(current), conf.PasswordPepperKeyID, keys)
+}
+
+func loadPepperKeys(conf *Config) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(conf.PasswordPepperKeys)+len(conf.PasswordPepperKeyFiles))
+	for id, key := range conf.PasswordPepperKeys {
+		keys[id] = []byte(key)
+	}
+
+	for id, path := range conf.PasswordPepperKeyFiles {
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("pepper key %q is configured twice", id)
+		}
+
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read pepper key %q: %w", id, err)
+		}
+		keys[id] = bytes.TrimRight(key, "\r\n")
+	}
+
+	return keys, nil
+}
+
+func newJWT(conf *Config) (*token.JWT, error) {
+	opts := token.Options{
+		Issuer:   conf.TokenIssuer,
+		Audience: conf.TokenAudience,
+		TTL:      conf.TokenTTL,
+	}
+
+	if conf.TokenAlgorithm != token.AlgorithmHS256 {
+		keyPEM, err := os.ReadFile(conf.TokenPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read token private key: %w", err)
+		}
+
+		return token.New(conf.TokenAlgorithm, nil, keyPEM, opts)
+	}
+
+	secret := []byte(conf.TokenSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "no token secret configured: using a random one, tokens will not survive restarts")
+	}
+
+	return token.NewHS256(secret, opts)
 }
 
 func (s *Service) ListenAndServe() {
@@ -61,20 +324,97 @@ func (s *Service) Shutdown() {
 	if err := s.srv.Shutdown(canCtx); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 	}
+
+	closeAll(s.closers)
+}
+
+// closeAll calls every closer, logging the errors.
+func closeAll(closers []func() error) {
+	for _, closeFn := range closers {
+		if err := closeFn(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+type userDB interface {
+	user.Queries
+	user.Commands
+}
+
+type sessionDB interface {
+	session.Queries
+	session.Commands
+}
+
+type refreshDB interface {
+	refresh.Queries
+	refresh.Commands
 }
 
-type memoryDBs struct {
-	user *memory.UserDB
+type roleDB interface {
+	role.Queries
+	role.Commands
+}
+
+type resetDB interface {
+	reset.Queries
+	reset.Commands
+}
+
+type lockoutDB interface {
+	user.LockoutQueries
+	user.LockoutCommands
+}
+
+type passwordHistoryDB interface {
+	user.PasswordHistoryQueries
+	user.PasswordHistoryCommands
+}
+
+type dbs struct {
+	user    userDB
+	session sessionDB
+	refresh refreshDB
+	role    roleDB
+	reset   resetDB
+	lockout lockoutDB
+
+	passwordHistory passwordHistoryDB
 }
 
 type queries struct {
-	user user.Queries
+	user    user.Queries
+	session session.Queries
+	refresh refresh.Queries
+	role    role.Queries
+	reset   reset.Queries
+	lockout user.LockoutQueries
+
+	passwordHistory user.PasswordHistoryQueries
 }
 
 type commands struct {
-	user user.Commands
+	user    user.Commands
+	session session.Commands
+	refresh refresh.Commands
+	role    role.Commands
+	reset   reset.Commands
+	lockout user.LockoutCommands
+
+	passwordHistory user.PasswordHistoryCommands
 }
 
 type services struct {
-	userCreator Creator
+	userCreator          Creator
+	userUpdater          Updater
+	userDeleter          Deleter
+	userLister           Lister
+	userAuthenticator    Authenticator
+	userPasswordChanger  PasswordChanger
+	userPasswordResetter PasswordResetter
+	userLockouts         Lockouts
+	sessionManager       Sessions
+	refreshManager       RefreshTokens
+	roleManager          Roles
 }
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/mabaro3009/example-architecture-go/data"
+	"github.com/mabaro3009/example-architecture-go/pkg/hash"
+	"github.com/mabaro3009/example-architecture-go/pkg/token"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBootstrapAdmin(t *testing.T) {
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description string
+		username    string
+		creatorErr  error
+		existing    *user.User
+		getErr      error
+		expCreated  bool
+		expError    error
+	}{
+		{
+			description: "not configured",
+		},
+		{
+			description: "already exists",
+			username:    "root",
+			creatorErr:  user.ErrUsernameAlreadyExists,
+			existing:    &user.User{Username: "root", Role: user.RoleAdmin},
+			expCreated:  true,
+		},
+		{
+			description: "taken by a user",
+			username:    "root",
+			creatorErr:  user.ErrUsernameAlreadyExists,
+			existing:    &user.User{Username: "root", Role: user.RoleUser},
+			expCreated:  true,
+			expError:    errBootstrapAdminTaken,
+		},
+		{
+			description: "taken by a deleted user",
+			username:    "root",
+			creatorErr:  user.ErrUsernameAlreadyExists,
+			getErr:      user.ErrDoesNotExist,
+			expCreated:  true,
+			expError:    errBootstrapAdminTaken,
+		},
+		{
+			description: "get error",
+			username:    "root",
+			creatorErr:  user.ErrUsernameAlreadyExists,
+			getErr:      randomErr,
+			expCreated:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "random error",
+			username:    "root",
+			creatorErr:  randomErr,
+			expCreated:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "created",
+			username:    "root",
+			expCreated:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			conf := &Config{
+				BootstrapAdminUsername: tc.username,
+				BootstrapAdminPassword: "password",
+			}
+			created := false
+			m := &mockCreator{func(ctx context.Context, params user.CreateParams) (*user.User, error) {
+				assert.Equal(t, "root", params.Username)
+				assert.Equal(t, "password", params.Password)
+				assert.Equal(t, user.RoleAdmin, params.Role)
+				created = true
+
+				return &user.User{}, tc.creatorErr
+			}}
+
+			users := &mockUsernameGetter{func(ctx context.Context, username string) (*user.User, error) {
+				assert.Equal(t, "root", username)
+
+				return tc.existing, tc.getErr
+			}}
+
+			err := bootstrapAdmin(context.Background(), conf, m, users)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expCreated, created)
+		})
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "pepper")
+	assert.NoError(t, os.WriteFile(keyFile, append(bytes.Repeat([]byte("f"), 32), '\n'), 0o600))
+	key := strings.Repeat("k", 32)
+
+	testCases := []struct {
+		description string
+		keyID       string
+		keys        map[string]string
+		keyFiles    map[string]string
+		expErr      bool
+		expPrefix   string
+	}{
+		{
+			description: "no pepper",
+			expPrefix:   "$2a$04$",
+		},
+		{
+			description: "pepper",
+			keyID:       "k1",
+			keys:        map[string]string{"k1": key},
+			expPrefix:   "$pepper$k=k1$2a$04$",
+		},
+		{
+			description: "pepper from file",
+			keyID:       "f1",
+			keys:        map[string]string{"k1": key},
+			keyFiles:    map[string]string{"f1": keyFile},
+			expPrefix:   "$pepper$k=f1$2a$04$",
+		},
+		{
+			description: "missing file",
+			keyID:       "f1",
+			keyFiles:    map[string]string{"f1": filepath.Join(dir, "missing")},
+			expErr:      true,
+		},
+		{
+			description: "key configured twice",
+			keyID:       "k1",
+			keys:        map[string]string{"k1": key},
+			keyFiles:    map[string]string{"k1": keyFile},
+			expErr:      true,
+		},
+		{
+			description: "unknown current key",
+			keyID:       "k2",
+			keys:        map[string]string{"k1": key},
+			expErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			conf := &Config{
+				PasswordHashAlgorithm:  hash.AlgorithmBCrypt,
+				BCryptCost:             bcrypt.MinCost,
+				PasswordPepperKeyID:    tc.keyID,
+				PasswordPepperKeys:     tc.keys,
+				PasswordPepperKeyFiles: tc.keyFiles,
+			}
+
+			h, err := newHasher(conf)
+			if tc.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			hashed, err := h.Hash("password")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(hashed), tc.expPrefix), string(hashed))
+			assert.NoError(t, h.Verify(hashed, "password"))
+		})
+	}
+}
+
+func TestNewPasswordValidator_BCryptLimit(t *testing.T) {
+	key := strings.Repeat("k", 32)
+	long := strings.Repeat("correct horse ", 6)
+
+	testCases := []struct {
+		description string
+		algorithm   string
+		keys        map[string]string
+		expError    error
+	}{
+		{
+			description: "bcrypt",
+			algorithm:   hash.AlgorithmBCrypt,
+			expError:    user.ErrWeakPassword,
+		},
+		{
+			description: "bcrypt with pepper",
+			algorithm:   hash.AlgorithmBCrypt,
+			keys:        map[string]string{"k1": key},
+		},
+		{
+			description: "argon2id",
+			algorithm:   hash.AlgorithmArgon2id,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			conf := &Config{
+				PasswordHashAlgorithm: tc.algorithm,
+				PasswordMaxLength:     128,
+				PasswordPepperKeys:    tc.keys,
+			}
+
+			validator, closeValidator, err := newPasswordValidator(conf)
+			assert.NoError(t, err)
+			defer closeValidator()
+
+			err = validator.Validate("alice", long)
+			assert.ErrorIs(t, err, tc.expError)
+		})
+	}
+}
+
+func TestNewService_ClosesDatabasesOnError(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("open files cannot be listed on this system")
+	}
+
+	dir := t.TempDir()
+	conf := &Config{
+		DatabaseType:        data.TypeFile,
+		DatabaseDir:         dir,
+		TokenAlgorithm:      token.AlgorithmRS256,
+		TokenPrivateKeyPath: filepath.Join(dir, "missing.pem"),
+	}
+
+	_, err := NewService(conf)
+	assert.Error(t, err)
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	assert.NoError(t, err)
+	for _, fd := range fds {
+		target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		assert.False(t, strings.HasPrefix(target, dir), "%s is still open", target)
+	}
+}
+
+func TestNewService_PasswordResetRoutes(t *testing.T) {
+	testCases := []struct {
+		description string
+		environment string
+		logTokens   bool
+		expStatus   int
+	}{
+		{
+			description: "local",
+			environment: EnvironmentLocalDev,
+			expStatus:   http.StatusAccepted,
+		},
+		{
+			description: "no notifier",
+			environment: "production",
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "tokens logged explicitly",
+			environment: "production",
+			logTokens:   true,
+			expStatus:   http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var conf Config
+			assert.NoError(t, envconfig.Process("example_test", &conf))
+			conf.Environment = tc.environment
+			conf.PasswordResetLogTokens = tc.logTokens
+			conf.TokenSecret = string(testSecret)
+
+			s, err := NewService(&conf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer s.Shutdown()
+
+			r := httptest.NewRequest(http.MethodPost, "/auth/password-reset/request", strings.NewReader(`{"username": "usr"}`))
+			w := httptest.NewRecorder()
+			s.srv.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+type mockUsernameGetter struct {
+	getByUsername func(ctx context.Context, username string) (*user.User, error)
+}
+
+func (m *mockUsernameGetter) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	return m.getByUsername(ctx, username)
+}
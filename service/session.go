@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/role"
+	"github.com/mabaro3009/example-architecture-go/session"
+)
+
+func addSessionRoutes(router *mux.Router, auth *authMiddleware, sessions Sessions) {
+	selfOr := func(h http.Handler) http.Handler {
+		return auth.requireAuthentication(requireSelfOr(role.PermSessionsManage)(h))
+	}
+
+	router.Methods(http.MethodGet).Path("/users/{id}/sessions").Handler(selfOr(handleSessionList(sessions)))
+	router.Methods(http.MethodDelete).Path("/users/{id}/sessions").Handler(selfOr(handleSessionRevokeAll(sessions)))
+	router.Methods(http.MethodDelete).Path("/users/{id}/sessions/{session_id}").Handler(selfOr(handleSessionRevoke(sessions)))
+}
+
+type SessionLister interface {
+	List(ctx context.Context, userID string) ([]*session.Session, error)
+}
+
+type SessionRevoker interface {
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeAll(ctx context.Context, userID string) error
+}
+
+type Sessions interface {
+	SessionCreator
+	SessionAuthenticator
+	SessionLister
+	SessionRevoker
+	SessionTokenRevoker
+}
+
+// sessionCookie sets and reads the cookie that carries the session token of
+// browser clients. The cookie is HttpOnly so scripts cannot read it, and
+// SameSite=Lax so it is not sent with cross-site state changing requests.
+type sessionCookie struct {
+	name   string
+	secure bool
+}
+
+func (c *sessionCookie) set(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.name,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (c *sessionCookie) clear(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (c *sessionCookie) token(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(c.name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+}
+
+func handleSessionList(sessions SessionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+		p, _ := PrincipalFromContext(r.Context())
+
+		ss, err := sessions.List(context.Background(), id)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		resp := make([]sessionResponse, 0, len(ss))
+		for _, s := range ss {
+			resp = append(resp, sessionResponse{
+				ID:         s.ID,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				ExpiresAt:  s.ExpiresAt,
+				UserAgent:  s.UserAgent,
+				IP:         s.IP,
+				Current:    p != nil && s.ID == p.SessionID,
+			})
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+func handleSessionRevoke(sessions SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		sessionID, ok := mux.Vars(r)["session_id"]
+		if !ok {
+			body := map[string]string{"error": "missing session id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if err := sessions.Revoke(context.Background(), id, sessionID); err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, session.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleSessionRevokeAll(sessions SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if err := sessions.RevokeAll(context.Background(), id); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// clientIP returns the address of the peer of the request. Forwarding headers
+// are ignored because they can be set by any client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/session"
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCookie = &sessionCookie{name: "session", secure: true}
+
+func TestHandleSessionList(t *testing.T) {
+	testCases := []struct {
+		description string
+		principal   *Principal
+		listErr     error
+		expStatus   int
+	}{
+		{
+			description: "random err",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			listErr:     errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "admin",
+			principal:   testPrincipal(t, "2", user.RoleAdmin),
+			expStatus:   http.StatusOK,
+		},
+		{
+			description: "success",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser, SessionID: "s1"},
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users/1/sessions", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+			if tc.principal != nil {
+				r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+			}
+			w := httptest.NewRecorder()
+			s := &mockSessions{list: func(ctx context.Context, userID string) ([]*session.Session, error) {
+				assert.Equal(t, "1", userID)
+
+				return []*session.Session{
+					{ID: "s1", UserID: "1", ExpiresAt: time.Now().Add(time.Hour), UserAgent: "agent", IP: "10.0.0.1"},
+					{ID: "s2", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+				}, tc.listErr
+			}}
+
+			handleSessionList(s)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response []map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			if assert.Len(t, response, 2) {
+				assert.Equal(t, "s1", response[0]["id"])
+				assert.Equal(t, "agent", response[0]["user_agent"])
+				assert.Equal(t, "10.0.0.1", response[0]["ip"])
+				assert.Equal(t, tc.principal.SessionID == "s1", response[0]["current"])
+				assert.Equal(t, false, response[1]["current"])
+				_, ok := response[0]["token_hash"]
+				assert.False(t, ok)
+			}
+		})
+	}
+}
+
+func TestHandleSessionRevoke(t *testing.T) {
+	testCases := []struct {
+		description string
+		principal   *Principal
+		revokeErr   error
+		expStatus   int
+	}{
+		{
+			description: "does not exist",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			revokeErr:   session.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "random err",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			revokeErr:   errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "admin",
+			principal:   testPrincipal(t, "2", user.RoleAdmin),
+			expStatus:   http.StatusNoContent,
+		},
+		{
+			description: "success",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/users/1/sessions/s1", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "1", "session_id": "s1"})
+			if tc.principal != nil {
+				r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+			}
+			w := httptest.NewRecorder()
+			s := &mockSessions{revoke: func(ctx context.Context, userID, id string) error {
+				assert.Equal(t, "1", userID)
+				assert.Equal(t, "s1", id)
+
+				return tc.revokeErr
+			}}
+
+			handleSessionRevoke(s)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandleSessionRevokeAll(t *testing.T) {
+	testCases := []struct {
+		description string
+		principal   *Principal
+		revokeErr   error
+		expStatus   int
+	}{
+		{
+			description: "random err",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			revokeErr:   errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/users/1/sessions", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+			if tc.principal != nil {
+				r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+			}
+			w := httptest.NewRecorder()
+			s := &mockSessions{revokeAll: func(ctx context.Context, userID string) error {
+				assert.Equal(t, "1", userID)
+
+				return tc.revokeErr
+			}}
+
+			handleSessionRevokeAll(s)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+type mockSessions struct {
+	create       func(ctx context.Context, params session.CreateParams) (*session.Session, string, error)
+	authenticate func(ctx context.Context, token string) (*session.Session, error)
+	list         func(ctx context.Context, userID string) ([]*session.Session, error)
+	revoke       func(ctx context.Context, userID, id string) error
+	revokeAll    func(ctx context.Context, userID string) error
+	revokeOthers func(ctx context.Context, userID, keepID string) error
+	revokeToken  func(ctx context.Context, token string) error
+}
+
+func (m *mockSessions) Create(ctx context.Context, params session.CreateParams) (*session.Session, string, error) {
+	return m.create(ctx, params)
+}
+
+func (m *mockSessions) Authenticate(ctx context.Context, token string) (*session.Session, error) {
+	return m.authenticate(ctx, token)
+}
+
+func (m *mockSessions) List(ctx context.Context, userID string) ([]*session.Session, error) {
+	return m.list(ctx, userID)
+}
+
+func (m *mockSessions) Revoke(ctx context.Context, userID, id string) error {
+	return m.revoke(ctx, userID, id)
+}
+
+func (m *mockSessions) RevokeAll(ctx context.Context, userID string) error {
+	return m.revokeAll(ctx, userID)
+}
+
+func (m *mockSessions) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	return m.revokeOthers(ctx, userID, keepID)
+}
+
+func (m *mockSessions) RevokeToken(ctx context.Context, token string) error {
+	return m.revokeToken(ctx, token)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sweeper deletes what can no longer be used from a store.
+type Sweeper interface {
+	Sweep(ctx context.Context) error
+}
+
+// startSweeping runs every sweeper each interval until the returned function
+// is called, which waits for a running sweep to finish. Errors are logged and
+// the sweep is retried on the next tick. A zero interval disables sweeping.
+func startSweeping(interval time.Duration, sweepers ...Sweeper) func() error {
+	if interval <= 0 {
+		return func() error { return nil }
+	}
+
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for _, s := range sweepers {
+					if err := s.Sweep(context.Background()); err != nil {
+						_, _ = fmt.Fprintln(os.Stderr, "sweep failed:", err)
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() error {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+
+		return nil
+	}
+}
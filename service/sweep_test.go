@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartSweeping(t *testing.T) {
+	var sweeps int32
+	s := &mockSweeper{sweep: func(ctx context.Context) error {
+		atomic.AddInt32(&sweeps, 1)
+		return nil
+	}}
+
+	stop := startSweeping(time.Millisecond, s)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&sweeps) >= 2
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, stop())
+
+	stopped := atomic.LoadInt32(&sweeps)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&sweeps))
+	assert.NoError(t, stop())
+}
+
+type mockSweeper struct {
+	sweep func(ctx context.Context) error
+}
+
+func (m *mockSweeper) Sweep(ctx context.Context) error {
+	return m.sweep(ctx)
+}
@@ -3,24 +3,151 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mabaro3009/example-architecture-go/pkg/httpx"
+	"github.com/mabaro3009/example-architecture-go/role"
 	"github.com/mabaro3009/example-architecture-go/user"
 )
 
-func addUserRoutes(router *mux.Router, creator Creator, query user.Queries) {
-	router.Methods(http.MethodPost).Path("/users").HandlerFunc(handleUserCreate(creator))
-	router.Methods(http.MethodGet).Path("/users/{id}").HandlerFunc(handleUserGet(query))
+// addUserRoutes registers the user routes. Anybody can sign up with the
+// default role, and users can read, update and delete themselves. Everything
+// else needs a permission, and roles can only be given by callers holding
+// every permission of the role.
+func addUserRoutes(router *mux.Router, auth *authMiddleware, creator Creator, updater Updater, deleter Deleter, lister Lister, changer PasswordChanger, lockouts Lockouts, query UserGetter, roles RoleGetter) {
+	can := func(perm role.Permission, h http.Handler) http.Handler {
+		return auth.requireAuthentication(requirePermission(perm)(h))
+	}
+	selfOr := func(perm role.Permission, h http.Handler) http.Handler {
+		return auth.requireAuthentication(requireSelfOr(perm)(h))
+	}
+
+	router.Methods(http.MethodPost).Path("/users").Handler(auth.allowAnonymous(handleUserCreate(creator, roles)))
+	router.Methods(http.MethodGet).Path("/users").Handler(can(role.PermUsersRead, handleUserList(lister)))
+	router.Methods(http.MethodGet).Path("/users/{id}").Handler(selfOr(role.PermUsersRead, handleUserGet(query, lockouts)))
+	router.Methods(http.MethodPatch).Path("/users/{id}").Handler(selfOr(role.PermUsersWrite, handleUserUpdate(updater, roles)))
+	router.Methods(http.MethodDelete).Path("/users/{id}").Handler(selfOr(role.PermUsersDelete, handleUserDelete(deleter)))
+	router.Methods(http.MethodPost).Path("/users/{id}/restore").Handler(can(role.PermUsersDelete, handleUserRestore(deleter)))
+	router.Methods(http.MethodPost).Path("/users/{id}/password").Handler(selfOr(role.PermUsersWrite, handlePasswordChange(changer)))
+	router.Methods(http.MethodPost).Path("/users/{id}/unlock").Handler(can(role.PermUsersUnlock, handleUserUnlock(query, lockouts)))
 }
 
 type Creator interface {
 	Create(ctx context.Context, params user.CreateParams) (*user.User, error)
 }
 
-func handleUserCreate(creator Creator) http.HandlerFunc {
+type Updater interface {
+	Update(ctx context.Context, params user.UpdateParams) (*user.User, error)
+}
+
+type Deleter interface {
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) (*user.User, error)
+}
+
+type Lister interface {
+	List(ctx context.Context, params user.ListParams) (*user.ListResult, error)
+}
+
+type PasswordChanger interface {
+	Change(ctx context.Context, params user.ChangePasswordParams) error
+}
+
+type Lockouts interface {
+	Get(ctx context.Context, userID string) (*user.Lockout, error)
+	Unlock(ctx context.Context, userID string) error
+}
+
+type SessionOthersRevoker interface {
+	RevokeOthers(ctx context.Context, userID, keepID string) error
+}
+
+type RefreshUserRevoker interface {
+	RevokeAll(ctx context.Context, userID string) error
+}
+
+// credentialRevoker logs out every other session and refresh token of a user
+// whose password changed. Access tokens cannot be revoked and stay valid
+// until they expire.
+type credentialRevoker struct {
+	sessions      SessionOthersRevoker
+	refreshTokens RefreshUserRevoker
+}
+
+func (c *credentialRevoker) RevokeCredentials(ctx context.Context, userID, keepSessionID string) error {
+	if err := c.sessions.RevokeOthers(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+
+	return c.refreshTokens.RevokeAll(ctx, userID)
+}
+
+type UserGetter interface {
+	user.GetByID
+	user.GetByIDIncludingDeleted
+}
+
+type userResponse struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func newUserResponse(u *user.User) userResponse {
+	return userResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		Role:      u.Role.String(),
+		CreatedAt: u.CreatedAt,
+		DeletedAt: u.DeletedAt,
+	}
+}
+
+// missingRolePermission returns a permission of the role name that the caller p
+// does not hold, if any, so that nobody can give a user more than they have.
+// Unknown roles are left to the creator and updater to reject.
+func missingRolePermission(ctx context.Context, p *Principal, roles RoleGetter, name string) (role.Permission, error) {
+	rl, err := roles.Get(ctx, name)
+	if errors.Is(err, role.ErrDoesNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, perm := range rl.Permissions {
+		if !p.Can(perm) {
+			return perm, nil
+		}
+	}
+
+	return "", nil
+}
+
+// writeRoleAssignmentError writes the response of a missingRolePermission
+// failure and reports whether there was one.
+func writeRoleAssignmentError(w http.ResponseWriter, missing role.Permission, err error) bool {
+	switch {
+	case err != nil:
+		body := map[string]string{"error": err.Error()}
+		_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+	case missing != "":
+		writeMissingPermission(w, missing)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func handleUserCreate(creator Creator, roles RoleGetter) http.HandlerFunc {
 	type userCreateRequest struct {
 		ID       string `json:"id,required"`
 		Username string `json:"username"`
@@ -42,6 +169,19 @@ func handleUserCreate(creator Creator) http.HandlerFunc {
 			return
 		}
 
+		p, _ := PrincipalFromContext(r.Context())
+		if req.Role != "" && req.Role != user.RoleUser {
+			if !p.Can(role.PermRolesAssign) {
+				writeMissingPermission(w, role.PermRolesAssign)
+				return
+			}
+
+			missing, err := missingRolePermission(r.Context(), p, roles, req.Role)
+			if writeRoleAssignmentError(w, missing, err) {
+				return
+			}
+		}
+
 		params := user.CreateParams{
 			ID:       req.ID,
 			Username: req.Username,
@@ -52,9 +192,17 @@ func handleUserCreate(creator Creator) http.HandlerFunc {
 		u, err := creator.Create(context.Background(), params)
 		if err != nil {
 			body := map[string]string{"error": err.Error()}
-			switch err {
-			case user.ErrInvalidRole, user.ErrInvalidUsername, user.ErrPasswordTooSmall:
+			var (
+				conflict  *user.ConflictError
+				policyErr *user.PasswordPolicyError
+			)
+			switch {
+			case errors.As(err, &policyErr):
+				writePasswordPolicyError(w, err, policyErr)
+			case errors.Is(err, user.ErrInvalidRole), errors.Is(err, user.ErrInvalidUsername), errors.Is(err, user.ErrPasswordTooSmall):
 				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			case errors.As(err, &conflict):
+				_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
 			default:
 				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
 			}
@@ -71,13 +219,19 @@ func handleUserCreate(creator Creator) http.HandlerFunc {
 	}
 }
 
-func handleUserGet(q user.GetByID) http.HandlerFunc {
+// handleUserGet returns a user. Callers allowed to unlock users also get its
+// login lockout state.
+func handleUserGet(q UserGetter, lockouts Lockouts) http.HandlerFunc {
+	type lockoutResponse struct {
+		Locked       bool       `json:"locked"`
+		LockedUntil  *time.Time `json:"locked_until"`
+		FailedLogins int        `json:"failed_logins"`
+		Lockouts     int        `json:"lockouts"`
+	}
+
 	type userGetResponse struct {
-		ID        string     `json:"id"`
-		Username  string     `json:"username"`
-		Role      string     `json:"role"`
-		CreatedAt time.Time  `json:"created_at"`
-		DeletedAt *time.Time `json:"deleted_at"`
+		userResponse
+		Lockout *lockoutResponse `json:"lockout,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -89,7 +243,32 @@ func handleUserGet(q user.GetByID) http.HandlerFunc {
 			return
 		}
 
-		u, err := q.GetByID(context.Background(), id)
+		includeDeleted := false
+		if v := r.URL.Query().Get("include_deleted"); v != "" {
+			var err error
+			includeDeleted, err = strconv.ParseBool(v)
+			if err != nil {
+				body := map[string]string{"error": "invalid include_deleted"}
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+				return
+			}
+		}
+
+		p, _ := PrincipalFromContext(r.Context())
+		if includeDeleted && !p.Can(role.PermUsersRead) {
+			writeMissingPermission(w, role.PermUsersRead)
+			return
+		}
+
+		var (
+			u   *user.User
+			err error
+		)
+		if includeDeleted {
+			u, err = q.GetByIDIncludingDeleted(context.Background(), id)
+		} else {
+			u, err = q.GetByID(context.Background(), id)
+		}
 		if err != nil {
 			body := map[string]string{"error": err.Error()}
 			switch err {
@@ -101,14 +280,336 @@ func handleUserGet(q user.GetByID) http.HandlerFunc {
 			return
 		}
 
-		resp := userGetResponse{
-			ID:        u.ID,
-			Username:  u.Username,
-			Role:      u.Role.String(),
-			CreatedAt: u.CreatedAt,
-			DeletedAt: nil,
+		resp := userGetResponse{userResponse: newUserResponse(u)}
+		if p.Can(role.PermUsersUnlock) {
+			l, err := lockouts.Get(context.Background(), u.ID)
+			if err != nil {
+				body := map[string]string{"error": err.Error()}
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+				return
+			}
+
+			resp.Lockout = &lockoutResponse{
+				Locked:       l.Locked(time.Now()),
+				LockedUntil:  l.LockedUntil,
+				FailedLogins: l.Failures,
+				Lockouts:     l.Lockouts,
+			}
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+func handleUserUpdate(updater Updater, roles RoleGetter) http.HandlerFunc {
+	type userUpdateRequest struct {
+		Username *string `json:"username"`
+		Role     *string `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		var req userUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if req.Role != nil {
+			p, _ := PrincipalFromContext(r.Context())
+			if !p.Can(role.PermRolesAssign) {
+				writeMissingPermission(w, role.PermRolesAssign)
+				return
+			}
+
+			missing, err := missingRolePermission(r.Context(), p, roles, *req.Role)
+			if writeRoleAssignmentError(w, missing, err) {
+				return
+			}
+		}
+
+		params := user.UpdateParams{
+			ID:       id,
+			Username: req.Username,
+			Role:     req.Role,
+		}
+
+		u, err := updater.Update(context.Background(), params)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			var conflict *user.ConflictError
+			switch {
+			case errors.Is(err, user.ErrInvalidRole), errors.Is(err, user.ErrInvalidUsername):
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			case errors.As(err, &conflict):
+				_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, newUserResponse(u))
+	}
+}
+
+func handleUserDelete(deleter Deleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		if err := deleter.Delete(context.Background(), id); err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleUserRestore(deleter Deleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		u, err := deleter.Restore(context.Background(), id)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			case errors.Is(err, user.ErrNotDeleted):
+				_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		_ = httpx.WriteJSONResponse(w, http.StatusOK, newUserResponse(u))
+	}
+}
+
+func handleUserUnlock(q UserGetter, lockouts Lockouts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		u, err := q.GetByID(context.Background(), id)
+		if err == nil {
+			err = lockouts.Unlock(context.Background(), u.ID)
+		}
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handlePasswordChange changes the password of a user, who must send the
+// current one. The session of the caller stays logged in when it belongs to
+// the user.
+func handlePasswordChange(changer PasswordChanger) http.HandlerFunc {
+	type passwordChangeRequest struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := mux.Vars(r)["id"]
+		if !ok {
+			body := map[string]string{"error": "missing id in url"}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		var req passwordChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		params := user.ChangePasswordParams{
+			ID:              id,
+			CurrentPassword: req.CurrentPassword,
+			NewPassword:     req.NewPassword,
+		}
+		if p, _ := PrincipalFromContext(r.Context()); p != nil && p.UserID == id {
+			params.KeepSessionID = p.SessionID
+		}
+
+		if err := changer.Change(context.Background(), params); err != nil {
+			body := map[string]string{"error": err.Error()}
+			var (
+				policyErr *user.PasswordPolicyError
+				locked    *user.LockedError
+			)
+			switch {
+			case errors.As(err, &locked):
+				writeLocked(w, body, locked)
+			case errors.As(err, &policyErr):
+				writePasswordPolicyError(w, err, policyErr)
+			case errors.Is(err, user.ErrPasswordTooSmall), errors.Is(err, user.ErrPasswordReused):
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			case errors.Is(err, user.ErrIncorrectPassword):
+				_ = httpx.WriteJSONResponse(w, http.StatusForbidden, body)
+			case errors.Is(err, user.ErrDoesNotExist):
+				_ = httpx.WriteJSONResponse(w, http.StatusNotFound, body)
+			case errors.Is(err, user.ErrPasswordChanged):
+				_ = httpx.WriteJSONResponse(w, http.StatusConflict, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleUserList(lister Lister) http.HandlerFunc {
+	type userListResponse struct {
+		Items      []userResponse `json:"items"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := parseListParams(r)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+
+		res, err := lister.List(context.Background(), params)
+		if err != nil {
+			body := map[string]string{"error": err.Error()}
+			switch {
+			case errors.Is(err, user.ErrInvalidLimit), errors.Is(err, user.ErrInvalidSort),
+				errors.Is(err, user.ErrInvalidDeletedFilter), errors.Is(err, user.ErrInvalidCursor):
+				_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+			default:
+				_ = httpx.WriteJSONResponse(w, http.StatusInternalServerError, body)
+			}
+			return
+		}
+
+		resp := userListResponse{
+			Items: make([]userResponse, 0, len(res.Users)),
+		}
+		for _, u := range res.Users {
+			resp.Items = append(resp.Items, newUserResponse(u))
+		}
+		if res.NextCursor != "" {
+			resp.NextCursor = &res.NextCursor
 		}
 
 		_ = httpx.WriteJSONResponse(w, http.StatusOK, resp)
 	}
 }
+
+func parseListParams(r *http.Request) (user.ListParams, error) {
+	query := r.URL.Query()
+	params := user.ListParams{
+		Role:           query.Get("role"),
+		Deleted:        user.DeletedFilter(query.Get("deleted")),
+		UsernamePrefix: query.Get("username_prefix"),
+		Sort:           user.SortField(query.Get("sort")),
+		Cursor:         query.Get("cursor"),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		params.Descending = true
+	default:
+		return params, errors.New("invalid order. Valid orders are asc and desc")
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return params, user.ErrInvalidLimit
+		}
+		params.Limit = limit
+	}
+
+	for key, dst := range map[string]**time.Time{
+		"created_from": &params.CreatedFrom,
+		"created_to":   &params.CreatedTo,
+	} {
+		v := query.Get(key)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return params, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = &t
+	}
+
+	return params, nil
+}
+
+// writePasswordPolicyError responds with 400 and every rule the password
+// breaks, so clients can show them all at once.
+func writePasswordPolicyError(w http.ResponseWriter, err error, policyErr *user.PasswordPolicyError) {
+	type violationResponse struct {
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	violations := make([]violationResponse, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		violations = append(violations, violationResponse{
+			Rule:    v.Rule.String(),
+			Message: v.Message,
+		})
+	}
+
+	body := struct {
+		Error      string              `json:"error"`
+		Violations []violationResponse `json:"violations"`
+	}{
+		Error:      err.Error(),
+		Violations: violations,
+	}
+	_ = httpx.WriteJSONResponse(w, http.StatusBadRequest, body)
+}
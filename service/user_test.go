@@ -5,11 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mabaro3009/example-architecture-go/infra/memory"
+	"github.com/mabaro3009/example-architecture-go/role"
 	"github.com/mabaro3009/example-architecture-go/user"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,6 +50,21 @@ func TestHandleUserCreate(t *testing.T) {
 			creatorErr:  user.ErrPasswordTooSmall,
 			expStatus:   http.StatusBadRequest,
 		},
+		{
+			description: "wrapped invalid pass",
+			creatorErr:  fmt.Errorf("invalid password: %w", user.ErrPasswordTooSmall),
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "username conflict",
+			creatorErr:  user.ErrUsernameAlreadyExists,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "id conflict",
+			creatorErr:  user.ErrIDAlreadyExists,
+			expStatus:   http.StatusConflict,
+		},
 		{
 			description: "random err",
 			creatorErr:  errors.New("random error"),
@@ -70,7 +90,7 @@ func TestHandleUserCreate(t *testing.T) {
 				return &user.User{}, tc.creatorErr
 			}}
 
-			handleUserCreate(m)(w, r)
+			handleUserCreate(m, newTestRoles(t))(w, r)
 
 			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
 			if tc.expStatus != http.StatusCreated {
@@ -89,6 +109,120 @@ func TestHandleUserCreate(t *testing.T) {
 	}
 }
 
+func TestHandleUserCreate_PasswordPolicy(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{
+		"username": "usr",
+		"password": "usr",
+	})
+	r := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(buff))
+	w := httptest.NewRecorder()
+	m := &mockCreator{func(ctx context.Context, params user.CreateParams) (*user.User, error) {
+		return nil, fmt.Errorf("invalid password: %w", &user.PasswordPolicyError{Violations: []user.PasswordViolation{
+			{Rule: user.RuleMinLength, Message: "must be at least 8 characters long"},
+			{Rule: user.RuleUsername, Message: "must not contain the username"},
+		}})
+	}}
+
+	handleUserCreate(m, newTestRoles(t))(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response struct {
+		Error      string              `json:"error"`
+		Violations []map[string]string `json:"violations"`
+	}
+	_ = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.Contains(t, response.Error, "must not contain the username")
+	assert.Equal(t, []map[string]string{
+		{"rule": "min_length", "message": "must be at least 8 characters long"},
+		{"rule": "username", "message": "must not contain the username"},
+	}, response.Violations)
+}
+
+func TestHandleUserCreate_Admin(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{
+		"username": "usr",
+		"password": "1234",
+		"role":     user.RoleAdmin,
+	})
+	testCases := []struct {
+		description string
+		principal   *Principal
+		expStatus   int
+	}{
+		{
+			description: "anonymous",
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "user",
+			principal:   &Principal{UserID: "1", Role: user.RoleUser},
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "admin",
+			principal:   testPrincipal(t, "1", user.RoleAdmin),
+			expStatus:   http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(buff))
+			if tc.principal != nil {
+				r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+			}
+			w := httptest.NewRecorder()
+			m := &mockCreator{func(ctx context.Context, params user.CreateParams) (*user.User, error) {
+				return &user.User{Role: user.RoleAdmin}, nil
+			}}
+
+			handleUserCreate(m, newTestRoles(t))(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandleUserCreate_Concurrent(t *testing.T) {
+	const requests = 20
+
+	db := memory.NewUserDB()
+	roles := newTestRoles(t)
+	creator := user.NewCreator(user.NewSimplePasswordValidator(1), &mockHasher{}, roles, db)
+	router := mux.NewRouter()
+	auth := newAuthMiddleware(nil, nil, db, roles, testCookie)
+	addUserRoutes(router, auth, creator, user.NewUpdater(db, roles, db), user.NewDeleter(db, db), user.NewLister(db), nil, nil, db, roles)
+
+	buff, _ := json.Marshal(map[string]string{
+		"username": "usr",
+		"password": "1234",
+	})
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(buff))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			statuses <- w.Result().StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	assert.Equal(t, map[int]int{
+		http.StatusCreated:  1,
+		http.StatusConflict: requests - 1,
+	}, counts)
+}
+
 func TestHandleUserGet(t *testing.T) {
 	t.Run("not found", func(t *testing.T) {
 		userID := "userID"
@@ -96,13 +230,13 @@ func TestHandleUserGet(t *testing.T) {
 		r = mux.SetURLVars(r, map[string]string{"id": userID})
 		w := httptest.NewRecorder()
 
-		mock := &mockQuery{func(ctx context.Context, id string) (*user.User, error) {
+		mock := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
 			assert.Equal(t, userID, id)
 
 			return nil, user.ErrDoesNotExist
 		}}
 
-		handleUserGet(mock)(w, r)
+		handleUserGet(mock, nil)(w, r)
 		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
 	})
 
@@ -112,13 +246,13 @@ func TestHandleUserGet(t *testing.T) {
 		r = mux.SetURLVars(r, map[string]string{"id": userID})
 		w := httptest.NewRecorder()
 
-		mock := &mockQuery{func(ctx context.Context, id string) (*user.User, error) {
+		mock := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
 			assert.Equal(t, userID, id)
 
 			return nil, errors.New("random error")
 		}}
 
-		handleUserGet(mock)(w, r)
+		handleUserGet(mock, nil)(w, r)
 		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
 	})
 
@@ -128,13 +262,13 @@ func TestHandleUserGet(t *testing.T) {
 		r = mux.SetURLVars(r, map[string]string{"id": userID})
 		w := httptest.NewRecorder()
 
-		mock := &mockQuery{func(ctx context.Context, id string) (*user.User, error) {
+		mock := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
 			assert.Equal(t, userID, id)
 
 			return &user.User{}, nil
 		}}
 
-		handleUserGet(mock)(w, r)
+		handleUserGet(mock, nil)(w, r)
 		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
 		var response map[string]interface{}
@@ -146,6 +280,472 @@ func TestHandleUserGet(t *testing.T) {
 			assert.True(t, ok)
 		}
 	})
+
+	t.Run("deleted hidden by default", func(t *testing.T) {
+		userID := "userID"
+		r := httptest.NewRequest(http.MethodGet, "/users/{id}", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": userID})
+		w := httptest.NewRecorder()
+
+		mock := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
+			return nil, user.ErrDoesNotExist
+		}}
+
+		handleUserGet(mock, nil)(w, r)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("include deleted", func(t *testing.T) {
+		userID := "userID"
+		deletedAt := time.Now()
+		r := httptest.NewRequest(http.MethodGet, "/users/{id}?include_deleted=true", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": userID})
+		r = r.WithContext(withPrincipal(r.Context(), testPrincipal(t, "admin", user.RoleAdmin)))
+		w := httptest.NewRecorder()
+
+		mock := &mockQuery{getByIDIncludingDeleted: func(ctx context.Context, id string) (*user.User, error) {
+			assert.Equal(t, userID, id)
+
+			return &user.User{ID: id, DeletedAt: &deletedAt}, nil
+		}}
+
+		handleUserGet(mock, newMockLockouts(nil))(w, r)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		var response map[string]interface{}
+		_ = json.NewDecoder(w.Result().Body).Decode(&response)
+		assert.NotNil(t, response["deleted_at"])
+	})
+
+	t.Run("lockout shown to admins", func(t *testing.T) {
+		lockedUntil := time.Now().Add(time.Minute)
+		get := func(principal *Principal) map[string]interface{} {
+			r := httptest.NewRequest(http.MethodGet, "/users/{id}", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "userID"})
+			r = r.WithContext(withPrincipal(r.Context(), principal))
+			w := httptest.NewRecorder()
+			mock := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
+				return &user.User{ID: id}, nil
+			}}
+
+			handleUserGet(mock, newMockLockouts(&user.Lockout{Failures: 2, Lockouts: 1, LockedUntil: &lockedUntil}))(w, r)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			return response
+		}
+
+		response := get(testPrincipal(t, "admin", user.RoleAdmin))
+		assert.Equal(t, "userID", response["id"])
+		assert.Equal(t, map[string]interface{}{
+			"locked":        true,
+			"locked_until":  lockedUntil.Format(time.RFC3339Nano),
+			"failed_logins": float64(2),
+			"lockouts":      float64(1),
+		}, response["lockout"])
+
+		response = get(&Principal{UserID: "userID", Role: user.RoleUser})
+		_, ok := response["lockout"]
+		assert.False(t, ok)
+	})
+
+	t.Run("include deleted by non admin", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/users/{id}?include_deleted=true", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": "userID"})
+		r = r.WithContext(withPrincipal(r.Context(), &Principal{UserID: "userID", Role: user.RoleUser}))
+		w := httptest.NewRecorder()
+
+		handleUserGet(&mockQuery{}, nil)(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("invalid include deleted", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/users/{id}?include_deleted=maybe", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": "userID"})
+		w := httptest.NewRecorder()
+
+		handleUserGet(&mockQuery{}, nil)(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func TestHandleUserDelete(t *testing.T) {
+	testCases := []struct {
+		description string
+		deleterErr  error
+		expStatus   int
+	}{
+		{
+			description: "not found",
+			deleterErr:  user.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "random err",
+			deleterErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			deleterErr:  nil,
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "userID"
+			r := httptest.NewRequest(http.MethodDelete, "/users/{id}", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			w := httptest.NewRecorder()
+			m := &mockDeleter{delete: func(ctx context.Context, id string) error {
+				assert.Equal(t, userID, id)
+
+				return tc.deleterErr
+			}}
+
+			handleUserDelete(m)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandleUserRestore(t *testing.T) {
+	testCases := []struct {
+		description string
+		deleterErr  error
+		expStatus   int
+	}{
+		{
+			description: "not found",
+			deleterErr:  user.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "not deleted",
+			deleterErr:  user.ErrNotDeleted,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "random err",
+			deleterErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			deleterErr:  nil,
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "userID"
+			r := httptest.NewRequest(http.MethodPost, "/users/{id}/restore", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			w := httptest.NewRecorder()
+			m := &mockDeleter{restore: func(ctx context.Context, id string) (*user.User, error) {
+				assert.Equal(t, userID, id)
+
+				return &user.User{ID: id}, tc.deleterErr
+			}}
+
+			handleUserRestore(m)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandleUserUnlock(t *testing.T) {
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description string
+		getErr      error
+		unlockErr   error
+		expUnlock   bool
+		expStatus   int
+	}{
+		{
+			description: "not found",
+			getErr:      user.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "random err",
+			unlockErr:   randomErr,
+			expUnlock:   true,
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			expUnlock:   true,
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "userID"
+			r := httptest.NewRequest(http.MethodPost, "/users/{id}/unlock", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			w := httptest.NewRecorder()
+			q := &mockQuery{getByID: func(ctx context.Context, id string) (*user.User, error) {
+				assert.Equal(t, userID, id)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &user.User{ID: id}, nil
+			}}
+			unlocked := false
+			lockouts := &mockLockouts{unlock: func(ctx context.Context, id string) error {
+				assert.Equal(t, userID, id)
+				unlocked = true
+
+				return tc.unlockErr
+			}}
+
+			handleUserUnlock(q, lockouts)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			assert.Equal(t, tc.expUnlock, unlocked)
+		})
+	}
+}
+
+func TestHandlePasswordChange(t *testing.T) {
+	buff, _ := json.Marshal(map[string]string{
+		"current_password": "old",
+		"new_password":     "new",
+	})
+	testCases := []struct {
+		description   string
+		principal     *Principal
+		changerErr    error
+		expKeepID     string
+		expStatus     int
+		expRetryAfter string
+		expViolations bool
+	}{
+		{
+			description:   "weak password",
+			changerErr:    &user.PasswordPolicyError{Violations: []user.PasswordViolation{{Rule: user.RuleMinLength}}},
+			expStatus:     http.StatusBadRequest,
+			expViolations: true,
+		},
+		{
+			description: "reused password",
+			changerErr:  user.ErrPasswordReused,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "incorrect current password",
+			changerErr:  user.ErrIncorrectPassword,
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description:   "locked out",
+			changerErr:    &user.LockedError{Until: time.Now().Add(time.Minute)},
+			expStatus:     http.StatusTooManyRequests,
+			expRetryAfter: "60",
+		},
+		{
+			description: "not found",
+			changerErr:  user.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "changed concurrently",
+			changerErr:  user.ErrPasswordChanged,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "random err",
+			changerErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "self keeps its session",
+			principal:   &Principal{UserID: "userID", SessionID: "s"},
+			expKeepID:   "s",
+			expStatus:   http.StatusNoContent,
+		},
+		{
+			description: "admin keeps no session of the user",
+			principal:   &Principal{UserID: "admin", SessionID: "s"},
+			expStatus:   http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "userID"
+			r := httptest.NewRequest(http.MethodPost, "/users/{id}/password", bytes.NewReader(buff))
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			if tc.principal != nil {
+				r = r.WithContext(withPrincipal(r.Context(), tc.principal))
+			}
+			w := httptest.NewRecorder()
+			m := &mockPasswordChanger{func(ctx context.Context, params user.ChangePasswordParams) error {
+				assert.Equal(t, userID, params.ID)
+				assert.Equal(t, "old", params.CurrentPassword)
+				assert.Equal(t, "new", params.NewPassword)
+				assert.Equal(t, tc.expKeepID, params.KeepSessionID)
+
+				return tc.changerErr
+			}}
+
+			handlePasswordChange(m)(w, r)
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			assert.Equal(t, tc.expRetryAfter, w.Result().Header.Get("Retry-After"))
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			_, ok := response["violations"]
+			assert.Equal(t, tc.expViolations, ok)
+		})
+	}
+}
+
+func TestCredentialRevoker(t *testing.T) {
+	var keptSession, revokedTokens string
+	revoker := &credentialRevoker{
+		sessions: &mockSessions{revokeOthers: func(ctx context.Context, userID, keepID string) error {
+			assert.Equal(t, "1", userID)
+			keptSession = keepID
+			return nil
+		}},
+		refreshTokens: &mockRefreshTokens{revokeAll: func(ctx context.Context, userID string) error {
+			revokedTokens = userID
+			return nil
+		}},
+	}
+
+	assert.NoError(t, revoker.RevokeCredentials(context.Background(), "1", "s"))
+	assert.Equal(t, "s", keptSession)
+	assert.Equal(t, "1", revokedTokens)
+}
+
+func TestHandleUserList(t *testing.T) {
+	testCases := []struct {
+		description string
+		query       string
+		listerErr   error
+		nextCursor  string
+		expParams   user.ListParams
+		expStatus   int
+	}{
+		{
+			description: "invalid order",
+			query:       "order=up",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid limit",
+			query:       "limit=ten",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid created_from",
+			query:       "created_from=yesterday",
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid cursor",
+			query:       "cursor=abc",
+			listerErr:   user.ErrInvalidCursor,
+			expParams:   user.ListParams{Cursor: "abc"},
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "random err",
+			listerErr:   errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			query:       "role=admin&deleted=include&username_prefix=ab&sort=username&order=desc&limit=5&created_from=2022-01-01T00:00:00Z",
+			nextCursor:  "next",
+			expParams: user.ListParams{
+				Role:           "admin",
+				Deleted:        user.DeletedInclude,
+				UsernamePrefix: "ab",
+				Sort:           user.SortUsername,
+				Descending:     true,
+				Limit:          5,
+				CreatedFrom:    func() *time.Time { t := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC); return &t }(),
+			},
+			expStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users?"+tc.query, nil)
+			w := httptest.NewRecorder()
+			m := &mockLister{func(ctx context.Context, params user.ListParams) (*user.ListResult, error) {
+				assert.Equal(t, tc.expParams, params)
+
+				return &user.ListResult{Users: []*user.User{{ID: "1"}}, NextCursor: tc.nextCursor}, tc.listerErr
+			}}
+
+			handleUserList(m)(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Items      []map[string]interface{} `json:"items"`
+				NextCursor *string                  `json:"next_cursor"`
+			}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+			assert.Len(t, response.Items, 1)
+			assert.Equal(t, &tc.nextCursor, response.NextCursor)
+		})
+	}
+}
+
+type mockLockouts struct {
+	get    func(ctx context.Context, userID string) (*user.Lockout, error)
+	unlock func(ctx context.Context, userID string) error
+}
+
+// newMockLockouts returns state, or an empty lockout when it is nil, for
+// every user.
+func newMockLockouts(state *user.Lockout) *mockLockouts {
+	return &mockLockouts{get: func(ctx context.Context, userID string) (*user.Lockout, error) {
+		if state == nil {
+			return &user.Lockout{UserID: userID}, nil
+		}
+
+		return state, nil
+	}}
+}
+
+func (m *mockLockouts) Get(ctx context.Context, userID string) (*user.Lockout, error) {
+	return m.get(ctx, userID)
+}
+
+func (m *mockLockouts) Unlock(ctx context.Context, userID string) error {
+	return m.unlock(ctx, userID)
+}
+
+type mockPasswordChanger struct {
+	change func(ctx context.Context, params user.ChangePasswordParams) error
+}
+
+func (m *mockPasswordChanger) Change(ctx context.Context, params user.ChangePasswordParams) error {
+	return m.change(ctx, params)
+}
+
+type mockLister struct {
+	list func(ctx context.Context, params user.ListParams) (*user.ListResult, error)
+}
+
+func (m *mockLister) List(ctx context.Context, params user.ListParams) (*user.ListResult, error) {
+	return m.list(ctx, params)
 }
 
 type mockCreator struct {
@@ -156,10 +756,149 @@ func (m *mockCreator) Create(ctx context.Context, params user.CreateParams) (*us
 	return m.create(ctx, params)
 }
 
+func TestHandleUserUpdate(t *testing.T) {
+	userID := "userID"
+	username := "usr"
+	roleName := "admin"
+	buff, _ := json.Marshal(map[string]string{
+		"username": username,
+		"role":     roleName,
+	})
+	admin := testPrincipal(t, "admin", user.RoleAdmin)
+	testCases := []struct {
+		description string
+		body        []byte
+		principal   *Principal
+		updaterErr  error
+		expStatus   int
+	}{
+		{
+			description: "invalid body",
+			body:        []byte("{"),
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "role change by non admin",
+			body:        buff,
+			principal:   &Principal{UserID: userID, Role: user.RoleUser},
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "self escalation with roles:assign only",
+			body:        buff,
+			principal:   &Principal{UserID: userID, Role: "assigner", Permissions: []role.Permission{role.PermRolesAssign}},
+			expStatus:   http.StatusForbidden,
+		},
+		{
+			description: "invalid role",
+			body:        buff,
+			updaterErr:  user.ErrInvalidRole,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "invalid username",
+			body:        buff,
+			updaterErr:  user.ErrInvalidUsername,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			description: "not found",
+			body:        buff,
+			updaterErr:  user.ErrDoesNotExist,
+			expStatus:   http.StatusNotFound,
+		},
+		{
+			description: "username conflict",
+			body:        buff,
+			updaterErr:  user.ErrUsernameAlreadyExists,
+			expStatus:   http.StatusConflict,
+		},
+		{
+			description: "random err",
+			body:        buff,
+			updaterErr:  errors.New("random error"),
+			expStatus:   http.StatusInternalServerError,
+		},
+		{
+			description: "success",
+			body:        buff,
+			expStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/users/{id}", bytes.NewReader(tc.body))
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			principal := tc.principal
+			if principal == nil {
+				principal = admin
+			}
+			r = r.WithContext(withPrincipal(r.Context(), principal))
+			w := httptest.NewRecorder()
+			m := &mockUpdater{func(ctx context.Context, params user.UpdateParams) (*user.User, error) {
+				assert.Equal(t, userID, params.ID)
+				assert.Equal(t, &username, params.Username)
+				assert.Equal(t, &roleName, params.Role)
+
+				return &user.User{}, tc.updaterErr
+			}}
+
+			handleUserUpdate(m, newTestRoles(t))(w, r)
+
+			assert.Equal(t, tc.expStatus, w.Result().StatusCode)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			_ = json.NewDecoder(w.Result().Body).Decode(&response)
+
+			expKeys := []string{"id", "username", "role", "created_at", "deleted_at"}
+			for _, key := range expKeys {
+				_, ok := response[key]
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+type mockUpdater struct {
+	update func(ctx context.Context, params user.UpdateParams) (*user.User, error)
+}
+
+func (m *mockUpdater) Update(ctx context.Context, params user.UpdateParams) (*user.User, error) {
+	return m.update(ctx, params)
+}
+
+type mockHasher struct{}
+
+func (m *mockHasher) Hash(password string) ([]byte, error) {
+	return []byte(password), nil
+}
+
+type mockDeleter struct {
+	delete  func(ctx context.Context, id string) error
+	restore func(ctx context.Context, id string) (*user.User, error)
+}
+
+func (m *mockDeleter) Delete(ctx context.Context, id string) error {
+	return m.delete(ctx, id)
+}
+
+func (m *mockDeleter) Restore(ctx context.Context, id string) (*user.User, error) {
+	return m.restore(ctx, id)
+}
+
 type mockQuery struct {
-	getByID func(ctx context.Context, id string) (*user.User, error)
+	getByID                 func(ctx context.Context, id string) (*user.User, error)
+	getByIDIncludingDeleted func(ctx context.Context, id string) (*user.User, error)
 }
 
 func (m *mockQuery) GetByID(ctx context.Context, id string) (*user.User, error) {
 	return m.getByID(ctx, id)
 }
+
+func (m *mockQuery) GetByIDIncludingDeleted(ctx context.Context, id string) (*user.User, error) {
+	return m.getByIDIncludingDeleted(ctx, id)
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+type Commands interface {
+	Insert
+	Touch
+	Delete
+	DeleteByUserID
+	DeleteExpired
+}
+
+type Insert interface {
+	Insert(ctx context.Context, s *Session) error
+}
+
+// Touch records that the session id was used at lastSeenAt.
+type Touch interface {
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+}
+
+type Delete interface {
+	Delete(ctx context.Context, id string) error
+}
+
+type DeleteByUserID interface {
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// DeleteExpired deletes every session that expired at or before now.
+type DeleteExpired interface {
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
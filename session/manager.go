@@ -0,0 +1,212 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTTL = 24 * time.Hour
+
+	tokenBytes = 32
+)
+
+type ManagerQueries interface {
+	GetByID
+	GetByTokenHash
+	ListByUserID
+}
+
+type ManagerCommands interface {
+	Insert
+	Touch
+	Delete
+	DeleteByUserID
+	DeleteExpired
+}
+
+type Manager struct {
+	ttl time.Duration
+	q   ManagerQueries
+	cmd ManagerCommands
+}
+
+func NewManager(ttl time.Duration, q ManagerQueries, cmd ManagerCommands) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Manager{
+		ttl: ttl,
+		q:   q,
+		cmd: cmd,
+	}
+}
+
+type CreateParams struct {
+	UserID    string
+	UserAgent string
+	IP        string
+}
+
+// Create starts a session for the user and returns it with its secret token.
+func (m *Manager) Create(ctx context.Context, params CreateParams) (*Session, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	s := &Session{
+		ID:         uuid.NewString(),
+		UserID:     params.UserID,
+		TokenHash:  HashToken(token),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(m.ttl),
+		UserAgent:  params.UserAgent,
+		IP:         params.IP,
+	}
+
+	if err = m.cmd.Insert(ctx, s); err != nil {
+		return nil, "", err
+	}
+
+	return s, token, nil
+}
+
+// Authenticate returns the session of token and records it as seen. It
+// returns ErrDoesNotExist for unknown or revoked tokens and ErrExpired for
+// expired sessions, which are deleted.
+func (m *Manager) Authenticate(ctx context.Context, token string) (*Session, error) {
+	s, err := m.q.GetByTokenHash(ctx, HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !now.Before(s.ExpiresAt) {
+		if err = m.cmd.Delete(ctx, s.ID); err != nil && !errors.Is(err, ErrDoesNotExist) {
+			return nil, err
+		}
+
+		return nil, ErrExpired
+	}
+
+	if err = m.cmd.Touch(ctx, s.ID, now); err != nil {
+		return nil, err
+	}
+	s.LastSeenAt = now
+
+	return s, nil
+}
+
+// List returns the sessions of the user that have not expired.
+func (m *Manager) List(ctx context.Context, userID string) ([]*Session, error) {
+	all, err := m.q.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]*Session, 0, len(all))
+	for _, s := range all {
+		if now.Before(s.ExpiresAt) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends the session id of the user. It returns ErrDoesNotExist when the
+// session belongs to somebody else.
+func (m *Manager) Revoke(ctx context.Context, userID, id string) error {
+	s, err := m.q.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if s.UserID != userID {
+		return ErrDoesNotExist
+	}
+
+	return m.cmd.Delete(ctx, id)
+}
+
+// RevokeAll ends every session of the user.
+func (m *Manager) RevokeAll(ctx context.Context, userID string) error {
+	return m.cmd.DeleteByUserID(ctx, userID)
+}
+
+// RevokeOthers ends every session of the user but keepID. An empty keepID
+// ends all of them.
+func (m *Manager) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	if keepID == "" {
+		return m.RevokeAll(ctx, userID)
+	}
+
+	sessions, err := m.q.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.ID == keepID {
+			continue
+		}
+		if err = m.cmd.Delete(ctx, s.ID); err != nil && !errors.Is(err, ErrDoesNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RevokeToken ends the session of token. Unknown tokens are ignored, so it
+// can be used to log out with a cookie that may already be stale.
+func (m *Manager) RevokeToken(ctx context.Context, token string) error {
+	s, err := m.q.GetByTokenHash(ctx, HashToken(token))
+	if errors.Is(err, ErrDoesNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = m.cmd.Delete(ctx, s.ID)
+	if errors.Is(err, ErrDoesNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Sweep deletes the expired sessions, which Authenticate only deletes when
+// they are used again.
+func (m *Manager) Sweep(ctx context.Context) error {
+	return m.cmd.DeleteExpired(ctx, time.Now())
+}
+
+// HashToken returns the hash under which the session of token is stored.
+// Tokens are random, so a fast hash is enough.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+
+	return sum[:]
+}
+
+func newToken() (string, error) {
+	buff := make([]byte, tokenBytes)
+	if _, err := rand.Read(buff); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buff), nil
+}
@@ -0,0 +1,339 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+	params := CreateParams{
+		UserID:    "1",
+		UserAgent: "agent",
+		IP:        "10.0.0.1",
+	}
+	var inserted *Session
+	cmd := &mockManagerCMD{insert: func(ctx context.Context, s *Session) error {
+		inserted = s
+		return nil
+	}}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	s, token, err := m.Create(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, inserted, s)
+	assert.Equal(t, params.UserID, s.UserID)
+	assert.Equal(t, params.UserAgent, s.UserAgent)
+	assert.Equal(t, params.IP, s.IP)
+	assert.NotEmpty(t, s.ID)
+	assert.Equal(t, HashToken(token), s.TokenHash)
+	assert.Equal(t, s.CreatedAt, s.LastSeenAt)
+	assert.Equal(t, time.Hour, s.ExpiresAt.Sub(s.CreatedAt))
+
+	_, other, err := m.Create(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestAuthenticate(t *testing.T) {
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description string
+		getErr      error
+		touchErr    error
+		deleteErr   error
+		expiresIn   time.Duration
+		expTouched  bool
+		expDeleted  bool
+		expError    error
+	}{
+		{
+			description: "unknown token",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "random error",
+			getErr:      randomErr,
+			expError:    randomErr,
+		},
+		{
+			description: "expired",
+			expiresIn:   -time.Second,
+			expDeleted:  true,
+			expError:    ErrExpired,
+		},
+		{
+			description: "expired and deleted concurrently",
+			expiresIn:   -time.Second,
+			deleteErr:   ErrDoesNotExist,
+			expDeleted:  true,
+			expError:    ErrExpired,
+		},
+		{
+			description: "random delete error",
+			expiresIn:   -time.Second,
+			deleteErr:   randomErr,
+			expDeleted:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "random touch error",
+			expiresIn:   time.Hour,
+			touchErr:    randomErr,
+			expTouched:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			expiresIn:   time.Hour,
+			expTouched:  true,
+			expError:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			token := "token"
+			q := &mockManagerQueries{getByTokenHash: func(ctx context.Context, tokenHash []byte) (*Session, error) {
+				assert.Equal(t, HashToken(token), tokenHash)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &Session{ID: "s", ExpiresAt: time.Now().Add(tc.expiresIn)}, nil
+			}}
+			touched, deleted := false, false
+			cmd := &mockManagerCMD{
+				touch: func(ctx context.Context, id string, lastSeenAt time.Time) error {
+					assert.Equal(t, "s", id)
+					touched = true
+
+					return tc.touchErr
+				},
+				delete: func(ctx context.Context, id string) error {
+					assert.Equal(t, "s", id)
+					deleted = true
+
+					return tc.deleteErr
+				},
+			}
+
+			m := NewManager(time.Hour, q, cmd)
+
+			s, err := m.Authenticate(context.Background(), token)
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expTouched, touched)
+			assert.Equal(t, tc.expDeleted, deleted)
+			if tc.expError == nil {
+				assert.Equal(t, "s", s.ID)
+				assert.False(t, s.LastSeenAt.IsZero())
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	now := time.Now()
+	q := &mockManagerQueries{listByUserID: func(ctx context.Context, userID string) ([]*Session, error) {
+		assert.Equal(t, "1", userID)
+
+		return []*Session{
+			{ID: "expired", ExpiresAt: now.Add(-time.Second)},
+			{ID: "active", ExpiresAt: now.Add(time.Hour)},
+		}, nil
+	}}
+
+	m := NewManager(time.Hour, q, nil)
+
+	sessions, err := m.List(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "active", sessions[0].ID)
+}
+
+func TestRevoke(t *testing.T) {
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description string
+		userID      string
+		getErr      error
+		deleteErr   error
+		expDeleted  bool
+		expError    error
+	}{
+		{
+			description: "unknown session",
+			userID:      "1",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "session of other user",
+			userID:      "2",
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "random delete error",
+			userID:      "1",
+			deleteErr:   randomErr,
+			expDeleted:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			userID:      "1",
+			expDeleted:  true,
+			expError:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &mockManagerQueries{getByID: func(ctx context.Context, id string) (*Session, error) {
+				assert.Equal(t, "s", id)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &Session{ID: "s", UserID: "1"}, nil
+			}}
+			deleted := false
+			cmd := &mockManagerCMD{delete: func(ctx context.Context, id string) error {
+				assert.Equal(t, "s", id)
+				deleted = true
+
+				return tc.deleteErr
+			}}
+
+			m := NewManager(time.Hour, q, cmd)
+
+			err := m.Revoke(context.Background(), tc.userID, "s")
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expDeleted, deleted)
+		})
+	}
+}
+
+func TestRevokeOthers(t *testing.T) {
+	q := &mockManagerQueries{listByUserID: func(ctx context.Context, userID string) ([]*Session, error) {
+		assert.Equal(t, "1", userID)
+
+		return []*Session{{ID: "a"}, {ID: "current"}, {ID: "gone"}, {ID: "b"}}, nil
+	}}
+	var (
+		deleted    []string
+		allDeleted string
+	)
+	cmd := &mockManagerCMD{
+		delete: func(ctx context.Context, id string) error {
+			if id == "gone" {
+				return ErrDoesNotExist
+			}
+			deleted = append(deleted, id)
+
+			return nil
+		},
+		deleteByUserID: func(ctx context.Context, userID string) error {
+			allDeleted = userID
+			return nil
+		},
+	}
+
+	m := NewManager(time.Hour, q, cmd)
+
+	assert.NoError(t, m.RevokeOthers(context.Background(), "1", "current"))
+	assert.Equal(t, []string{"a", "b"}, deleted)
+	assert.Empty(t, allDeleted)
+
+	assert.NoError(t, m.RevokeOthers(context.Background(), "1", ""))
+	assert.Equal(t, "1", allDeleted)
+}
+
+func TestRevokeToken(t *testing.T) {
+	q := &mockManagerQueries{getByTokenHash: func(ctx context.Context, tokenHash []byte) (*Session, error) {
+		if string(tokenHash) != string(HashToken("token")) {
+			return nil, ErrDoesNotExist
+		}
+
+		return &Session{ID: "s"}, nil
+	}}
+	var deleted string
+	cmd := &mockManagerCMD{delete: func(ctx context.Context, id string) error {
+		deleted = id
+		return nil
+	}}
+
+	m := NewManager(time.Hour, q, cmd)
+
+	assert.NoError(t, m.RevokeToken(context.Background(), "stale"))
+	assert.Empty(t, deleted)
+
+	assert.NoError(t, m.RevokeToken(context.Background(), "token"))
+	assert.Equal(t, "s", deleted)
+}
+
+func TestSweep(t *testing.T) {
+	var swept time.Time
+	cmd := &mockManagerCMD{deleteExpired: func(ctx context.Context, now time.Time) error {
+		swept = now
+		return nil
+	}}
+
+	m := NewManager(time.Hour, nil, cmd)
+
+	before := time.Now()
+	assert.NoError(t, m.Sweep(context.Background()))
+	assert.False(t, swept.Before(before))
+}
+
+type mockManagerQueries struct {
+	getByID        func(ctx context.Context, id string) (*Session, error)
+	getByTokenHash func(ctx context.Context, tokenHash []byte) (*Session, error)
+	listByUserID   func(ctx context.Context, userID string) ([]*Session, error)
+}
+
+func (m *mockManagerQueries) GetByID(ctx context.Context, id string) (*Session, error) {
+	return m.getByID(ctx, id)
+}
+
+func (m *mockManagerQueries) GetByTokenHash(ctx context.Context, tokenHash []byte) (*Session, error) {
+	return m.getByTokenHash(ctx, tokenHash)
+}
+
+func (m *mockManagerQueries) ListByUserID(ctx context.Context, userID string) ([]*Session, error) {
+	return m.listByUserID(ctx, userID)
+}
+
+type mockManagerCMD struct {
+	insert         func(ctx context.Context, s *Session) error
+	touch          func(ctx context.Context, id string, lastSeenAt time.Time) error
+	delete         func(ctx context.Context, id string) error
+	deleteByUserID func(ctx context.Context, userID string) error
+	deleteExpired  func(ctx context.Context, now time.Time) error
+}
+
+func (m *mockManagerCMD) Insert(ctx context.Context, s *Session) error {
+	return m.insert(ctx, s)
+}
+
+func (m *mockManagerCMD) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	return m.touch(ctx, id, lastSeenAt)
+}
+
+func (m *mockManagerCMD) Delete(ctx context.Context, id string) error {
+	return m.delete(ctx, id)
+}
+
+func (m *mockManagerCMD) DeleteByUserID(ctx context.Context, userID string) error {
+	return m.deleteByUserID(ctx, userID)
+}
+
+func (m *mockManagerCMD) DeleteExpired(ctx context.Context, now time.Time) error {
+	return m.deleteExpired(ctx, now)
+}
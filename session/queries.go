@@ -0,0 +1,23 @@
+package session
+
+import "context"
+
+type Queries interface {
+	GetByID
+	GetByTokenHash
+	ListByUserID
+}
+
+type GetByID interface {
+	GetByID(ctx context.Context, id string) (*Session, error)
+}
+
+type GetByTokenHash interface {
+	GetByTokenHash(ctx context.Context, tokenHash []byte) (*Session, error)
+}
+
+// ListByUserID returns every session of the user, expired ones included,
+// sorted by creation time.
+type ListByUserID interface {
+	ListByUserID(ctx context.Context, userID string) ([]*Session, error)
+}
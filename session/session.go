@@ -0,0 +1,24 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDoesNotExist = errors.New("session does not exist")
+	ErrExpired      = errors.New("session has expired")
+)
+
+// Session is a login of a user. The token handed to the client is never
+// stored: only its hash is, so a leaked store cannot be used to log in.
+type Session struct {
+	ID         string
+	UserID     string
+	TokenHash  []byte
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	IP         string
+}
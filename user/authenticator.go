@@ -0,0 +1,125 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// dummyPassword is hashed once so that logins of unknown users spend as long
+// verifying a password as logins of existing users.
+const dummyPassword = "dummy password used to equalize login timings"
+
+// PasswordVerifier checks passwords against their hashes. NeedsRehash reports
+// whether a hash was made with an outdated algorithm or parameters.
+type PasswordVerifier interface {
+	Verify(hashedPassword []byte, password string) error
+	NeedsRehash(hashedPassword []byte) bool
+}
+
+// LoginLockout locks users out after repeated failed logins. It is
+// implemented by Lockouts.
+type LoginLockout interface {
+	Check(ctx context.Context, userID string) error
+	Fail(ctx context.Context, userID string) error
+	Succeed(ctx context.Context, userID string) error
+}
+
+type AuthenticatorQueries interface {
+	GetByUsername
+}
+
+type AuthenticatorCommands interface {
+	UpdatePassword
+}
+
+type Authenticator struct {
+	hasher   PasswordHasher
+	verifier PasswordVerifier
+	lockout  LoginLockout
+	q        AuthenticatorQueries
+	cmd      AuthenticatorCommands
+
+	dummyOnce sync.Once
+	dummyHash []byte
+}
+
+func NewAuthenticator(h PasswordHasher, v PasswordVerifier, lockout LoginLockout, q AuthenticatorQueries, cmd AuthenticatorCommands) *Authenticator {
+	return &Authenticator{
+		hasher:   h,
+		verifier: v,
+		lockout:  lockout,
+		q:        q,
+		cmd:      cmd,
+	}
+}
+
+// Authenticate returns the user with the given credentials. It returns
+// ErrInvalidCredentials when the user does not exist, is soft-deleted or the
+// password does not match, and a *LockedError, matching ErrAccountLocked, when
+// the user is locked out after too many failed logins, even with the right
+// password. Passwords hashed with an outdated algorithm or parameters are
+// hashed again with the current ones.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	u, err := a.q.GetByUsername(ctx, username)
+	if err != nil && err != ErrDoesNotExist {
+		return nil, err
+	}
+
+	if err == ErrDoesNotExist || u.DeletedAt != nil {
+		_ = a.verifier.Verify(a.dummy(), password)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err = a.lockout.Check(ctx, u.ID); err != nil {
+		return nil, err
+	}
+
+	if err = a.verifier.Verify(u.HashedPassword, password); err != nil {
+		if err = a.lockout.Fail(ctx, u.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err = a.lockout.Succeed(ctx, u.ID); err != nil {
+		return nil, err
+	}
+
+	if a.verifier.NeedsRehash(u.HashedPassword) {
+		a.rehash(ctx, u, password)
+	}
+
+	return u, nil
+}
+
+// rehash replaces the hashed password of u unless it changed since it was
+// read. The credentials are already verified, so a failed rehash does not fail
+// the login and is retried on the next one.
+func (a *Authenticator) rehash(ctx context.Context, u *User, password string) {
+	hashedPassword, err := a.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+
+	err = a.cmd.UpdatePassword(ctx, &UpdatePasswordParams{
+		ID:                u.ID,
+		OldHashedPassword: u.HashedPassword,
+		HashedPassword:    hashedPassword,
+	})
+	if err == nil {
+		u.HashedPassword = hashedPassword
+	}
+}
+
+func (a *Authenticator) dummy() []byte {
+	a.dummyOnce.Do(func() {
+		a.dummyHash, _ = a.hasher.Hash(dummyPassword)
+	})
+
+	return a.dummyHash
+}
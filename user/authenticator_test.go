@@ -0,0 +1,306 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	deletedAt := time.Now()
+	randomErr := errors.New("random error")
+	mismatch := errors.New("mismatch")
+
+	testCases := []struct {
+		description   string
+		getErr        error
+		deletedAt     *time.Time
+		password      string
+		needsRehash   bool
+		updateErr     error
+		expVerifyHash []byte
+		expUpdate     bool
+		expHash       []byte
+		expError      error
+	}{
+		{
+			description:   "unknown user",
+			getErr:        ErrDoesNotExist,
+			password:      "pass",
+			expVerifyHash: []byte(dummyPassword),
+			expError:      ErrInvalidCredentials,
+		},
+		{
+			description:   "deleted user",
+			deletedAt:     &deletedAt,
+			password:      "pass",
+			expVerifyHash: []byte(dummyPassword),
+			expError:      ErrInvalidCredentials,
+		},
+		{
+			description: "random error",
+			getErr:      randomErr,
+			password:    "pass",
+			expError:    randomErr,
+		},
+		{
+			description:   "wrong password",
+			password:      "wrong",
+			expVerifyHash: []byte("pass"),
+			expError:      ErrInvalidCredentials,
+		},
+		{
+			description:   "wrong password with outdated hash",
+			password:      "wrong",
+			needsRehash:   true,
+			expVerifyHash: []byte("pass"),
+			expError:      ErrInvalidCredentials,
+		},
+		{
+			description:   "rehash",
+			password:      "pass",
+			needsRehash:   true,
+			expVerifyHash: []byte("pass"),
+			expUpdate:     true,
+			expHash:       []byte("new pass"),
+		},
+		{
+			description:   "rehash error does not fail login",
+			password:      "pass",
+			needsRehash:   true,
+			updateErr:     ErrPasswordChanged,
+			expVerifyHash: []byte("pass"),
+			expUpdate:     true,
+			expHash:       []byte("pass"),
+		},
+		{
+			description:   "all good",
+			password:      "pass",
+			expVerifyHash: []byte("pass"),
+			expHash:       []byte("pass"),
+			expError:      nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			username := "abc"
+			var (
+				verifiedHash []byte
+				updated      bool
+			)
+			h := &mockPassHasher{hash: func(password string) ([]byte, error) {
+				if password == dummyPassword {
+					return []byte(password), nil
+				}
+				return []byte("new " + password), nil
+			}}
+			v := &mockPassVerifier{
+				verify: func(hashedPassword []byte, password string) error {
+					verifiedHash = hashedPassword
+					assert.Equal(t, tc.password, password)
+					if string(hashedPassword) != password {
+						return mismatch
+					}
+
+					return nil
+				},
+				needsRehash: func(hashedPassword []byte) bool {
+					assert.Equal(t, []byte("pass"), hashedPassword)
+
+					return tc.needsRehash
+				},
+			}
+			cmd := &mockAuthenticatorCommands{updatePassword: func(ctx context.Context, params *UpdatePasswordParams) error {
+				updated = true
+				assert.Equal(t, "1", params.ID)
+				assert.Equal(t, []byte("pass"), params.OldHashedPassword)
+				assert.Equal(t, []byte("new pass"), params.HashedPassword)
+
+				return tc.updateErr
+			}}
+			q := &mockAuthenticatorQueries{getByUsername: func(ctx context.Context, name string) (*User, error) {
+				assert.Equal(t, username, name)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &User{ID: "1", Username: name, HashedPassword: []byte("pass"), DeletedAt: tc.deletedAt}, nil
+			}}
+
+			a := NewAuthenticator(h, v, newNoLockout(), q, cmd)
+
+			u, err := a.Authenticate(context.Background(), username, tc.password)
+			assert.Equal(t, tc.expVerifyHash, verifiedHash)
+			assert.Equal(t, tc.expUpdate, updated)
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.Equal(t, "1", u.ID)
+				assert.Equal(t, tc.expHash, u.HashedPassword)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_Lockout(t *testing.T) {
+	randomErr := errors.New("random error")
+	lockedErr := &LockedError{Until: time.Now().Add(time.Minute)}
+
+	testCases := []struct {
+		description string
+		password    string
+		checkErr    error
+		failErr     error
+		succeedErr  error
+		expVerify   bool
+		expFail     bool
+		expSucceed  bool
+		expError    error
+	}{
+		{
+			description: "locked with the right password",
+			password:    "pass",
+			checkErr:    lockedErr,
+			expError:    ErrAccountLocked,
+		},
+		{
+			description: "check error",
+			password:    "pass",
+			checkErr:    randomErr,
+			expError:    randomErr,
+		},
+		{
+			description: "wrong password",
+			password:    "wrong",
+			expVerify:   true,
+			expFail:     true,
+			expError:    ErrInvalidCredentials,
+		},
+		{
+			description: "wrong password locks",
+			password:    "wrong",
+			failErr:     lockedErr,
+			expVerify:   true,
+			expFail:     true,
+			expError:    ErrAccountLocked,
+		},
+		{
+			description: "succeed error",
+			password:    "pass",
+			succeedErr:  randomErr,
+			expVerify:   true,
+			expSucceed:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			password:    "pass",
+			expVerify:   true,
+			expSucceed:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var verified, failed, succeeded bool
+			v := &mockPassVerifier{
+				verify: func(hashedPassword []byte, password string) error {
+					verified = true
+					if string(hashedPassword) != password {
+						return ErrInvalidCredentials
+					}
+
+					return nil
+				},
+				needsRehash: func(hashedPassword []byte) bool {
+					return false
+				},
+			}
+			lockout := &mockLoginLockout{
+				check: func(ctx context.Context, userID string) error {
+					assert.Equal(t, "1", userID)
+					return tc.checkErr
+				},
+				fail: func(ctx context.Context, userID string) error {
+					failed = true
+					return tc.failErr
+				},
+				succeed: func(ctx context.Context, userID string) error {
+					succeeded = true
+					return tc.succeedErr
+				},
+			}
+			q := &mockAuthenticatorQueries{getByUsername: func(ctx context.Context, name string) (*User, error) {
+				return &User{ID: "1", Username: name, HashedPassword: []byte("pass")}, nil
+			}}
+
+			a := NewAuthenticator(&mockPassHasher{}, v, lockout, q, &mockAuthenticatorCommands{})
+
+			u, err := a.Authenticate(context.Background(), "abc", tc.password)
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.Equal(t, "1", u.ID)
+			}
+			assert.Equal(t, tc.expVerify, verified)
+			assert.Equal(t, tc.expFail, failed)
+			assert.Equal(t, tc.expSucceed, succeeded)
+		})
+	}
+}
+
+type mockLoginLockout struct {
+	check   func(ctx context.Context, userID string) error
+	fail    func(ctx context.Context, userID string) error
+	succeed func(ctx context.Context, userID string) error
+}
+
+// newNoLockout never locks anybody out.
+func newNoLockout() *mockLoginLockout {
+	noop := func(ctx context.Context, userID string) error { return nil }
+
+	return &mockLoginLockout{check: noop, fail: noop, succeed: noop}
+}
+
+func (m *mockLoginLockout) Check(ctx context.Context, userID string) error {
+	return m.check(ctx, userID)
+}
+
+func (m *mockLoginLockout) Fail(ctx context.Context, userID string) error {
+	return m.fail(ctx, userID)
+}
+
+func (m *mockLoginLockout) Succeed(ctx context.Context, userID string) error {
+	return m.succeed(ctx, userID)
+}
+
+type mockPassVerifier struct {
+	verify      func(hashedPassword []byte, password string) error
+	needsRehash func(hashedPassword []byte) bool
+}
+
+func (m *mockPassVerifier) Verify(hashedPassword []byte, password string) error {
+	return m.verify(hashedPassword, password)
+}
+
+func (m *mockPassVerifier) NeedsRehash(hashedPassword []byte) bool {
+	return m.needsRehash(hashedPassword)
+}
+
+type mockAuthenticatorQueries struct {
+	getByUsername func(ctx context.Context, username string) (*User, error)
+}
+
+func (m *mockAuthenticatorQueries) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return m.getByUsername(ctx, username)
+}
+
+type mockAuthenticatorCommands struct {
+	updatePassword func(ctx context.Context, params *UpdatePasswordParams) error
+}
+
+func (m *mockAuthenticatorCommands) UpdatePassword(ctx context.Context, params *UpdatePasswordParams) error {
+	return m.updatePassword(ctx, params)
+}
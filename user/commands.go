@@ -4,6 +4,10 @@ import "context"
 
 type Commands interface {
 	Insert
+	Update
+	Delete
+	Restore
+	UpdatePassword
 }
 
 type InsertParams struct {
@@ -13,6 +17,57 @@ type InsertParams struct {
 	Role           string
 }
 
+// Insert stores a new user. It must fail atomically with ErrIDAlreadyExists or
+// ErrUsernameAlreadyExists, possibly wrapped, when the ID or the username is
+// already taken. Stores that keep custom roles must also fail atomically with
+// ErrInvalidRole, possibly wrapped, when the role is neither built in nor
+// stored, so that it cannot be deleted between the check and the insert.
 type Insert interface {
 	Insert(ctx context.Context, params *InsertParams) error
 }
+
+// UpdateParams holds the fields to change of the user with the given ID. Nil
+// fields are left untouched.
+type UpdateParams struct {
+	ID       string
+	Username *string
+	Role     *string
+}
+
+// Update changes the fields of an existing user. It returns ErrDoesNotExist
+// when there is no user with the ID, or when it is soft-deleted,
+// ErrUsernameAlreadyExists, possibly wrapped, when the new username is taken by
+// another user, and ErrInvalidRole, possibly wrapped, when the new role does not
+// exist, checked atomically like in Insert.
+type Update interface {
+	Update(ctx context.Context, params *UpdateParams) error
+}
+
+// UpdatePasswordParams holds the new hashed password of the user with the
+// given ID. When OldHashedPassword is not nil the password is only replaced if
+// it is still OldHashedPassword.
+type UpdatePasswordParams struct {
+	ID                string
+	OldHashedPassword []byte
+	HashedPassword    []byte
+}
+
+// UpdatePassword replaces the hashed password of an existing user. It returns
+// ErrDoesNotExist when there is no user with the ID, or when it is
+// soft-deleted, and ErrPasswordChanged when the current password is not
+// OldHashedPassword.
+type UpdatePassword interface {
+	UpdatePassword(ctx context.Context, params *UpdatePasswordParams) error
+}
+
+// Delete soft-deletes a user by setting its DeletedAt. It returns
+// ErrDoesNotExist when there is no user with the ID or it is already deleted.
+type Delete interface {
+	Delete(ctx context.Context, id string) error
+}
+
+// Restore clears the DeletedAt of a soft-deleted user. It returns
+// ErrDoesNotExist when there is no soft-deleted user with the ID.
+type Restore interface {
+	Restore(ctx context.Context, id string) error
+}
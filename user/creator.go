@@ -10,25 +10,33 @@ import (
 )
 
 var (
-	ErrInvalidUsername       = errors.New("invalid username")
-	ErrInvalidRole           = errors.New("invalid role. Valid roles are user and admin")
-	ErrUsernameAlreadyExists = errors.New("this username is already in use")
-	ErrIDAlreadyExists       = errors.New("this ID is already in use")
+	ErrInvalidUsername = errors.New("invalid username")
+	ErrInvalidRole     = errors.New("invalid role")
+
+	ErrUsernameAlreadyExists error = &ConflictError{msg: "this username is already in use"}
+	ErrIDAlreadyExists       error = &ConflictError{msg: "this ID is already in use"}
 )
 
+// ConflictError is the type of the errors returned when a write clashes with
+// the unique ID or username of an existing user.
+type ConflictError struct {
+	msg string
+}
+
+func (e *ConflictError) Error() string {
+	return e.msg
+}
+
+// PasswordValidator checks that password is acceptable for the user with the
+// given username.
 type PasswordValidator interface {
-	Validate(password string) error
+	Validate(username, password string) error
 }
 
 type PasswordHasher interface {
 	Hash(password string) ([]byte, error)
 }
 
-type CreatorQueries interface {
-	GetByID
-	GetByUsername
-}
-
 type CreatorCommands interface {
 	Insert
 }
@@ -36,15 +44,15 @@ type CreatorCommands interface {
 type Creator struct {
 	validator PasswordValidator
 	hasher    PasswordHasher
-	q         CreatorQueries
+	roles     RoleChecker
 	cmd       CreatorCommands
 }
 
-func NewCreator(v PasswordValidator, h PasswordHasher, q CreatorQueries, cmd CreatorCommands) *Creator {
+func NewCreator(v PasswordValidator, h PasswordHasher, roles RoleChecker, cmd CreatorCommands) *Creator {
 	return &Creator{
 		validator: v,
 		hasher:    h,
-		q:         q,
+		roles:     roles,
 		cmd:       cmd,
 	}
 }
@@ -57,10 +65,16 @@ type CreateParams struct {
 }
 
 func (c *Creator) Create(ctx context.Context, params CreateParams) (*User, error) {
-	if err := c.checkCreateParams(ctx, params); err != nil {
+	if err := c.checkCreateParams(params); err != nil {
 		return nil, err
 	}
 
+	if params.Role != "" {
+		if err := checkRole(ctx, c.roles, params.Role); err != nil {
+			return nil, err
+		}
+	}
+
 	hashedPassword, err := c.hasher.Hash(params.Password)
 	if err != nil {
 		return nil, err
@@ -82,7 +96,7 @@ func (c *Creator) Create(ctx context.Context, params CreateParams) (*User, error
 	}
 
 	if err = c.cmd.Insert(ctx, insertParams); err != nil {
-		return nil, err
+		return nil, mapConflict(err)
 	}
 
 	return &User{
@@ -95,36 +109,27 @@ func (c *Creator) Create(ctx context.Context, params CreateParams) (*User, error
 	}, nil
 }
 
-func (c *Creator) checkCreateParams(ctx context.Context, params CreateParams) error {
-	if params.ID != "" {
-		_, err := c.q.GetByID(ctx, params.ID)
-		if err == nil {
-			return ErrIDAlreadyExists
-		}
-		if err != ErrDoesNotExist {
-			return err
-		}
-	}
-
+func (c *Creator) checkCreateParams(params CreateParams) error {
 	if params.Username == "" {
 		return ErrInvalidUsername
 	}
 
-	if params.Role != "" && params.Role != RoleUser && params.Role != RoleAdmin {
-		return ErrInvalidRole
+	if err := c.validator.Validate(params.Username, params.Password); err != nil {
+		return fmt.Errorf("invalid password: %w", err)
 	}
 
-	_, err := c.q.GetByUsername(ctx, params.Username)
-	if err == nil {
+	return nil
+}
+
+// mapConflict unwraps the uniqueness conflicts reported by the storage layer
+// so callers can compare them directly.
+func mapConflict(err error) error {
+	switch {
+	case errors.Is(err, ErrIDAlreadyExists):
+		return ErrIDAlreadyExists
+	case errors.Is(err, ErrUsernameAlreadyExists):
 		return ErrUsernameAlreadyExists
-	}
-	if err != ErrDoesNotExist {
+	default:
 		return err
 	}
-
-	if err = c.validator.Validate(params.Password); err != nil {
-		return fmt.Errorf("invalid password: %w", err)
-	}
-
-	return nil
 }
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,36 +11,32 @@ import (
 
 func TestCreate_IDAlreadyExists(t *testing.T) {
 	userID := "abc"
-	q := &mockCreatorQueries{
-		getByID: func(ctx context.Context, id string) (*User, error) {
-			assert.Equal(t, userID, id)
-			return &User{}, nil
-		},
-	}
+	v := &mockPassValidator{validate: func(username, password string) error { return nil }}
+	h := &mockPassHasher{hash: func(password string) ([]byte, error) { return []byte(password), nil }}
+	cmd := &mockCreatorCMD{func(ctx context.Context, params *InsertParams) error {
+		assert.Equal(t, userID, params.ID)
+		return fmt.Errorf("insert failed: %w", ErrIDAlreadyExists)
+	}}
 
-	c := NewCreator(nil, nil, q, nil)
+	c := NewCreator(v, h, newMockRoleChecker(), cmd)
 
-	params := CreateParams{ID: userID}
+	params := CreateParams{ID: userID, Username: "abc"}
 	u, err := c.Create(context.Background(), params)
 	assert.Nil(t, u)
-	assert.ErrorIs(t, err, ErrIDAlreadyExists)
+	assert.Equal(t, ErrIDAlreadyExists, err)
 }
 
 func TestCreate_UsernameAlreadyExists(t *testing.T) {
 	userID := "1"
 	usernameExisting := "abc"
-	q := &mockCreatorQueries{
-		getByID: func(ctx context.Context, id string) (*User, error) {
-			assert.Equal(t, userID, id)
-			return nil, ErrDoesNotExist
-		},
-		getByUsername: func(ctx context.Context, username string) (*User, error) {
-			assert.Equal(t, usernameExisting, username)
-			return &User{}, nil
-		},
-	}
+	v := &mockPassValidator{validate: func(username, password string) error { return nil }}
+	h := &mockPassHasher{hash: func(password string) ([]byte, error) { return []byte(password), nil }}
+	cmd := &mockCreatorCMD{func(ctx context.Context, params *InsertParams) error {
+		assert.Equal(t, usernameExisting, params.Username)
+		return fmt.Errorf("insert failed: %w", ErrUsernameAlreadyExists)
+	}}
 
-	c := NewCreator(nil, nil, q, nil)
+	c := NewCreator(v, h, newMockRoleChecker(), cmd)
 
 	params := CreateParams{
 		ID:       userID,
@@ -48,7 +45,10 @@ func TestCreate_UsernameAlreadyExists(t *testing.T) {
 	}
 	u, err := c.Create(context.Background(), params)
 	assert.Nil(t, u)
-	assert.ErrorIs(t, err, ErrUsernameAlreadyExists)
+	assert.Equal(t, ErrUsernameAlreadyExists, err)
+
+	var conflict *ConflictError
+	assert.ErrorAs(t, err, &conflict)
 }
 
 func TestCreate(t *testing.T) {
@@ -98,6 +98,15 @@ func TestCreate(t *testing.T) {
 			errPV:       nil,
 			expError:    ErrInvalidRole,
 		},
+		{
+			description: "role check error",
+			id:          "1",
+			username:    "abc",
+			password:    "aa",
+			role:        "broken",
+			errPV:       nil,
+			expError:    errRoleCheck,
+		},
 		{
 			description: "all good",
 			id:          "1",
@@ -107,6 +116,15 @@ func TestCreate(t *testing.T) {
 			errPV:       nil,
 			expError:    nil,
 		},
+		{
+			description: "all good with custom role",
+			id:          "1",
+			username:    "abc",
+			password:    "aa",
+			role:        "auditor",
+			errPV:       nil,
+			expError:    nil,
+		},
 		{
 			description: "all good with no id",
 			id:          "",
@@ -130,7 +148,8 @@ func TestCreate(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
 
-			v := &mockPassValidator{validate: func(password string) error {
+			v := &mockPassValidator{validate: func(username, password string) error {
+				assert.Equal(t, tc.username, username)
 				assert.Equal(t, tc.password, password)
 
 				return tc.errPV
@@ -142,19 +161,6 @@ func TestCreate(t *testing.T) {
 				return []byte(password), nil
 			}}
 
-			q := &mockCreatorQueries{
-				getByID: func(ctx context.Context, id string) (*User, error) {
-					assert.Equal(t, tc.id, id)
-
-					return nil, ErrDoesNotExist
-				},
-				getByUsername: func(ctx context.Context, username string) (*User, error) {
-					assert.Equal(t, tc.username, username)
-
-					return nil, ErrDoesNotExist
-				},
-			}
-
 			cmd := &mockCreatorCMD{func(ctx context.Context, params *InsertParams) error {
 				assert.Equal(t, tc.username, params.Username)
 				assert.Equal(t, []byte(tc.password), params.HashedPassword)
@@ -162,7 +168,7 @@ func TestCreate(t *testing.T) {
 				return nil
 			}}
 
-			c := NewCreator(v, h, q, cmd)
+			c := NewCreator(v, h, newMockRoleChecker(), cmd)
 
 			params := CreateParams{
 				ID:       tc.id,
@@ -176,33 +182,45 @@ func TestCreate(t *testing.T) {
 	}
 }
 
-type mockPassValidator struct {
-	validate func(password string) error
+var errRoleCheck = errors.New("role check failed")
+
+type mockRoleChecker struct {
+	roleExists func(ctx context.Context, name string) (bool, error)
 }
 
-func (m *mockPassValidator) Validate(password string) error {
-	return m.validate(password)
+// newMockRoleChecker knows the built-in roles and an "auditor" custom role,
+// and fails for the "broken" role.
+func newMockRoleChecker() *mockRoleChecker {
+	return &mockRoleChecker{roleExists: func(ctx context.Context, name string) (bool, error) {
+		switch name {
+		case RoleUser, RoleAdmin, "auditor":
+			return true, nil
+		case "broken":
+			return false, errRoleCheck
+		default:
+			return false, nil
+		}
+	}}
 }
 
-type mockPassHasher struct {
-	hash func(password string) ([]byte, error)
+func (m *mockRoleChecker) RoleExists(ctx context.Context, name string) (bool, error) {
+	return m.roleExists(ctx, name)
 }
 
-func (m *mockPassHasher) Hash(password string) ([]byte, error) {
-	return m.hash(password)
+type mockPassValidator struct {
+	validate func(username, password string) error
 }
 
-type mockCreatorQueries struct {
-	getByID       func(ctx context.Context, id string) (*User, error)
-	getByUsername func(ctx context.Context, username string) (*User, error)
+func (m *mockPassValidator) Validate(username, password string) error {
+	return m.validate(username, password)
 }
 
-func (m *mockCreatorQueries) GetByID(ctx context.Context, id string) (*User, error) {
-	return m.getByID(ctx, id)
+type mockPassHasher struct {
+	hash func(password string) ([]byte, error)
 }
 
-func (m *mockCreatorQueries) GetByUsername(ctx context.Context, username string) (*User, error) {
-	return m.getByUsername(ctx, username)
+func (m *mockPassHasher) Hash(password string) ([]byte, error) {
+	return m.hash(password)
 }
 
 type mockCreatorCMD struct {
@@ -0,0 +1,53 @@
+package user
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotDeleted = errors.New("user is not deleted")
+)
+
+type DeleterQueries interface {
+	GetByID
+	GetByIDIncludingDeleted
+}
+
+type DeleterCommands interface {
+	Delete
+	Restore
+}
+
+type Deleter struct {
+	q   DeleterQueries
+	cmd DeleterCommands
+}
+
+func NewDeleter(q DeleterQueries, cmd DeleterCommands) *Deleter {
+	return &Deleter{
+		q:   q,
+		cmd: cmd,
+	}
+}
+
+func (d *Deleter) Delete(ctx context.Context, id string) error {
+	return d.cmd.Delete(ctx, id)
+}
+
+func (d *Deleter) Restore(ctx context.Context, id string) (*User, error) {
+	u, err := d.q.GetByIDIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.DeletedAt == nil {
+		return nil, ErrNotDeleted
+	}
+
+	if err = d.cmd.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return d.q.GetByID(ctx, id)
+}
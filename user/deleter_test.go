@@ -0,0 +1,123 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelete(t *testing.T) {
+	userID := "1"
+	cmd := &mockDeleterCMD{delete: func(ctx context.Context, id string) error {
+		assert.Equal(t, userID, id)
+
+		return ErrDoesNotExist
+	}}
+
+	d := NewDeleter(nil, cmd)
+
+	err := d.Delete(context.Background(), userID)
+	assert.ErrorIs(t, err, ErrDoesNotExist)
+}
+
+func TestRestore(t *testing.T) {
+	deletedAt := time.Now()
+	randomErr := errors.New("random error")
+	testCases := []struct {
+		description string
+		getErr      error
+		deletedAt   *time.Time
+		restoreErr  error
+		expRestore  bool
+		expError    error
+	}{
+		{
+			description: "does not exist",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "not deleted",
+			deletedAt:   nil,
+			expError:    ErrNotDeleted,
+		},
+		{
+			description: "restore error",
+			deletedAt:   &deletedAt,
+			restoreErr:  randomErr,
+			expRestore:  true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			deletedAt:   &deletedAt,
+			expRestore:  true,
+			expError:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "1"
+			restored := false
+			q := &mockDeleterQueries{
+				getByID: func(ctx context.Context, id string) (*User, error) {
+					return &User{ID: id}, nil
+				},
+				getByIDIncludingDeleted: func(ctx context.Context, id string) (*User, error) {
+					assert.Equal(t, userID, id)
+					if tc.getErr != nil {
+						return nil, tc.getErr
+					}
+
+					return &User{ID: id, DeletedAt: tc.deletedAt}, nil
+				},
+			}
+			cmd := &mockDeleterCMD{restore: func(ctx context.Context, id string) error {
+				restored = true
+				assert.Equal(t, userID, id)
+
+				return tc.restoreErr
+			}}
+
+			d := NewDeleter(q, cmd)
+
+			u, err := d.Restore(context.Background(), userID)
+			assert.Equal(t, tc.expRestore, restored)
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.Equal(t, userID, u.ID)
+				assert.Nil(t, u.DeletedAt)
+			}
+		})
+	}
+}
+
+type mockDeleterQueries struct {
+	getByID                 func(ctx context.Context, id string) (*User, error)
+	getByIDIncludingDeleted func(ctx context.Context, id string) (*User, error)
+}
+
+func (m *mockDeleterQueries) GetByID(ctx context.Context, id string) (*User, error) {
+	return m.getByID(ctx, id)
+}
+
+func (m *mockDeleterQueries) GetByIDIncludingDeleted(ctx context.Context, id string) (*User, error) {
+	return m.getByIDIncludingDeleted(ctx, id)
+}
+
+type mockDeleterCMD struct {
+	delete  func(ctx context.Context, id string) error
+	restore func(ctx context.Context, id string) error
+}
+
+func (m *mockDeleterCMD) Delete(ctx context.Context, id string) error {
+	return m.delete(ctx, id)
+}
+
+func (m *mockDeleterCMD) Restore(ctx context.Context, id string) error {
+	return m.restore(ctx, id)
+}
@@ -0,0 +1,152 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var (
+	ErrInvalidLimit         = errors.New("invalid limit")
+	ErrInvalidSort          = errors.New("invalid sort. Valid sorts are created_at and username")
+	ErrInvalidDeletedFilter = errors.New("invalid deleted filter. Valid filters are exclude, include and only")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+)
+
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	SortUsername  SortField = "username"
+)
+
+type DeletedFilter string
+
+const (
+	DeletedExclude DeletedFilter = "exclude"
+	DeletedInclude DeletedFilter = "include"
+	DeletedOnly    DeletedFilter = "only"
+)
+
+// ListParams filters and paginates users. Zero values disable a filter.
+// CreatedFrom is inclusive and CreatedTo is exclusive.
+type ListParams struct {
+	Role           string
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	Deleted        DeletedFilter
+	UsernamePrefix string
+	Sort           SortField
+	Descending     bool
+	Limit          int
+	Cursor         string
+}
+
+type ListResult struct {
+	Users      []*User
+	NextCursor string
+}
+
+// ListCursor is the position of a user in a listing. It is handed to clients
+// as an opaque string.
+type ListCursor struct {
+	Sort       SortField `json:"s"`
+	Descending bool      `json:"d"`
+	ID         string    `json:"i"`
+	Username   string    `json:"u"`
+	CreatedAt  time.Time `json:"c"`
+}
+
+// EncodeListCursor returns the cursor pointing right after u in a listing
+// sorted as params.
+func EncodeListCursor(u *User, params *ListParams) string {
+	buff, _ := json.Marshal(&ListCursor{
+		Sort:       params.Sort,
+		Descending: params.Descending,
+		ID:         u.ID,
+		Username:   u.Username,
+		CreatedAt:  u.CreatedAt,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+// DecodeListCursor returns the cursor of params, or nil when the listing
+// starts from the beginning. The cursor must come from a listing with the same
+// sort.
+func DecodeListCursor(params *ListParams) (*ListCursor, error) {
+	if params.Cursor == "" {
+		return nil, nil
+	}
+
+	buff, err := base64.RawURLEncoding.DecodeString(params.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c ListCursor
+	if err = json.Unmarshal(buff, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != params.Sort || c.Descending != params.Descending || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+type ListerQueries interface {
+	List
+}
+
+type Lister struct {
+	q ListerQueries
+}
+
+func NewLister(q ListerQueries) *Lister {
+	return &Lister{q: q}
+}
+
+func (l *Lister) List(ctx context.Context, params ListParams) (*ListResult, error) {
+	if params.Limit == 0 {
+		params.Limit = DefaultListLimit
+	}
+	if params.Sort == "" {
+		params.Sort = SortCreatedAt
+	}
+	if params.Deleted == "" {
+		params.Deleted = DeletedExclude
+	}
+
+	if err := checkListParams(&params); err != nil {
+		return nil, err
+	}
+
+	return l.q.List(ctx, &params)
+}
+
+func checkListParams(params *ListParams) error {
+	if params.Limit < 0 || params.Limit > MaxListLimit {
+		return ErrInvalidLimit
+	}
+
+	if params.Sort != SortCreatedAt && params.Sort != SortUsername {
+		return ErrInvalidSort
+	}
+
+	if params.Deleted != DeletedExclude && params.Deleted != DeletedInclude && params.Deleted != DeletedOnly {
+		return ErrInvalidDeletedFilter
+	}
+
+	_, err := DecodeListCursor(params)
+
+	return err
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	cursor := EncodeListCursor(&User{ID: "1", Username: "abc"}, &ListParams{Sort: SortUsername})
+
+	testCases := []struct {
+		description string
+		params      ListParams
+		expParams   *ListParams
+		expError    error
+	}{
+		{
+			description: "defaults",
+			params:      ListParams{},
+			expParams:   &ListParams{Sort: SortCreatedAt, Deleted: DeletedExclude, Limit: DefaultListLimit},
+		},
+		{
+			description: "limit too big",
+			params:      ListParams{Limit: MaxListLimit + 1},
+			expError:    ErrInvalidLimit,
+		},
+		{
+			description: "negative limit",
+			params:      ListParams{Limit: -1},
+			expError:    ErrInvalidLimit,
+		},
+		{
+			description: "invalid sort",
+			params:      ListParams{Sort: "role"},
+			expError:    ErrInvalidSort,
+		},
+		{
+			description: "invalid deleted filter",
+			params:      ListParams{Deleted: "all"},
+			expError:    ErrInvalidDeletedFilter,
+		},
+		{
+			description: "malformed cursor",
+			params:      ListParams{Cursor: "%%%"},
+			expError:    ErrInvalidCursor,
+		},
+		{
+			description: "cursor of another sort",
+			params:      ListParams{Sort: SortCreatedAt, Cursor: cursor},
+			expError:    ErrInvalidCursor,
+		},
+		{
+			description: "cursor of another order",
+			params:      ListParams{Sort: SortUsername, Descending: true, Cursor: cursor},
+			expError:    ErrInvalidCursor,
+		},
+		{
+			description: "all good",
+			params:      ListParams{Sort: SortUsername, Deleted: DeletedOnly, Limit: 5, Cursor: cursor},
+			expParams:   &ListParams{Sort: SortUsername, Deleted: DeletedOnly, Limit: 5, Cursor: cursor},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &mockListerQueries{list: func(ctx context.Context, params *ListParams) (*ListResult, error) {
+				assert.Equal(t, tc.expParams, params)
+
+				return &ListResult{}, nil
+			}}
+
+			l := NewLister(q)
+
+			_, err := l.List(context.Background(), tc.params)
+			assert.ErrorIs(t, err, tc.expError)
+		})
+	}
+}
+
+func TestDecodeListCursor(t *testing.T) {
+	u := &User{ID: "1", Username: "abc"}
+	params := &ListParams{Sort: SortUsername, Descending: true}
+	params.Cursor = EncodeListCursor(u, params)
+
+	c, err := DecodeListCursor(params)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", c.ID)
+	assert.Equal(t, "abc", c.Username)
+
+	c, err = DecodeListCursor(&ListParams{})
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+type mockListerQueries struct {
+	list func(ctx context.Context, params *ListParams) (*ListResult, error)
+}
+
+func (m *mockListerQueries) List(ctx context.Context, params *ListParams) (*ListResult, error) {
+	return m.list(ctx, params)
+}
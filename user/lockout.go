@@ -0,0 +1,198 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAccountLocked = errors.New("account is locked after too many failed logins")
+)
+
+// Lockout is the login lockout state of a user. Failures counts the failed
+// logins since the last lock or successful login, and Lockouts the locks
+// since the last successful login or unlock.
+type Lockout struct {
+	UserID      string
+	Failures    int
+	Lockouts    int
+	LockedUntil *time.Time
+}
+
+// Locked reports whether the user cannot log in at now.
+func (l *Lockout) Locked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
+
+// LockedError is returned for users that are locked out. It matches
+// ErrAccountLocked.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s: try again after %s", ErrAccountLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// GetLockout returns the lockout state of a user, which is empty for users
+// that never failed to log in.
+type GetLockout interface {
+	GetLockout(ctx context.Context, userID string) (*Lockout, error)
+}
+
+// LoginFailureParams holds a failed login of the user at Now, and when it locks
+// the user out.
+type LoginFailureParams struct {
+	UserID    string
+	Now       time.Time
+	Threshold int
+	// LockFor returns how long the user is locked for after lockouts previous
+	// locks.
+	LockFor func(lockouts int) time.Duration
+}
+
+// AddLoginFailure counts a failed login of the user and returns the resulting
+// state. Failures of a user that is locked at Now are not counted. Once the
+// failures reach Threshold the user is locked until Now plus LockFor of its
+// previous locks, the lock is counted and the failures are reset. Counting and
+// locking must happen in a single step, so that concurrent failures lock the
+// user only once.
+type AddLoginFailure interface {
+	AddLoginFailure(ctx context.Context, params *LoginFailureParams) (*Lockout, error)
+}
+
+// ResetLockout forgets the failures and locks of the user.
+type ResetLockout interface {
+	ResetLockout(ctx context.Context, userID string) error
+}
+
+type LockoutQueries interface {
+	GetLockout
+}
+
+type LockoutCommands interface {
+	AddLoginFailure
+	ResetLockout
+}
+
+// LockoutPolicy locks users out for Duration after Threshold consecutive
+// failed logins. Every further lock before a successful login lasts twice as
+// long as the previous one, up to MaxDuration. A zero Threshold disables
+// lockouts.
+type LockoutPolicy struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	Threshold:   5,
+	Duration:    time.Minute,
+	MaxDuration: time.Hour,
+}
+
+// duration returns how long the user is locked for after lockouts previous
+// locks.
+func (p LockoutPolicy) duration(lockouts int) time.Duration {
+	d := p.Duration
+	for i := 0; i < lockouts && d < p.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > p.MaxDuration {
+		d = p.MaxDuration
+	}
+
+	return d
+}
+
+// Lockouts protects accounts against brute force by locking them after
+// repeated failed logins.
+type Lockouts struct {
+	policy LockoutPolicy
+	q      LockoutQueries
+	cmd    LockoutCommands
+}
+
+func NewLockouts(policy LockoutPolicy, q LockoutQueries, cmd LockoutCommands) *Lockouts {
+	if policy.Duration <= 0 {
+		policy.Duration = DefaultLockoutPolicy.Duration
+	}
+	if policy.MaxDuration < policy.Duration {
+		policy.MaxDuration = policy.Duration
+	}
+
+	return &Lockouts{
+		policy: policy,
+		q:      q,
+		cmd:    cmd,
+	}
+}
+
+// Get returns the lockout state of the user.
+func (l *Lockouts) Get(ctx context.Context, userID string) (*Lockout, error) {
+	return l.q.GetLockout(ctx, userID)
+}
+
+// Check returns a *LockedError when the user is locked out.
+func (l *Lockouts) Check(ctx context.Context, userID string) error {
+	if l.policy.Threshold <= 0 {
+		return nil
+	}
+
+	state, err := l.q.GetLockout(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if state.Locked(time.Now()) {
+		return &LockedError{Until: *state.LockedUntil}
+	}
+
+	return nil
+}
+
+// Fail counts a failed login of the user. It returns a *LockedError when the
+// user is locked out, by this failure or by a concurrent one.
+func (l *Lockouts) Fail(ctx context.Context, userID string) error {
+	if l.policy.Threshold <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	state, err := l.cmd.AddLoginFailure(ctx, &LoginFailureParams{
+		UserID:    userID,
+		Now:       now,
+		Threshold: l.policy.Threshold,
+		LockFor:   l.policy.duration,
+	})
+	if err != nil {
+		return err
+	}
+
+	if state.Locked(now) {
+		return &LockedError{Until: *state.LockedUntil}
+	}
+
+	return nil
+}
+
+// Succeed forgets the failures and locks of the user after a successful
+// login.
+func (l *Lockouts) Succeed(ctx context.Context, userID string) error {
+	if l.policy.Threshold <= 0 {
+		return nil
+	}
+
+	return l.cmd.ResetLockout(ctx, userID)
+}
+
+// Unlock lets a locked out user log in again.
+func (l *Lockouts) Unlock(ctx context.Context, userID string) error {
+	return l.cmd.ResetLockout(ctx, userID)
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutPolicy_Duration(t *testing.T) {
+	p := LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: 5 * time.Minute}
+
+	assert.Equal(t, time.Minute, p.duration(0))
+	assert.Equal(t, 2*time.Minute, p.duration(1))
+	assert.Equal(t, 4*time.Minute, p.duration(2))
+	assert.Equal(t, 5*time.Minute, p.duration(3))
+	assert.Equal(t, 5*time.Minute, p.duration(100))
+}
+
+func TestLockouts_Check(t *testing.T) {
+	randomErr := errors.New("random error")
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		description string
+		threshold   int
+		state       *Lockout
+		getErr      error
+		expError    error
+	}{
+		{
+			description: "disabled",
+			threshold:   0,
+			state:       &Lockout{LockedUntil: &future},
+		},
+		{
+			description: "never failed",
+			threshold:   3,
+			state:       &Lockout{},
+		},
+		{
+			description: "lock expired",
+			threshold:   3,
+			state:       &Lockout{Lockouts: 1, LockedUntil: &past},
+		},
+		{
+			description: "locked",
+			threshold:   3,
+			state:       &Lockout{Lockouts: 1, LockedUntil: &future},
+			expError:    ErrAccountLocked,
+		},
+		{
+			description: "random error",
+			threshold:   3,
+			getErr:      randomErr,
+			expError:    randomErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &mockLockoutQueries{getLockout: func(ctx context.Context, userID string) (*Lockout, error) {
+				assert.Equal(t, "1", userID)
+				return tc.state, tc.getErr
+			}}
+
+			l := NewLockouts(LockoutPolicy{Threshold: tc.threshold}, q, nil)
+
+			err := l.Check(context.Background(), "1")
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+
+			var locked *LockedError
+			if errors.As(err, &locked) {
+				assert.Equal(t, future, locked.Until)
+			}
+		})
+	}
+}
+
+func TestLockouts_Fail(t *testing.T) {
+	randomErr := errors.New("random error")
+	future := time.Now().Add(time.Minute)
+	past := time.Now().Add(-time.Minute)
+
+	testCases := []struct {
+		description string
+		state       *Lockout
+		addErr      error
+		expError    error
+	}{
+		{
+			description: "below the threshold",
+			state:       &Lockout{Failures: 2},
+		},
+		{
+			description: "expired lock",
+			state:       &Lockout{Failures: 1, Lockouts: 1, LockedUntil: &past},
+		},
+		{
+			description: "locked",
+			state:       &Lockout{Lockouts: 1, LockedUntil: &future},
+			expError:    ErrAccountLocked,
+		},
+		{
+			description: "add error",
+			addErr:      randomErr,
+			expError:    randomErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			cmd := &mockLockoutCMD{addLoginFailure: func(ctx context.Context, params *LoginFailureParams) (*Lockout, error) {
+				assert.Equal(t, "1", params.UserID)
+				assert.Equal(t, 3, params.Threshold)
+				assert.WithinDuration(t, time.Now(), params.Now, time.Second)
+				assert.Equal(t, 4*time.Minute, params.LockFor(2))
+
+				return tc.state, tc.addErr
+			}}
+
+			l := NewLockouts(LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: time.Hour}, nil, cmd)
+
+			err := l.Fail(context.Background(), "1")
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+
+			var locked *LockedError
+			if errors.As(err, &locked) {
+				assert.Equal(t, future, locked.Until)
+			}
+		})
+	}
+}
+
+func TestLockouts_Disabled(t *testing.T) {
+	l := NewLockouts(LockoutPolicy{}, nil, nil)
+
+	assert.NoError(t, l.Check(context.Background(), "1"))
+	assert.NoError(t, l.Fail(context.Background(), "1"))
+	assert.NoError(t, l.Succeed(context.Background(), "1"))
+}
+
+type mockLockoutQueries struct {
+	getLockout func(ctx context.Context, userID string) (*Lockout, error)
+}
+
+func (m *mockLockoutQueries) GetLockout(ctx context.Context, userID string) (*Lockout, error) {
+	return m.getLockout(ctx, userID)
+}
+
+type mockLockoutCMD struct {
+	addLoginFailure func(ctx context.Context, params *LoginFailureParams) (*Lockout, error)
+	resetLockout    func(ctx context.Context, userID string) error
+}
+
+func (m *mockLockoutCMD) AddLoginFailure(ctx context.Context, params *LoginFailureParams) (*Lockout, error) {
+	return m.addLoginFailure(ctx, params)
+}
+
+func (m *mockLockoutCMD) ResetLockout(ctx context.Context, userID string) error {
+	return m.resetLockout(ctx, userID)
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("the current password is incorrect")
+)
+
+// RecentPasswords keeps users from reusing their recent passwords. It is
+// implemented by PasswordHistory.
+type RecentPasswords interface {
+	Check(ctx context.Context, u *User, password string) error
+	Record(ctx context.Context, userID string, oldHashedPassword []byte) error
+}
+
+// CredentialRevoker ends every session of the user but keepSessionID and
+// every refresh token of the user, so a changed password logs out whoever
+// knew the old one.
+type CredentialRevoker interface {
+	RevokeCredentials(ctx context.Context, userID, keepSessionID string) error
+}
+
+type PasswordChangerQueries interface {
+	GetByID
+}
+
+type PasswordChangerCommands interface {
+	UpdatePassword
+}
+
+// ChangePasswordParams holds the new password of the user with the given ID.
+// KeepSessionID is the session that stays logged in, if any.
+type ChangePasswordParams struct {
+	ID              string
+	CurrentPassword string
+	NewPassword     string
+	KeepSessionID   string
+}
+
+type PasswordChanger struct {
+	validator PasswordValidator
+	hasher    PasswordHasher
+	verifier  PasswordVerifier
+	recent    RecentPasswords
+	revoker   CredentialRevoker
+	lockout   LoginLockout
+	q         PasswordChangerQueries
+	cmd       PasswordChangerCommands
+}
+
+func NewPasswordChanger(v PasswordValidator, h PasswordHasher, pv PasswordVerifier, recent RecentPasswords, revoker CredentialRevoker, lockout LoginLockout, q PasswordChangerQueries, cmd PasswordChangerCommands) *PasswordChanger {
+	return &PasswordChanger{
+		validator: v,
+		hasher:    h,
+		verifier:  pv,
+		recent:    recent,
+		revoker:   revoker,
+		lockout:   lockout,
+		q:         q,
+		cmd:       cmd,
+	}
+}
+
+// Change replaces the password of the user after checking the current one.
+// It returns ErrIncorrectPassword when the current password does not match,
+// which counts as a failed login, a *LockedError when the user is locked out,
+// the error of the validator when the new one is rejected, ErrPasswordReused
+// when it was used recently and ErrPasswordChanged when the password changed
+// concurrently. Other credentials of the user are revoked once the password
+// is replaced, so errors doing so are returned with the new password already
+// in place.
+func (c *PasswordChanger) Change(ctx context.Context, params ChangePasswordParams) error {
+	u, err := c.q.GetByID(ctx, params.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = c.lockout.Check(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if err = c.verifier.Verify(u.HashedPassword, params.CurrentPassword); err != nil {
+		if err = c.lockout.Fail(ctx, u.ID); err != nil {
+			return err
+		}
+		return ErrIncorrectPassword
+	}
+
+	if err = c.lockout.Succeed(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if err = c.validator.Validate(u.Username, params.NewPassword); err != nil {
+		return err
+	}
+
+	if err = c.recent.Check(ctx, u, params.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := c.hasher.Hash(params.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = c.cmd.UpdatePassword(ctx, &UpdatePasswordParams{
+		ID:                u.ID,
+		OldHashedPassword: u.HashedPassword,
+		HashedPassword:    hashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.revoker.RevokeCredentials(ctx, u.ID, params.KeepSessionID); err != nil {
+		return err
+	}
+
+	return c.recent.Record(ctx, u.ID, u.HashedPassword)
+}
@@ -0,0 +1,251 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePassword(t *testing.T) {
+	randomErr := errors.New("random error")
+	weakErr := &PasswordPolicyError{Violations: []PasswordViolation{{Rule: RuleMinLength}}}
+	lockedErr := &LockedError{Until: time.Now().Add(time.Minute)}
+
+	testCases := []struct {
+		description     string
+		getErr          error
+		currentPassword string
+		lockedErr       error
+		failErr         error
+		succeedErr      error
+		validateErr     error
+		checkErr        error
+		updateErr       error
+		revokeErr       error
+		recordErr       error
+		expFail         bool
+		expSucceed      bool
+		expUpdate       bool
+		expRevoke       bool
+		expRecord       bool
+		expError        error
+	}{
+		{
+			description:     "unknown user",
+			getErr:          ErrDoesNotExist,
+			currentPassword: "old",
+			expError:        ErrDoesNotExist,
+		},
+		{
+			description:     "locked out even with the right password",
+			currentPassword: "old",
+			lockedErr:       lockedErr,
+			expError:        ErrAccountLocked,
+		},
+		{
+			description:     "incorrect current password",
+			currentPassword: "wrong",
+			expFail:         true,
+			expError:        ErrIncorrectPassword,
+		},
+		{
+			description:     "incorrect current password locks out",
+			currentPassword: "wrong",
+			failErr:         lockedErr,
+			expFail:         true,
+			expError:        ErrAccountLocked,
+		},
+		{
+			description:     "succeed error",
+			currentPassword: "old",
+			succeedErr:      randomErr,
+			expSucceed:      true,
+			expError:        randomErr,
+		},
+		{
+			description:     "weak password",
+			currentPassword: "old",
+			validateErr:     weakErr,
+			expSucceed:      true,
+			expError:        ErrWeakPassword,
+		},
+		{
+			description:     "reused password",
+			currentPassword: "old",
+			checkErr:        ErrPasswordReused,
+			expSucceed:      true,
+			expError:        ErrPasswordReused,
+		},
+		{
+			description:     "changed concurrently",
+			currentPassword: "old",
+			updateErr:       ErrPasswordChanged,
+			expSucceed:      true,
+			expUpdate:       true,
+			expError:        ErrPasswordChanged,
+		},
+		{
+			description:     "revoke error",
+			currentPassword: "old",
+			revokeErr:       randomErr,
+			expSucceed:      true,
+			expUpdate:       true,
+			expRevoke:       true,
+			expError:        randomErr,
+		},
+		{
+			description:     "record error",
+			currentPassword: "old",
+			recordErr:       randomErr,
+			expSucceed:      true,
+			expUpdate:       true,
+			expRevoke:       true,
+			expRecord:       true,
+			expError:        randomErr,
+		},
+		{
+			description:     "all good",
+			currentPassword: "old",
+			expSucceed:      true,
+			expUpdate:       true,
+			expRevoke:       true,
+			expRecord:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var failed, succeeded, updated, revoked, recorded bool
+
+			q := &mockPasswordChangerQueries{getByID: func(ctx context.Context, id string) (*User, error) {
+				assert.Equal(t, "1", id)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &User{ID: "1", Username: "abc", HashedPassword: []byte("old")}, nil
+			}}
+			pv := &mockPassVerifier{verify: func(hashedPassword []byte, password string) error {
+				if string(hashedPassword) != password {
+					return randomErr
+				}
+
+				return nil
+			}}
+			v := &mockPassValidator{validate: func(username, password string) error {
+				assert.Equal(t, "abc", username)
+				assert.Equal(t, "new", password)
+
+				return tc.validateErr
+			}}
+			h := &mockPassHasher{hash: func(password string) ([]byte, error) {
+				return []byte("hashed " + password), nil
+			}}
+			recent := &mockRecentPasswords{
+				check: func(ctx context.Context, u *User, password string) error {
+					assert.Equal(t, "1", u.ID)
+					assert.Equal(t, "new", password)
+
+					return tc.checkErr
+				},
+				record: func(ctx context.Context, userID string, oldHashedPassword []byte) error {
+					recorded = true
+					assert.Equal(t, "1", userID)
+					assert.Equal(t, []byte("old"), oldHashedPassword)
+
+					return tc.recordErr
+				},
+			}
+			revoker := &mockCredentialRevoker{revokeCredentials: func(ctx context.Context, userID, keepSessionID string) error {
+				revoked = true
+				assert.Equal(t, "1", userID)
+				assert.Equal(t, "current", keepSessionID)
+
+				return tc.revokeErr
+			}}
+			cmd := &mockPasswordChangerCMD{updatePassword: func(ctx context.Context, params *UpdatePasswordParams) error {
+				updated = true
+				assert.Equal(t, "1", params.ID)
+				assert.Equal(t, []byte("old"), params.OldHashedPassword)
+				assert.Equal(t, []byte("hashed new"), params.HashedPassword)
+
+				return tc.updateErr
+			}}
+
+			lockout := &mockLoginLockout{
+				check: func(ctx context.Context, userID string) error {
+					assert.Equal(t, "1", userID)
+					return tc.lockedErr
+				},
+				fail: func(ctx context.Context, userID string) error {
+					failed = true
+					assert.Equal(t, "1", userID)
+					return tc.failErr
+				},
+				succeed: func(ctx context.Context, userID string) error {
+					succeeded = true
+					assert.Equal(t, "1", userID)
+					return tc.succeedErr
+				},
+			}
+
+			c := NewPasswordChanger(v, h, pv, recent, revoker, lockout, q, cmd)
+
+			err := c.Change(context.Background(), ChangePasswordParams{
+				ID:              "1",
+				CurrentPassword: tc.currentPassword,
+				NewPassword:     "new",
+				KeepSessionID:   "current",
+			})
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expFail, failed)
+			assert.Equal(t, tc.expSucceed, succeeded)
+			assert.Equal(t, tc.expUpdate, updated)
+			assert.Equal(t, tc.expRevoke, revoked)
+			assert.Equal(t, tc.expRecord, recorded)
+		})
+	}
+}
+
+type mockPasswordChangerQueries struct {
+	getByID func(ctx context.Context, id string) (*User, error)
+}
+
+func (m *mockPasswordChangerQueries) GetByID(ctx context.Context, id string) (*User, error) {
+	return m.getByID(ctx, id)
+}
+
+type mockPasswordChangerCMD struct {
+	updatePassword func(ctx context.Context, params *UpdatePasswordParams) error
+}
+
+func (m *mockPasswordChangerCMD) UpdatePassword(ctx context.Context, params *UpdatePasswordParams) error {
+	return m.updatePassword(ctx, params)
+}
+
+type mockRecentPasswords struct {
+	check  func(ctx context.Context, u *User, password string) error
+	record func(ctx context.Context, userID string, oldHashedPassword []byte) error
+}
+
+func (m *mockRecentPasswords) Check(ctx context.Context, u *User, password string) error {
+	return m.check(ctx, u, password)
+}
+
+func (m *mockRecentPasswords) Record(ctx context.Context, userID string, oldHashedPassword []byte) error {
+	return m.record(ctx, userID, oldHashedPassword)
+}
+
+type mockCredentialRevoker struct {
+	revokeCredentials func(ctx context.Context, userID, keepSessionID string) error
+}
+
+func (m *mockCredentialRevoker) RevokeCredentials(ctx context.Context, userID, keepSessionID string) error {
+	return m.revokeCredentials(ctx, userID, keepSessionID)
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrPasswordReused = errors.New("password was used recently")
+)
+
+// PasswordHistoryEntry is a previous password of a user.
+type PasswordHistoryEntry struct {
+	UserID         string
+	HashedPassword []byte
+	CreatedAt      time.Time
+}
+
+// ListPasswordHistory returns the most recent limit entries of a user, newest
+// first.
+type ListPasswordHistory interface {
+	ListPasswordHistory(ctx context.Context, userID string, limit int) ([]*PasswordHistoryEntry, error)
+}
+
+// AddPasswordHistory stores a previous password of a user.
+type AddPasswordHistory interface {
+	AddPasswordHistory(ctx context.Context, entry *PasswordHistoryEntry) error
+}
+
+// PrunePasswordHistory deletes every entry of a user but the most recent keep.
+type PrunePasswordHistory interface {
+	PrunePasswordHistory(ctx context.Context, userID string, keep int) error
+}
+
+type PasswordHistoryQueries interface {
+	ListPasswordHistory
+}
+
+type PasswordHistoryCommands interface {
+	AddPasswordHistory
+	PrunePasswordHistory
+}
+
+// PasswordHistory keeps users from reusing their last size passwords, the
+// current one included. A size of 0 disables it.
+type PasswordHistory struct {
+	size     int
+	verifier PasswordVerifier
+	q        PasswordHistoryQueries
+	cmd      PasswordHistoryCommands
+}
+
+func NewPasswordHistory(size int, v PasswordVerifier, q PasswordHistoryQueries, cmd PasswordHistoryCommands) *PasswordHistory {
+	if size < 0 {
+		size = 0
+	}
+
+	return &PasswordHistory{
+		size:     size,
+		verifier: v,
+		q:        q,
+		cmd:      cmd,
+	}
+}
+
+// Check returns ErrPasswordReused when password is the current password of u
+// or one of the previous ones remembered. Hashes that cannot be verified
+// anymore, as after removing a pepper key, are skipped.
+func (h *PasswordHistory) Check(ctx context.Context, u *User, password string) error {
+	if h.size == 0 {
+		return nil
+	}
+
+	if h.verifier.Verify(u.HashedPassword, password) == nil {
+		return ErrPasswordReused
+	}
+
+	if h.size == 1 {
+		return nil
+	}
+
+	entries, err := h.q.ListPasswordHistory(ctx, u.ID, h.size-1)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if h.verifier.Verify(e.HashedPassword, password) == nil {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// Record remembers oldHashedPassword, the password of the user userID before
+// it was changed, and forgets the ones that fall out of the history.
+func (h *PasswordHistory) Record(ctx context.Context, userID string, oldHashedPassword []byte) error {
+	keep := h.size - 1
+	if keep <= 0 {
+		return h.cmd.PrunePasswordHistory(ctx, userID, 0)
+	}
+
+	err := h.cmd.AddPasswordHistory(ctx, &PasswordHistoryEntry{
+		UserID:         userID,
+		HashedPassword: oldHashedPassword,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.cmd.PrunePasswordHistory(ctx, userID, keep)
+}
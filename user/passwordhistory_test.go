@@ -0,0 +1,207 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHistory_Check(t *testing.T) {
+	randomErr := errors.New("random error")
+	mismatch := errors.New("mismatch")
+
+	testCases := []struct {
+		description string
+		size        int
+		password    string
+		history     []string
+		listErr     error
+		expLimit    int
+		expError    error
+	}{
+		{
+			description: "disabled",
+			size:        0,
+			password:    "current",
+		},
+		{
+			description: "current password",
+			size:        1,
+			password:    "current",
+			expError:    ErrPasswordReused,
+		},
+		{
+			description: "only the current password is checked",
+			size:        1,
+			password:    "new",
+		},
+		{
+			description: "previous password",
+			size:        3,
+			password:    "older",
+			history:     []string{"old", "older"},
+			expLimit:    2,
+			expError:    ErrPasswordReused,
+		},
+		{
+			description: "unverifiable hashes are skipped",
+			size:        3,
+			password:    "new",
+			history:     []string{"unknown key", "old"},
+			expLimit:    2,
+		},
+		{
+			description: "random error",
+			size:        3,
+			password:    "new",
+			listErr:     randomErr,
+			expLimit:    2,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			size:        3,
+			password:    "new",
+			history:     []string{"old", "older"},
+			expLimit:    2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var limit int
+			v := &mockPassVerifier{verify: func(hashedPassword []byte, password string) error {
+				switch string(hashedPassword) {
+				case "unknown key":
+					return randomErr
+				case password:
+					return nil
+				}
+
+				return mismatch
+			}}
+			q := &mockPasswordHistoryQueries{listPasswordHistory: func(ctx context.Context, userID string, l int) ([]*PasswordHistoryEntry, error) {
+				assert.Equal(t, "1", userID)
+				limit = l
+				if tc.listErr != nil {
+					return nil, tc.listErr
+				}
+
+				entries := make([]*PasswordHistoryEntry, 0, len(tc.history))
+				for _, h := range tc.history {
+					entries = append(entries, &PasswordHistoryEntry{UserID: userID, HashedPassword: []byte(h)})
+				}
+
+				return entries, nil
+			}}
+
+			h := NewPasswordHistory(tc.size, v, q, &mockPasswordHistoryCommands{})
+
+			err := h.Check(context.Background(), &User{ID: "1", HashedPassword: []byte("current")}, tc.password)
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expLimit, limit)
+		})
+	}
+}
+
+func TestPasswordHistory_Record(t *testing.T) {
+	randomErr := errors.New("random error")
+
+	testCases := []struct {
+		description string
+		size        int
+		addErr      error
+		expAdd      bool
+		expPrune    bool
+		expKeep     int
+		expError    error
+	}{
+		{
+			description: "disabled forgets the history",
+			size:        0,
+			expPrune:    true,
+			expKeep:     0,
+		},
+		{
+			description: "only the current password forgets the history",
+			size:        1,
+			expPrune:    true,
+			expKeep:     0,
+		},
+		{
+			description: "add error",
+			size:        5,
+			addErr:      randomErr,
+			expAdd:      true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			size:        5,
+			expAdd:      true,
+			expPrune:    true,
+			expKeep:     4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				added  bool
+				pruned bool
+				keep   int
+			)
+			cmd := &mockPasswordHistoryCommands{
+				addPasswordHistory: func(ctx context.Context, entry *PasswordHistoryEntry) error {
+					added = true
+					assert.Equal(t, "1", entry.UserID)
+					assert.Equal(t, []byte("old"), entry.HashedPassword)
+					assert.False(t, entry.CreatedAt.IsZero())
+
+					return tc.addErr
+				},
+				prunePasswordHistory: func(ctx context.Context, userID string, k int) error {
+					pruned = true
+					assert.Equal(t, "1", userID)
+					keep = k
+
+					return nil
+				},
+			}
+
+			h := NewPasswordHistory(tc.size, &mockPassVerifier{}, &mockPasswordHistoryQueries{}, cmd)
+
+			err := h.Record(context.Background(), "1", []byte("old"))
+			assert.ErrorIs(t, err, tc.expError)
+			assert.Equal(t, tc.expAdd, added)
+			assert.Equal(t, tc.expPrune, pruned)
+			assert.Equal(t, tc.expKeep, keep)
+		})
+	}
+}
+
+type mockPasswordHistoryQueries struct {
+	listPasswordHistory func(ctx context.Context, userID string, limit int) ([]*PasswordHistoryEntry, error)
+}
+
+func (m *mockPasswordHistoryQueries) ListPasswordHistory(ctx context.Context, userID string, limit int) ([]*PasswordHistoryEntry, error) {
+	return m.listPasswordHistory(ctx, userID, limit)
+}
+
+type mockPasswordHistoryCommands struct {
+	addPasswordHistory   func(ctx context.Context, entry *PasswordHistoryEntry) error
+	prunePasswordHistory func(ctx context.Context, userID string, keep int) error
+}
+
+func (m *mockPasswordHistoryCommands) AddPasswordHistory(ctx context.Context, entry *PasswordHistoryEntry) error {
+	return m.addPasswordHistory(ctx, entry)
+}
+
+func (m *mockPasswordHistoryCommands) PrunePasswordHistory(ctx context.Context, userID string, keep int) error {
+	return m.prunePasswordHistory(ctx, userID, keep)
+}
@@ -0,0 +1,288 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUsernameLenToCheck keeps very short usernames from rejecting every
+// password that happens to contain them.
+const minUsernameLenToCheck = 3
+
+var (
+	ErrWeakPassword          = errors.New("password does not meet the password policy")
+	ErrInvalidPasswordPolicy = errors.New("invalid password policy")
+)
+
+// PasswordRule identifies a rule of a PasswordPolicy.
+type PasswordRule string
+
+const (
+	RuleMinLength     PasswordRule = "min_length"
+	RuleMaxLength     PasswordRule = "max_length"
+	RuleMaxBytes      PasswordRule = "max_bytes"
+	RuleLower         PasswordRule = "lower"
+	RuleUpper         PasswordRule = "upper"
+	RuleDigit         PasswordRule = "digit"
+	RuleSymbol        PasswordRule = "symbol"
+	RuleUsername      PasswordRule = "username"
+	RuleMaxRepeated   PasswordRule = "max_repeated"
+	RuleMaxSequential PasswordRule = "max_sequential"
+	RuleMinEntropy    PasswordRule = "min_entropy"
+	RuleBreached      PasswordRule = "breached"
+)
+
+func (r PasswordRule) String() string {
+	return string(r)
+}
+
+// PasswordPolicy are the rules a password must follow. Lengths are counted in
+// characters, not bytes, except for MaxBytes. Zero values disable a rule.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MaxBytes is the longest allowed password in UTF-8 bytes, for hashing
+	// algorithms that ignore what comes after.
+	MaxBytes      int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DisallowUsername rejects passwords containing the username, ignoring
+	// case.
+	DisallowUsername bool
+	// MaxRepeated is the longest allowed run of the same character, as in
+	// "aaa".
+	MaxRepeated int
+	// MaxSequential is the longest allowed run of consecutive characters, as
+	// in "abcd" or "4321".
+	MaxSequential int
+	// MinEntropy is the minimum estimated entropy in bits. See
+	// estimateEntropy.
+	MinEntropy float64
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:        DefaultMinLen,
+	MaxLength:        128,
+	DisallowUsername: true,
+	MaxRepeated:      3,
+	MaxSequential:    4,
+	MinEntropy:       30,
+}
+
+// PasswordViolation is a rule that a password breaks.
+type PasswordViolation struct {
+	Rule    PasswordRule
+	Message string
+}
+
+// PasswordPolicyError lists every rule that a password breaks. It matches
+// ErrWeakPassword.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(messages, "; "))
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// PolicyPasswordValidator checks passwords against a PasswordPolicy.
+type PolicyPasswordValidator struct {
+	policy PasswordPolicy
+}
+
+func NewPolicyPasswordValidator(policy PasswordPolicy) (*PolicyPasswordValidator, error) {
+	switch {
+	case policy.MinLength < 0 || policy.MaxLength < 0 || policy.MaxBytes < 0 || policy.MaxRepeated < 0 || policy.MaxSequential < 0 || policy.MinEntropy < 0:
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidPasswordPolicy)
+	case policy.MaxLength != 0 && policy.MaxLength < policy.MinLength:
+		return nil, fmt.Errorf("%w: the max length is smaller than the min length", ErrInvalidPasswordPolicy)
+	}
+
+	return &PolicyPasswordValidator{policy: policy}, nil
+}
+
+// Validate returns a *PasswordPolicyError with every rule that password
+// breaks.
+func (v *PolicyPasswordValidator) Validate(username, password string) error {
+	var (
+		p          = v.policy
+		violations []PasswordViolation
+	)
+	violate := func(rule PasswordRule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violate(RuleMinLength, "must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(RuleMaxLength, "must be at most %d characters long", p.MaxLength)
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violate(RuleMaxBytes, "must be at most %d bytes long", p.MaxBytes)
+	}
+
+	classes := charClassesOf(password)
+	if p.RequireLower && !classes.lower {
+		violate(RuleLower, "must contain a lowercase letter")
+	}
+	if p.RequireUpper && !classes.upper {
+		violate(RuleUpper, "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !classes.digit {
+		violate(RuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !classes.symbol {
+		violate(RuleSymbol, "must contain a symbol")
+	}
+
+	if p.DisallowUsername && utf8.RuneCountInString(username) >= minUsernameLenToCheck &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violate(RuleUsername, "must not contain the username")
+	}
+
+	repeated, sequential := longestRuns(password)
+	if p.MaxRepeated > 0 && repeated > p.MaxRepeated {
+		violate(RuleMaxRepeated, "must not repeat a character more than %d times in a row", p.MaxRepeated)
+	}
+	if p.MaxSequential > 0 && sequential > p.MaxSequential {
+		violate(RuleMaxSequential, "must not contain more than %d consecutive characters like abcd or 4321", p.MaxSequential)
+	}
+
+	if p.MinEntropy > 0 && estimateEntropy(password) < p.MinEntropy {
+		violate(RuleMinEntropy, "is too easy to guess")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// charClasses are the classes of the characters of a password. Non-ASCII
+// characters are also counted as other.
+type charClasses struct {
+	lower, upper, digit, symbol, other bool
+}
+
+func charClassesOf(password string) charClasses {
+	var c charClasses
+	for _, r := range password {
+		if r >= utf8.RuneSelf {
+			c.other = true
+		}
+
+		switch {
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			c.symbol = true
+		}
+	}
+
+	return c
+}
+
+// poolSize is the number of characters an attacker has to try per position
+// to cover every class used.
+func (c charClasses) poolSize() int {
+	size := 0
+	if c.lower {
+		size += 26
+	}
+	if c.upper {
+		size += 26
+	}
+	if c.digit {
+		size += 10
+	}
+	if c.symbol {
+		size += 33
+	}
+	if c.other {
+		size += 100
+	}
+
+	return size
+}
+
+// estimateEntropy roughly estimates the entropy of password in bits as the
+// number of distinct characters times the bits of a character of the pool of
+// the classes used. Counting distinct characters only keeps repetitions from
+// adding strength.
+func estimateEntropy(password string) float64 {
+	pool := charClassesOf(password).poolSize()
+	if pool == 0 {
+		return 0
+	}
+
+	distinct := make(map[rune]struct{})
+	for _, r := range password {
+		distinct[r] = struct{}{}
+	}
+
+	return float64(len(distinct)) * math.Log2(float64(pool))
+}
+
+// longestRuns returns the length of the longest run of the same character and
+// of the longest run of consecutive characters, ascending or descending.
+func longestRuns(password string) (repeated, sequential int) {
+	var (
+		prev      rune
+		step      rune
+		repeatRun int
+		seqRun    int
+	)
+	for i, r := range []rune(password) {
+		d := r - prev
+		switch {
+		case i == 0:
+			repeatRun, seqRun = 1, 1
+		case d == 0:
+			repeatRun++
+			seqRun, step = 1, 0
+		case d == 1 || d == -1:
+			repeatRun = 1
+			if d == step {
+				seqRun++
+			} else {
+				// A new run, or a change of direction as in "abcb".
+				seqRun = 2
+			}
+			step = d
+		default:
+			repeatRun, seqRun, step = 1, 1, 0
+		}
+
+		if repeatRun > repeated {
+			repeated = repeatRun
+		}
+		if seqRun > sequential {
+			sequential = seqRun
+		}
+		prev = r
+	}
+
+	return repeated, sequential
+}
@@ -0,0 +1,194 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPolicyPasswordValidator(t *testing.T) {
+	testCases := []struct {
+		description string
+		policy      PasswordPolicy
+		expError    error
+	}{
+		{
+			description: "negative limit",
+			policy:      PasswordPolicy{MaxRepeated: -1},
+			expError:    ErrInvalidPasswordPolicy,
+		},
+		{
+			description: "negative max bytes",
+			policy:      PasswordPolicy{MaxBytes: -1},
+			expError:    ErrInvalidPasswordPolicy,
+		},
+		{
+			description: "max length smaller than min length",
+			policy:      PasswordPolicy{MinLength: 10, MaxLength: 8},
+			expError:    ErrInvalidPasswordPolicy,
+		},
+		{
+			description: "no max length",
+			policy:      PasswordPolicy{MinLength: 10},
+		},
+		{
+			description: "default",
+			policy:      DefaultPasswordPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := NewPolicyPasswordValidator(tc.policy)
+
+			assert.ErrorIs(t, err, tc.expError)
+		})
+	}
+}
+
+func TestPolicyPasswordValidator_Validate(t *testing.T) {
+	strict := DefaultPasswordPolicy
+	strict.RequireLower = true
+	strict.RequireUpper = true
+	strict.RequireDigit = true
+	strict.RequireSymbol = true
+
+	testCases := []struct {
+		description string
+		policy      PasswordPolicy
+		username    string
+		password    string
+		expRules    []PasswordRule
+	}{
+		{
+			description: "valid",
+			policy:      DefaultPasswordPolicy,
+			username:    "alice",
+			password:    "correct horse",
+		},
+		{
+			description: "too short",
+			policy:      DefaultPasswordPolicy,
+			password:    "x7#kQ",
+			expRules:    []PasswordRule{RuleMinLength},
+		},
+		{
+			description: "multi-byte characters are counted once",
+			policy:      PasswordPolicy{MinLength: 8},
+			password:    "ñandú€é",
+			expRules:    []PasswordRule{RuleMinLength},
+		},
+		{
+			description: "multi-byte characters",
+			policy:      PasswordPolicy{MinLength: 7, MaxLength: 7},
+			password:    "ñandú€é",
+		},
+		{
+			description: "too long",
+			policy:      PasswordPolicy{MaxLength: 10},
+			password:    "correct horse",
+			expRules:    []PasswordRule{RuleMaxLength},
+		},
+		{
+			description: "too many bytes",
+			policy:      PasswordPolicy{MaxLength: 8, MaxBytes: 8},
+			password:    "ñandú€é",
+			expRules:    []PasswordRule{RuleMaxBytes},
+		},
+		{
+			description: "missing classes",
+			policy:      strict,
+			password:    "correct horse",
+			expRules:    []PasswordRule{RuleUpper, RuleDigit},
+		},
+		{
+			description: "every class",
+			policy:      strict,
+			password:    "Correct horse 7",
+		},
+		{
+			description: "contains username",
+			policy:      DefaultPasswordPolicy,
+			username:    "Alice",
+			password:    "xx-aLiCe-2024",
+			expRules:    []PasswordRule{RuleUsername},
+		},
+		{
+			description: "short usernames are ignored",
+			policy:      DefaultPasswordPolicy,
+			username:    "co",
+			password:    "correct horse",
+		},
+		{
+			description: "repeated",
+			policy:      DefaultPasswordPolicy,
+			password:    "aaaaaaaa",
+			expRules:    []PasswordRule{RuleMaxRepeated, RuleMinEntropy},
+		},
+		{
+			description: "ascending sequence",
+			policy:      DefaultPasswordPolicy,
+			password:    "xq12345z",
+			expRules:    []PasswordRule{RuleMaxSequential},
+		},
+		{
+			description: "descending sequence",
+			policy:      DefaultPasswordPolicy,
+			password:    "zyxwvqm7",
+			expRules:    []PasswordRule{RuleMaxSequential},
+		},
+		{
+			description: "change of direction",
+			policy:      PasswordPolicy{MaxSequential: 4},
+			password:    "pqrsrqpm",
+		},
+		{
+			description: "low entropy",
+			policy:      DefaultPasswordPolicy,
+			password:    "abababab",
+			expRules:    []PasswordRule{RuleMinEntropy},
+		},
+		{
+			description: "every violation at once",
+			policy:      strict,
+			username:    "aaa",
+			password:    "aaaa",
+			expRules:    []PasswordRule{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol, RuleUsername, RuleMaxRepeated, RuleMinEntropy},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			v, err := NewPolicyPasswordValidator(tc.policy)
+			assert.NoError(t, err)
+
+			err = v.Validate(tc.username, tc.password)
+			if len(tc.expRules) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrWeakPassword)
+			var policyErr *PasswordPolicyError
+			if assert.True(t, errors.As(err, &policyErr)) {
+				rules := make([]PasswordRule, 0, len(policyErr.Violations))
+				for _, v := range policyErr.Violations {
+					rules = append(rules, v.Rule)
+					assert.NotEmpty(t, v.Message)
+				}
+				assert.Equal(t, tc.expRules, rules)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyError(t *testing.T) {
+	err := &PasswordPolicyError{Violations: []PasswordViolation{
+		{Rule: RuleMinLength, Message: "must be at least 8 characters long"},
+		{Rule: RuleDigit, Message: "must contain a digit"},
+	}}
+
+	assert.Equal(t, "password does not meet the password policy: must be at least 8 characters long; must contain a digit", err.Error())
+	assert.ErrorIs(t, err, ErrWeakPassword)
+}
@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"time"
+)
+
+// ResetTokens issues single use password reset tokens. Check returns the user
+// of a token without using it, and Redeem uses it.
+type ResetTokens interface {
+	Issue(ctx context.Context, userID string) (string, time.Time, error)
+	Check(ctx context.Context, secret string) (string, error)
+	Redeem(ctx context.Context, secret string) error
+}
+
+// ResetNotifier hands a password reset token to the user, as by email.
+type ResetNotifier interface {
+	NotifyPasswordReset(ctx context.Context, u *User, secret string, expiresAt time.Time) error
+}
+
+// AccountUnlocker forgets the failed logins and locks of a user. It is
+// implemented by Lockouts.
+type AccountUnlocker interface {
+	Unlock(ctx context.Context, userID string) error
+}
+
+type PasswordResetterQueries interface {
+	GetByID
+	GetByUsername
+}
+
+type PasswordResetterCommands interface {
+	UpdatePassword
+}
+
+// ConfirmResetParams holds the new password of the user of Token.
+type ConfirmResetParams struct {
+	Token       string
+	NewPassword string
+}
+
+type PasswordResetter struct {
+	tokens    ResetTokens
+	notifier  ResetNotifier
+	validator PasswordValidator
+	hasher    PasswordHasher
+	recent    RecentPasswords
+	revoker   CredentialRevoker
+	unlocker  AccountUnlocker
+	q         PasswordResetterQueries
+	cmd       PasswordResetterCommands
+}
+
+func NewPasswordResetter(tokens ResetTokens, n ResetNotifier, v PasswordValidator, h PasswordHasher, recent RecentPasswords, revoker CredentialRevoker, unlocker AccountUnlocker, q PasswordResetterQueries, cmd PasswordResetterCommands) *PasswordResetter {
+	return &PasswordResetter{
+		tokens:    tokens,
+		notifier:  n,
+		validator: v,
+		hasher:    h,
+		recent:    recent,
+		revoker:   revoker,
+		unlocker:  unlocker,
+		q:         q,
+		cmd:       cmd,
+	}
+}
+
+// Request sends a reset token to the user with the username. Unknown and
+// soft-deleted users are ignored without error, so callers cannot tell which
+// usernames exist.
+func (r *PasswordResetter) Request(ctx context.Context, username string) error {
+	u, err := r.q.GetByUsername(ctx, username)
+	if err == ErrDoesNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.DeletedAt != nil {
+		return nil
+	}
+
+	secret, expiresAt, err := r.tokens.Issue(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	return r.notifier.NotifyPasswordReset(ctx, u, secret, expiresAt)
+}
+
+// Confirm replaces the password of the user of the token, which cannot be
+// used again. The token is only used once the new password is accepted, so a
+// rejected password can be retried with the same token, and right before the
+// password is stored, so a token that cannot be used changes nothing. The
+// password is only replaced if it did not change since the token was checked.
+// The user is unlocked, and every session and refresh token of the user is
+// revoked, since whoever knew the old password may still be logged in.
+func (r *PasswordResetter) Confirm(ctx context.Context, params ConfirmResetParams) error {
+	userID, err := r.tokens.Check(ctx, params.Token)
+	if err != nil {
+		return err
+	}
+
+	u, err := r.q.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.validator.Validate(u.Username, params.NewPassword); err != nil {
+		return err
+	}
+
+	if err = r.recent.Check(ctx, u, params.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := r.hasher.Hash(params.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err = r.tokens.Redeem(ctx, params.Token); err != nil {
+		return err
+	}
+
+	err = r.cmd.UpdatePassword(ctx, &UpdatePasswordParams{
+		ID:                u.ID,
+		OldHashedPassword: u.HashedPassword,
+		HashedPassword:    hashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = r.unlocker.Unlock(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if err = r.revoker.RevokeCredentials(ctx, u.ID, ""); err != nil {
+		return err
+	}
+
+	return r.recent.Record(ctx, u.ID, u.HashedPassword)
+}
@@ -0,0 +1,316 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestReset(t *testing.T) {
+	randomErr := errors.New("random error")
+	deletedAt := time.Now()
+
+	testCases := []struct {
+		description string
+		getErr      error
+		deletedAt   *time.Time
+		issueErr    error
+		notifyErr   error
+		expIssue    bool
+		expNotify   bool
+		expError    error
+	}{
+		{
+			description: "unknown user",
+			getErr:      ErrDoesNotExist,
+		},
+		{
+			description: "deleted user",
+			deletedAt:   &deletedAt,
+		},
+		{
+			description: "random get error",
+			getErr:      randomErr,
+			expError:    randomErr,
+		},
+		{
+			description: "issue error",
+			issueErr:    randomErr,
+			expIssue:    true,
+			expError:    randomErr,
+		},
+		{
+			description: "notify error",
+			notifyErr:   randomErr,
+			expIssue:    true,
+			expNotify:   true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			expIssue:    true,
+			expNotify:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var issued, notified bool
+			expiresAt := time.Now().Add(time.Hour)
+
+			q := &mockPasswordResetterQueries{getByUsername: func(ctx context.Context, username string) (*User, error) {
+				assert.Equal(t, "abc", username)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &User{ID: "1", Username: username, DeletedAt: tc.deletedAt}, nil
+			}}
+			tokens := &mockResetTokens{issue: func(ctx context.Context, userID string) (string, time.Time, error) {
+				issued = true
+				assert.Equal(t, "1", userID)
+
+				return "secret", expiresAt, tc.issueErr
+			}}
+			n := &mockResetNotifier{notify: func(ctx context.Context, u *User, secret string, exp time.Time) error {
+				notified = true
+				assert.Equal(t, "1", u.ID)
+				assert.Equal(t, "secret", secret)
+				assert.Equal(t, expiresAt, exp)
+
+				return tc.notifyErr
+			}}
+
+			r := NewPasswordResetter(tokens, n, nil, nil, nil, nil, nil, q, nil)
+
+			err := r.Request(context.Background(), "abc")
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expIssue, issued)
+			assert.Equal(t, tc.expNotify, notified)
+		})
+	}
+}
+
+func TestConfirmReset(t *testing.T) {
+	randomErr := errors.New("random error")
+	invalidToken := errors.New("invalid token")
+	weakErr := &PasswordPolicyError{Violations: []PasswordViolation{{Rule: RuleMinLength}}}
+
+	testCases := []struct {
+		description string
+		checkErr    error
+		getErr      error
+		validateErr error
+		reuseErr    error
+		redeemErr   error
+		updateErr   error
+		unlockErr   error
+		revokeErr   error
+		expRedeem   bool
+		expUpdate   bool
+		expUnlock   bool
+		expRevoke   bool
+		expRecord   bool
+		expError    error
+	}{
+		{
+			description: "invalid token",
+			checkErr:    invalidToken,
+			expError:    invalidToken,
+		},
+		{
+			description: "deleted user",
+			getErr:      ErrDoesNotExist,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "weak password keeps the token",
+			validateErr: weakErr,
+			expError:    ErrWeakPassword,
+		},
+		{
+			description: "reused password keeps the token",
+			reuseErr:    ErrPasswordReused,
+			expError:    ErrPasswordReused,
+		},
+		{
+			description: "redeem error changes nothing",
+			redeemErr:   invalidToken,
+			expRedeem:   true,
+			expError:    invalidToken,
+		},
+		{
+			description: "password changed meanwhile",
+			updateErr:   ErrPasswordChanged,
+			expRedeem:   true,
+			expUpdate:   true,
+			expError:    ErrPasswordChanged,
+		},
+		{
+			description: "update error",
+			updateErr:   randomErr,
+			expRedeem:   true,
+			expUpdate:   true,
+			expError:    randomErr,
+		},
+		{
+			description: "unlock error",
+			unlockErr:   randomErr,
+			expUpdate:   true,
+			expRedeem:   true,
+			expUnlock:   true,
+			expError:    randomErr,
+		},
+		{
+			description: "revoke error",
+			revokeErr:   randomErr,
+			expUpdate:   true,
+			expRedeem:   true,
+			expUnlock:   true,
+			expRevoke:   true,
+			expError:    randomErr,
+		},
+		{
+			description: "all good",
+			expUpdate:   true,
+			expRedeem:   true,
+			expUnlock:   true,
+			expRevoke:   true,
+			expRecord:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var redeemed, updated, unlocked, revoked, recorded bool
+
+			tokens := &mockResetTokens{
+				check: func(ctx context.Context, secret string) (string, error) {
+					assert.Equal(t, "secret", secret)
+					return "1", tc.checkErr
+				},
+				redeem: func(ctx context.Context, secret string) error {
+					redeemed = true
+					assert.False(t, updated, "the token must be used before the password is updated")
+					assert.Equal(t, "secret", secret)
+					return tc.redeemErr
+				},
+			}
+			q := &mockPasswordResetterQueries{getByID: func(ctx context.Context, id string) (*User, error) {
+				assert.Equal(t, "1", id)
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+
+				return &User{ID: "1", Username: "abc", HashedPassword: []byte("old")}, nil
+			}}
+			v := &mockPassValidator{validate: func(username, password string) error {
+				assert.Equal(t, "abc", username)
+				assert.Equal(t, "new", password)
+
+				return tc.validateErr
+			}}
+			h := &mockPassHasher{hash: func(password string) ([]byte, error) {
+				return []byte("hashed " + password), nil
+			}}
+			recent := &mockRecentPasswords{
+				check: func(ctx context.Context, u *User, password string) error {
+					return tc.reuseErr
+				},
+				record: func(ctx context.Context, userID string, oldHashedPassword []byte) error {
+					recorded = true
+					assert.Equal(t, []byte("old"), oldHashedPassword)
+
+					return nil
+				},
+			}
+			revoker := &mockCredentialRevoker{revokeCredentials: func(ctx context.Context, userID, keepSessionID string) error {
+				revoked = true
+				assert.Equal(t, "1", userID)
+				assert.Empty(t, keepSessionID)
+
+				return tc.revokeErr
+			}}
+			unlocker := &mockAccountUnlocker{unlock: func(ctx context.Context, userID string) error {
+				unlocked = true
+				assert.Equal(t, "1", userID)
+
+				return tc.unlockErr
+			}}
+			cmd := &mockPasswordChangerCMD{updatePassword: func(ctx context.Context, params *UpdatePasswordParams) error {
+				updated = true
+				assert.Equal(t, "1", params.ID)
+				assert.Equal(t, []byte("old"), params.OldHashedPassword)
+				assert.Equal(t, []byte("hashed new"), params.HashedPassword)
+
+				return tc.updateErr
+			}}
+
+			r := NewPasswordResetter(tokens, nil, v, h, recent, revoker, unlocker, q, cmd)
+
+			err := r.Confirm(context.Background(), ConfirmResetParams{Token: "secret", NewPassword: "new"})
+			assert.ErrorIs(t, err, tc.expError)
+			if tc.expError == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expUpdate, updated)
+			assert.Equal(t, tc.expRedeem, redeemed)
+			assert.Equal(t, tc.expUnlock, unlocked)
+			assert.Equal(t, tc.expRevoke, revoked)
+			assert.Equal(t, tc.expRecord, recorded)
+		})
+	}
+}
+
+type mockPasswordResetterQueries struct {
+	getByID       func(ctx context.Context, id string) (*User, error)
+	getByUsername func(ctx context.Context, username string) (*User, error)
+}
+
+func (m *mockPasswordResetterQueries) GetByID(ctx context.Context, id string) (*User, error) {
+	return m.getByID(ctx, id)
+}
+
+func (m *mockPasswordResetterQueries) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return m.getByUsername(ctx, username)
+}
+
+type mockResetTokens struct {
+	issue  func(ctx context.Context, userID string) (string, time.Time, error)
+	check  func(ctx context.Context, secret string) (string, error)
+	redeem func(ctx context.Context, secret string) error
+}
+
+func (m *mockResetTokens) Issue(ctx context.Context, userID string) (string, time.Time, error) {
+	return m.issue(ctx, userID)
+}
+
+func (m *mockResetTokens) Check(ctx context.Context, secret string) (string, error) {
+	return m.check(ctx, secret)
+}
+
+func (m *mockResetTokens) Redeem(ctx context.Context, secret string) error {
+	return m.redeem(ctx, secret)
+}
+
+type mockResetNotifier struct {
+	notify func(ctx context.Context, u *User, secret string, expiresAt time.Time) error
+}
+
+func (m *mockResetNotifier) NotifyPasswordReset(ctx context.Context, u *User, secret string, expiresAt time.Time) error {
+	return m.notify(ctx, u, secret, expiresAt)
+}
+
+type mockAccountUnlocker struct {
+	unlock func(ctx context.Context, userID string) error
+}
+
+func (m *mockAccountUnlocker) Unlock(ctx context.Context, userID string) error {
+	return m.unlock(ctx, userID)
+}
@@ -18,10 +18,73 @@ func NewSimplePasswordValidator(minLen int) *SimplePasswordValidator {
 	return &SimplePasswordValidator{minLen: minLen}
 }
 
-func (v *SimplePasswordValidator) Validate(password string) error {
+func (v *SimplePasswordValidator) Validate(_, password string) error {
 	if len(password) < v.minLen {
 		return ErrPasswordTooSmall
 	}
 
 	return nil
 }
+
+// BreachedPasswords reports whether a password is known to be compromised.
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// BreachedPasswordValidator rejects passwords found in a list of breached
+// passwords with a *PasswordPolicyError.
+type BreachedPasswordValidator struct {
+	breached BreachedPasswords
+}
+
+func NewBreachedPasswordValidator(breached BreachedPasswords) *BreachedPasswordValidator {
+	return &BreachedPasswordValidator{breached: breached}
+}
+
+func (v *BreachedPasswordValidator) Validate(_, password string) error {
+	breached, err := v.breached.Contains(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		return &PasswordPolicyError{Violations: []PasswordViolation{{
+			Rule:    RuleBreached,
+			Message: "has appeared in a data breach",
+		}}}
+	}
+
+	return nil
+}
+
+// ChainPasswordValidator runs several validators in order. The violations of
+// every *PasswordPolicyError are merged into one, so all the broken rules are
+// reported together, while any other error is returned right away.
+type ChainPasswordValidator struct {
+	validators []PasswordValidator
+}
+
+func NewChainPasswordValidator(validators ...PasswordValidator) *ChainPasswordValidator {
+	return &ChainPasswordValidator{validators: validators}
+}
+
+func (v *ChainPasswordValidator) Validate(username, password string) error {
+	var violations []PasswordViolation
+	for _, validator := range v.validators {
+		err := validator.Validate(username, password)
+		if err == nil {
+			continue
+		}
+
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			return err
+		}
+		violations = append(violations, policyErr.Violations...)
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreachedPasswordValidator(t *testing.T) {
+	v := NewBreachedPasswordValidator(mockBreachedPasswords{"password": true})
+
+	err := v.Validate("usr", "password")
+	assert.ErrorIs(t, err, ErrWeakPassword)
+	var policyErr *PasswordPolicyError
+	if assert.True(t, errors.As(err, &policyErr)) {
+		assert.Equal(t, RuleBreached, policyErr.Violations[0].Rule)
+	}
+
+	assert.NoError(t, v.Validate("usr", "correct horse"))
+}
+
+func TestChainPasswordValidator(t *testing.T) {
+	randomErr := errors.New("random error")
+	violation := func(rule PasswordRule) error {
+		return &PasswordPolicyError{Violations: []PasswordViolation{{Rule: rule, Message: rule.String()}}}
+	}
+
+	testCases := []struct {
+		description string
+		errs        []error
+		expRules    []PasswordRule
+		expError    error
+	}{
+		{
+			description: "no validators",
+		},
+		{
+			description: "all valid",
+			errs:        []error{nil, nil},
+		},
+		{
+			description: "violations are merged",
+			errs:        []error{violation(RuleMinLength), nil, violation(RuleBreached)},
+			expRules:    []PasswordRule{RuleMinLength, RuleBreached},
+			expError:    ErrWeakPassword,
+		},
+		{
+			description: "other errors stop the chain",
+			errs:        []error{violation(RuleMinLength), ErrPasswordTooSmall, violation(RuleBreached)},
+			expError:    ErrPasswordTooSmall,
+		},
+		{
+			description: "random error",
+			errs:        []error{randomErr},
+			expError:    randomErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			validators := make([]PasswordValidator, 0, len(tc.errs))
+			for _, err := range tc.errs {
+				err := err
+				validators = append(validators, &mockPassValidator{validate: func(username, password string) error {
+					assert.Equal(t, "usr", username)
+					assert.Equal(t, "pass", password)
+
+					return err
+				}})
+			}
+
+			err := NewChainPasswordValidator(validators...).Validate("usr", "pass")
+
+			assert.ErrorIs(t, err, tc.expError)
+			var policyErr *PasswordPolicyError
+			if errors.As(err, &policyErr) {
+				rules := make([]PasswordRule, 0, len(policyErr.Violations))
+				for _, v := range policyErr.Violations {
+					rules = append(rules, v.Rule)
+				}
+				assert.Equal(t, tc.expRules, rules)
+			} else {
+				assert.Nil(t, tc.expRules)
+			}
+		})
+	}
+}
+
+func TestChainPasswordValidator_Simple(t *testing.T) {
+	v := NewChainPasswordValidator(
+		NewSimplePasswordValidator(DefaultMinLen),
+		NewBreachedPasswordValidator(mockBreachedPasswords{"password": true}),
+	)
+
+	assert.ErrorIs(t, v.Validate("usr", "pass"), ErrPasswordTooSmall)
+	assert.ErrorIs(t, v.Validate("usr", "password"), ErrWeakPassword)
+	assert.NoError(t, v.Validate("usr", "correct horse"))
+}
+
+type mockBreachedPasswords map[string]bool
+
+func (m mockBreachedPasswords) Contains(password string) (bool, error) {
+	return m[password], nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 )
 
+// Queries never return soft-deleted users unless their name says otherwise.
 type Queries interface {
 	GetByID
+	GetByIDIncludingDeleted
 	GetByUsername
+	List
 }
 
 type GetByID interface {
@@ -16,3 +19,15 @@ type GetByID interface {
 type GetByUsername interface {
 	GetByUsername(ctx context.Context, username string) (*User, error)
 }
+
+type GetByIDIncludingDeleted interface {
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*User, error)
+}
+
+// List returns a page of the users matching params, which have already been
+// validated by a Lister. Users are ordered by params.Sort and then by ID, and
+// the page starts right after params.Cursor. NextCursor is empty on the last
+// page.
+type List interface {
+	List(ctx context.Context, params *ListParams) (*ListResult, error)
+}
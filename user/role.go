@@ -1,5 +1,12 @@
 package user
 
+import (
+	"context"
+	"fmt"
+)
+
+// RoleUser and RoleAdmin are the built-in roles. Other roles are defined at
+// runtime, so roles given to users are checked with a RoleChecker.
 const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
@@ -10,3 +17,21 @@ type Role string
 func (r Role) String() string {
 	return string(r)
 }
+
+// RoleChecker reports whether a role with the given name exists.
+type RoleChecker interface {
+	RoleExists(ctx context.Context, name string) (bool, error)
+}
+
+func checkRole(ctx context.Context, roles RoleChecker, name string) error {
+	ok, err := roles.RoleExists(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: role %q does not exist", ErrInvalidRole, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"context"
+)
+
+type UpdaterQueries interface {
+	GetByID
+}
+
+type UpdaterCommands interface {
+	Update
+}
+
+type Updater struct {
+	q     UpdaterQueries
+	roles RoleChecker
+	cmd   UpdaterCommands
+}
+
+func NewUpdater(q UpdaterQueries, roles RoleChecker, cmd UpdaterCommands) *Updater {
+	return &Updater{
+		q:     q,
+		roles: roles,
+		cmd:   cmd,
+	}
+}
+
+func (u *Updater) Update(ctx context.Context, params UpdateParams) (*User, error) {
+	if err := checkUpdateParams(params); err != nil {
+		return nil, err
+	}
+
+	if params.Username == nil && params.Role == nil {
+		return u.q.GetByID(ctx, params.ID)
+	}
+
+	if params.Role != nil {
+		if err := checkRole(ctx, u.roles, *params.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.cmd.Update(ctx, &params); err != nil {
+		return nil, mapConflict(err)
+	}
+
+	return u.q.GetByID(ctx, params.ID)
+}
+
+func checkUpdateParams(params UpdateParams) error {
+	if params.Username != nil && *params.Username == "" {
+		return ErrInvalidUsername
+	}
+
+	return nil
+}
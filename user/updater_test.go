@@ -0,0 +1,129 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	empty := ""
+	username := "abc"
+	invalidRole := "not a role"
+	brokenRole := "broken"
+	role := "admin"
+	randomErr := fmt.Errorf("random error")
+
+	testCases := []struct {
+		description string
+		username    *string
+		role        *string
+		errUpdate   error
+		expUpdate   bool
+		expError    error
+	}{
+		{
+			description: "invalid username",
+			username:    &empty,
+			expError:    ErrInvalidUsername,
+		},
+		{
+			description: "invalid role",
+			role:        &invalidRole,
+			expError:    ErrInvalidRole,
+		},
+		{
+			description: "role check error",
+			role:        &brokenRole,
+			expError:    errRoleCheck,
+		},
+		{
+			description: "username already exists",
+			username:    &username,
+			errUpdate:   fmt.Errorf("update failed: %w", ErrUsernameAlreadyExists),
+			expUpdate:   true,
+			expError:    ErrUsernameAlreadyExists,
+		},
+		{
+			description: "does not exist",
+			role:        &role,
+			errUpdate:   ErrDoesNotExist,
+			expUpdate:   true,
+			expError:    ErrDoesNotExist,
+		},
+		{
+			description: "random error",
+			role:        &role,
+			errUpdate:   randomErr,
+			expUpdate:   true,
+			expError:    randomErr,
+		},
+		{
+			description: "nothing to update",
+			expUpdate:   false,
+			expError:    nil,
+		},
+		{
+			description: "all good",
+			username:    &username,
+			role:        &role,
+			expUpdate:   true,
+			expError:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			userID := "1"
+			updated := false
+			q := &mockUpdaterQueries{getByID: func(ctx context.Context, id string) (*User, error) {
+				assert.Equal(t, userID, id)
+
+				return &User{ID: id}, nil
+			}}
+			cmd := &mockUpdaterCMD{update: func(ctx context.Context, params *UpdateParams) error {
+				updated = true
+				assert.Equal(t, userID, params.ID)
+				assert.Equal(t, tc.username, params.Username)
+				assert.Equal(t, tc.role, params.Role)
+
+				return tc.errUpdate
+			}}
+
+			u := NewUpdater(q, newMockRoleChecker(), cmd)
+
+			params := UpdateParams{
+				ID:       userID,
+				Username: tc.username,
+				Role:     tc.role,
+			}
+			res, err := u.Update(context.Background(), params)
+			assert.Equal(t, tc.expUpdate, updated)
+			if tc.expError != nil {
+				assert.Nil(t, res)
+				assert.ErrorIs(t, err, tc.expError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, userID, res.ID)
+		})
+	}
+}
+
+type mockUpdaterQueries struct {
+	getByID func(ctx context.Context, id string) (*User, error)
+}
+
+func (m *mockUpdaterQueries) GetByID(ctx context.Context, id string) (*User, error) {
+	return m.getByID(ctx, id)
+}
+
+type mockUpdaterCMD struct {
+	update func(ctx context.Context, params *UpdateParams) error
+}
+
+func (m *mockUpdaterCMD) Update(ctx context.Context, params *UpdateParams) error {
+	return m.update(ctx, params)
+}
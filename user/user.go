@@ -6,9 +6,14 @@ import (
 )
 
 var (
-	ErrDoesNotExist = errors.New("user does not exist")
+	ErrDoesNotExist    = errors.New("user does not exist")
+	ErrPasswordChanged = errors.New("the password was changed concurrently")
 )
 
+// User is an account of the service. A user with a non-nil DeletedAt is
+// soft-deleted: it is hidden from queries and cannot log in, but it keeps its
+// username, which cannot be taken by another user, so it can always be
+// restored.
 type User struct {
 	ID             string
 	Username       string
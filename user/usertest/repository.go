@@ -0,0 +1,420 @@
+// Package usertest provides a conformance suite that every storage backend of
+// users must pass.
+package usertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mabaro3009/example-architecture-go/user"
+	"github.com/stretchr/testify/assert"
+)
+
+type Repository interface {
+	user.Queries
+	user.Commands
+}
+
+// Factory returns a new, empty repository. It is called once per test.
+type Factory func(t *testing.T) Repository
+
+// RunRepositoryTests checks that the repositories built by newRepository
+// behave like every other user storage backend.
+func RunRepositoryTests(t *testing.T, newRepository Factory) {
+	t.Run("insert and get", func(t *testing.T) {
+		testInsertAndGet(t, newRepository(t))
+	})
+	t.Run("does not exist", func(t *testing.T) {
+		testDoesNotExist(t, newRepository(t))
+	})
+	t.Run("duplicate id", func(t *testing.T) {
+		testDuplicateID(t, newRepository(t))
+	})
+	t.Run("duplicate username", func(t *testing.T) {
+		testDuplicateUsername(t, newRepository(t))
+	})
+	t.Run("update", func(t *testing.T) {
+		testUpdate(t, newRepository(t))
+	})
+	t.Run("update conflicts", func(t *testing.T) {
+		testUpdateConflicts(t, newRepository(t))
+	})
+	t.Run("update password", func(t *testing.T) {
+		testUpdatePassword(t, newRepository(t))
+	})
+	t.Run("soft delete", func(t *testing.T) {
+		testSoftDelete(t, newRepository(t))
+	})
+	t.Run("restore", func(t *testing.T) {
+		testRestore(t, newRepository(t))
+	})
+	t.Run("list", func(t *testing.T) {
+		testList(t, newRepository(t))
+	})
+	t.Run("concurrent inserts", func(t *testing.T) {
+		testConcurrentInserts(t, newRepository(t))
+	})
+}
+
+func testInsertAndGet(t *testing.T, r Repository) {
+	ctx := context.Background()
+	roles := []string{user.RoleUser, user.RoleAdmin}
+	for i, role := range roles {
+		err := r.Insert(ctx, &user.InsertParams{
+			ID:             fmt.Sprint(i),
+			Username:       fmt.Sprintf("user-%d", i),
+			HashedPassword: []byte(fmt.Sprintf("hash-%d", i)),
+			Role:           role,
+		})
+		assert.NoError(t, err)
+	}
+
+	for i, role := range roles {
+		byID, err := r.GetByID(ctx, fmt.Sprint(i))
+		assert.NoError(t, err)
+		byUsername, err := r.GetByUsername(ctx, fmt.Sprintf("user-%d", i))
+		assert.NoError(t, err)
+
+		for _, u := range []*user.User{byID, byUsername} {
+			if u == nil {
+				continue
+			}
+			assert.Equal(t, fmt.Sprint(i), u.ID)
+			assert.Equal(t, fmt.Sprintf("user-%d", i), u.Username)
+			assert.Equal(t, []byte(fmt.Sprintf("hash-%d", i)), u.HashedPassword)
+			assert.Equal(t, user.Role(role), u.Role)
+			assert.WithinDuration(t, time.Now(), u.CreatedAt, time.Minute)
+			assert.Nil(t, u.DeletedAt)
+		}
+	}
+}
+
+func testDoesNotExist(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	_, err := r.GetByID(ctx, "2")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	_, err = r.GetByUsername(ctx, "def")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+}
+
+func testDuplicateID(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	err := r.Insert(ctx, &user.InsertParams{ID: "1", Username: "def", HashedPassword: []byte("hash"), Role: user.RoleUser})
+	assert.ErrorIs(t, err, user.ErrIDAlreadyExists)
+
+	_, err = r.GetByUsername(ctx, "def")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+}
+
+func testDuplicateUsername(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	err := r.Insert(ctx, &user.InsertParams{ID: "2", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser})
+	assert.ErrorIs(t, err, user.ErrUsernameAlreadyExists)
+
+	_, err = r.GetByID(ctx, "2")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+}
+
+func testUpdate(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	username := "def"
+	err := r.Update(ctx, &user.UpdateParams{ID: "1", Username: &username})
+	assert.NoError(t, err)
+
+	u, err := r.GetByUsername(ctx, "def")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", u.ID)
+	assert.Equal(t, user.Role(user.RoleUser), u.Role)
+	assert.Equal(t, []byte("hash"), u.HashedPassword)
+
+	_, err = r.GetByUsername(ctx, "abc")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	role := user.RoleAdmin
+	err = r.Update(ctx, &user.UpdateParams{ID: "1", Role: &role})
+	assert.NoError(t, err)
+
+	u, err = r.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", u.Username)
+	assert.Equal(t, user.Role(user.RoleAdmin), u.Role)
+
+	err = r.Update(ctx, &user.UpdateParams{ID: "1", Username: &username, Role: &role})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "2", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+}
+
+func testUpdateConflicts(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "2", Username: "def", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	username := "abc"
+	err := r.Update(ctx, &user.UpdateParams{ID: "2", Username: &username})
+	assert.ErrorIs(t, err, user.ErrUsernameAlreadyExists)
+
+	u, err := r.GetByID(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", u.Username)
+
+	err = r.Update(ctx, &user.UpdateParams{ID: "3", Username: &username})
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+}
+
+func testUpdatePassword(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	err := r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "1", HashedPassword: []byte("new")})
+	assert.NoError(t, err)
+
+	u, err := r.GetByUsername(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), u.HashedPassword)
+	assert.Equal(t, "abc", u.Username)
+	assert.Equal(t, user.Role(user.RoleUser), u.Role)
+
+	err = r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "1", OldHashedPassword: []byte("hash"), HashedPassword: []byte("stale")})
+	assert.ErrorIs(t, err, user.ErrPasswordChanged)
+
+	err = r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "1", OldHashedPassword: []byte("new"), HashedPassword: []byte("newer")})
+	assert.NoError(t, err)
+
+	u, err = r.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("newer"), u.HashedPassword)
+
+	err = r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "2", HashedPassword: []byte("new")})
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+	err = r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "2", OldHashedPassword: []byte("hash"), HashedPassword: []byte("new")})
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	assert.NoError(t, r.Delete(ctx, "1"))
+	err = r.UpdatePassword(ctx, &user.UpdatePasswordParams{ID: "1", OldHashedPassword: []byte("newer"), HashedPassword: []byte("new")})
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+}
+
+func testSoftDelete(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	assert.NoError(t, r.Delete(ctx, "1"))
+
+	_, err := r.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+	_, err = r.GetByUsername(ctx, "abc")
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	u, err := r.GetByIDIncludingDeleted(ctx, "1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, u.DeletedAt) {
+		assert.WithinDuration(t, time.Now(), *u.DeletedAt, time.Minute)
+	}
+	assert.Equal(t, "abc", u.Username)
+
+	assert.ErrorIs(t, r.Delete(ctx, "1"), user.ErrDoesNotExist)
+	assert.ErrorIs(t, r.Delete(ctx, "2"), user.ErrDoesNotExist)
+
+	username := "def"
+	err = r.Update(ctx, &user.UpdateParams{ID: "1", Username: &username})
+	assert.ErrorIs(t, err, user.ErrDoesNotExist)
+
+	err = r.Insert(ctx, &user.InsertParams{ID: "2", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser})
+	assert.ErrorIs(t, err, user.ErrUsernameAlreadyExists)
+}
+
+func testRestore(t *testing.T, r Repository) {
+	ctx := context.Background()
+	assert.NoError(t, r.Insert(ctx, &user.InsertParams{ID: "1", Username: "abc", HashedPassword: []byte("hash"), Role: user.RoleUser}))
+
+	assert.ErrorIs(t, r.Restore(ctx, "1"), user.ErrDoesNotExist)
+	assert.ErrorIs(t, r.Restore(ctx, "2"), user.ErrDoesNotExist)
+
+	assert.NoError(t, r.Delete(ctx, "1"))
+	assert.NoError(t, r.Restore(ctx, "1"))
+
+	u, err := r.GetByUsername(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", u.ID)
+	assert.Nil(t, u.DeletedAt)
+
+	u, err = r.GetByIDIncludingDeleted(ctx, "1")
+	assert.NoError(t, err)
+	assert.Nil(t, u.DeletedAt)
+}
+
+func testList(t *testing.T, r Repository) {
+	ctx := context.Background()
+	users := make([]*user.User, 0, 10)
+	for i := 0; i < 10; i++ {
+		role := user.RoleUser
+		if i%3 == 0 {
+			role = user.RoleAdmin
+		}
+		// usernames are not in insertion order
+		username := fmt.Sprintf("user-%d", (i*7)%10)
+		if i == 9 {
+			username = "other"
+		}
+		err := r.Insert(ctx, &user.InsertParams{ID: fmt.Sprintf("id-%d", i), Username: username, HashedPassword: []byte("hash"), Role: role})
+		assert.NoError(t, err)
+
+		u, err := r.GetByID(ctx, fmt.Sprintf("id-%d", i))
+		assert.NoError(t, err)
+		users = append(users, u)
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, r.Delete(ctx, "id-4"))
+
+	ids := func(indexes ...int) []string {
+		res := make([]string, 0, len(indexes))
+		for _, i := range indexes {
+			res = append(res, fmt.Sprintf("id-%d", i))
+		}
+		return res
+	}
+
+	testCases := []struct {
+		description string
+		params      user.ListParams
+		expIDs      []string
+	}{
+		{
+			description: "created at ascending",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedExclude, Limit: 3},
+			expIDs:      ids(0, 1, 2, 3, 5, 6, 7, 8, 9),
+		},
+		{
+			description: "created at descending",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Descending: true, Deleted: user.DeletedExclude, Limit: 4},
+			expIDs:      ids(9, 8, 7, 6, 5, 3, 2, 1, 0),
+		},
+		{
+			description: "username ascending",
+			params:      user.ListParams{Sort: user.SortUsername, Deleted: user.DeletedExclude, Limit: 2},
+			expIDs:      ids(9, 0, 3, 6, 2, 5, 8, 1, 7),
+		},
+		{
+			description: "username descending",
+			params:      user.ListParams{Sort: user.SortUsername, Descending: true, Deleted: user.DeletedExclude, Limit: 5},
+			expIDs:      ids(7, 1, 8, 5, 2, 6, 3, 0, 9),
+		},
+		{
+			description: "role",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedExclude, Role: user.RoleAdmin, Limit: 1},
+			expIDs:      ids(0, 3, 6, 9),
+		},
+		{
+			description: "include deleted",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedInclude, Limit: 20},
+			expIDs:      ids(0, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+		},
+		{
+			description: "only deleted",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedOnly, Limit: 20},
+			expIDs:      ids(4),
+		},
+		{
+			description: "username prefix",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedExclude, UsernamePrefix: "user-", Limit: 20},
+			expIDs:      ids(0, 1, 2, 3, 5, 6, 7, 8),
+		},
+		{
+			description: "created range",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedInclude, CreatedFrom: &users[3].CreatedAt, CreatedTo: &users[6].CreatedAt, Limit: 20},
+			expIDs:      ids(3, 4, 5),
+		},
+		{
+			description: "no match",
+			params:      user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedExclude, UsernamePrefix: "nobody", Limit: 20},
+			expIDs:      ids(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			params := tc.params
+			got := make([]string, 0)
+			for page := 0; page < 20; page++ {
+				res, err := r.List(ctx, &params)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.LessOrEqual(t, len(res.Users), params.Limit)
+				for _, u := range res.Users {
+					got = append(got, u.ID)
+				}
+				if res.NextCursor == "" {
+					break
+				}
+				params.Cursor = res.NextCursor
+			}
+			assert.Equal(t, tc.expIDs, got)
+		})
+	}
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		res, err := r.List(ctx, &user.ListParams{Sort: user.SortCreatedAt, Deleted: user.DeletedExclude, Limit: 1})
+		assert.NoError(t, err)
+
+		_, err = r.List(ctx, &user.ListParams{Sort: user.SortUsername, Deleted: user.DeletedExclude, Limit: 1, Cursor: res.NextCursor})
+		assert.ErrorIs(t, err, user.ErrInvalidCursor)
+	})
+}
+
+func testConcurrentInserts(t *testing.T, r Repository) {
+	const (
+		workers  = 8
+		attempts = 20
+	)
+
+	ctx := context.Background()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		inserted = make(map[string]int)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < attempts; i++ {
+				username := fmt.Sprintf("user-%d", i)
+				err := r.Insert(ctx, &user.InsertParams{
+					ID:             fmt.Sprintf("%d-%d", w, i),
+					Username:       username,
+					HashedPassword: []byte("hash"),
+					Role:           user.RoleUser,
+				})
+				if err == nil {
+					mu.Lock()
+					inserted[username]++
+					mu.Unlock()
+				} else {
+					assert.ErrorIs(t, err, user.ErrUsernameAlreadyExists)
+				}
+
+				_, _ = r.GetByUsername(ctx, username)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Len(t, inserted, attempts)
+	for username, count := range inserted {
+		assert.Equal(t, 1, count, username)
+	}
+}